@@ -0,0 +1,208 @@
+// Package app is the application container: it builds every component of one agent from its configuration and owns their clients.
+// Containers share no state, so several differently configured agents can run in one process.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mealmate-agent/catalog"
+	"mealmate-agent/config"
+	"mealmate-agent/db"
+	"mealmate-agent/pipeline"
+	"mealmate-agent/profile"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+)
+
+// App holds the components of one agent, built by New and released by Close
+type App struct {
+	Config   *config.Config
+	Embedder embedding.Embedder
+	Store    vectorstore.Store
+	Source   db.EventSource
+	Database *db.MilvusDatabase
+	// CatalogStore and Catalog are nil when vector_store.catalog_top_k is 0
+	CatalogStore vectorstore.Store
+	Catalog      *catalog.Catalog
+	// Recommendations keeps the recommendations returned by the API and the feedback on them
+	Recommendations db.RecommendationStore
+	// Preferences keeps what users told us about their diet, allergies, dislikes and budget
+	Preferences db.PreferenceStore
+	// Profiles keeps the taste profile of every user and Profiler refreshes them as events are indexed,
+	// both nil when taste_profiles.kind is off
+	Profiles db.ProfileStore
+	Profiler *profile.Refresher
+	// Audit keeps a trace of every agent call, nil when audit.kind is off
+	Audit db.AuditStore
+	Agent compose.Runnable[string, string]
+	// Planner answers meal plans with the same components as Agent
+	Planner compose.Runnable[string, string]
+}
+
+type options struct {
+	embedder  embedding.Embedder
+	store     vectorstore.Store
+	catalog   vectorstore.Store
+	source    db.EventSource
+	chatModel model.BaseChatModel
+}
+
+// Option replaces a component that New would otherwise build from the configuration
+type Option func(*options)
+
+// WithEmbedder uses embedder instead of embedder.kind
+func WithEmbedder(embedder embedding.Embedder) Option {
+	return func(o *options) {
+		o.embedder = embedder
+	}
+}
+
+// WithVectorStore uses store instead of vector_store.kind, the App takes ownership and closes it
+func WithVectorStore(store vectorstore.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithCatalogStore uses store for the restaurant catalog instead of vector_store.kind, the App takes ownership and closes it
+func WithCatalogStore(store vectorstore.Store) Option {
+	return func(o *options) {
+		o.catalog = store
+	}
+}
+
+// WithEventSource uses source instead of event_source.kind, the App takes ownership and closes it
+func WithEventSource(source db.EventSource) Option {
+	return func(o *options) {
+		o.source = source
+	}
+}
+
+// WithChatModel uses cm instead of the Ark chat model
+func WithChatModel(cm model.BaseChatModel) Option {
+	return func(o *options) {
+		o.chatModel = cm
+	}
+}
+
+/**
+* @description: Build every component of an agent, releasing the ones already built if a later one fails
+* @param ctx context.Context
+* @param cfg validated configuration
+* @param opts components replacing the configured ones
+* @return the application, error if a component cannot be built
+ */
+func New(ctx context.Context, cfg *config.Config, opts ...Option) (a *App, err error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	a = &App{Config: cfg, Embedder: o.embedder, Store: o.store, CatalogStore: o.catalog, Source: o.source}
+	defer func() {
+		if err != nil {
+			if closeErr := a.Close(context.WithoutCancel(ctx)); closeErr != nil {
+				err = errors.Join(err, closeErr)
+			}
+			a = nil
+		}
+	}()
+
+	if a.Embedder == nil {
+		if a.Embedder, err = newEmbedder(ctx, cfg); err != nil {
+			return a, fmt.Errorf("embedder: %w", err)
+		}
+	}
+	if a.Store == nil {
+		if a.Store, err = newVectorStore(ctx, cfg, a.Embedder, cfg.VectorStore.Milvus.Collection, cfg.VectorStore.TopK); err != nil {
+			return a, fmt.Errorf("vector store: %w", err)
+		}
+	}
+	if a.CatalogStore == nil && cfg.VectorStore.CatalogTopK > 0 {
+		if a.CatalogStore, err = newVectorStore(ctx, cfg, a.Embedder, cfg.VectorStore.Milvus.CatalogCollection, cfg.VectorStore.CatalogTopK); err != nil {
+			return a, fmt.Errorf("catalog vector store: %w", err)
+		}
+	}
+	if a.CatalogStore != nil {
+		if a.Catalog, err = catalog.New(a.CatalogStore); err != nil {
+			return a, fmt.Errorf("catalog: %w", err)
+		}
+	}
+	if a.Source == nil {
+		if a.Source, err = newEventSource(ctx, cfg); err != nil {
+			return a, fmt.Errorf("event source: %w", err)
+		}
+	}
+
+	a.Database = db.NewMilvusDatabase(ctx, a.Store, a.Source, a.Embedder)
+	a.Database.DeadLetters = db.NewJSONLDeadLetterStore(cfg.Sync.DeadLetterPath)
+	a.Database.SyncCursors = db.NewJSONLSyncCursorStore(cfg.Sync.CursorPath)
+	a.Database.SyncInterval = cfg.Sync.Interval
+	a.Recommendations = db.NewJSONLRecommendationStore(cfg.Feedback.RecommendationsPath, cfg.Feedback.Path)
+	a.Preferences = db.NewJSONLPreferenceStore(cfg.Preferences.Path)
+	if a.Audit, err = newAuditStore(cfg); err != nil {
+		return a, fmt.Errorf("audit store: %w", err)
+	}
+
+	chatModel := o.chatModel
+	if chatModel == nil {
+		if chatModel, err = newChatModel(ctx, cfg); err != nil {
+			return a, fmt.Errorf("chat model: %w", err)
+		}
+	}
+	agentOptions := []pipeline.AgentOption{
+		pipeline.WithChatModel(chatModel),
+		pipeline.WithTopK(cfg.VectorStore.TopK),
+		pipeline.WithPopularRestaurants(a.Database),
+		pipeline.WithCatalog(a.Catalog, cfg.VectorStore.CatalogTopK),
+		pipeline.WithGuard(pipeline.GuardMode(cfg.Agent.Guard), cfg.Agent.GuardMinValid),
+		pipeline.WithFeedback(a.Recommendations),
+		pipeline.WithPreferences(a.Preferences),
+	}
+	// Profiles are summarised by the chat model, an offline setup keeps the prompt without them
+	if _, offline := chatModel.(noChatModel); cfg.Profiles.Kind == "jsonl" && !offline {
+		a.Profiles = db.NewJSONLProfileStore(cfg.Profiles.Path)
+		a.Profiler = profile.NewRefresher(chatModel, a.Source, a.Profiles, cfg.Profiles.RefreshDelay)
+		a.Database.OnIndexed = a.Profiler.Notify
+		agentOptions = append(agentOptions, pipeline.WithTasteProfiles(a.Profiles))
+	}
+	if a.Agent, err = pipeline.BuildMealMateAgent(ctx, a.Store, agentOptions...); err != nil {
+		return a, fmt.Errorf("agent: %w", err)
+	}
+	if a.Planner, err = pipeline.BuildMealPlanAgent(ctx, a.Store, agentOptions...); err != nil {
+		return a, fmt.Errorf("meal plan agent: %w", err)
+	}
+	return a, nil
+}
+
+/**
+* @description: Flush and close the vector stores and event source, call after the auto sync has stopped
+* @param ctx context.Context
+* @return nil if success, error if failed
+ */
+func (a *App) Close(ctx context.Context) error {
+	var errs []error
+	if a.CatalogStore != nil {
+		if err := a.CatalogStore.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush catalog vector store: %w", err))
+		}
+		if err := a.CatalogStore.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close catalog vector store: %w", err))
+		}
+	}
+	if a.Database != nil {
+		return errors.Join(append(errs, a.Database.Close(ctx))...)
+	}
+	// Built partially, release what exists
+	if a.Store != nil {
+		errs = append(errs, a.Store.Close())
+	}
+	if a.Source != nil {
+		errs = append(errs, a.Source.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mealmate-agent/config"
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+	"mealmate-agent/vectorstore"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// offlineConfig runs on the hash embedder, the memory store and a JSONL event file, every file in a temporary directory
+func offlineConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	events := filepath.Join(dir, "events.jsonl")
+	line := `{"id": 1, "user_id": "u1", "restaurant_name": "Sakura", "message": "Salmon nigiri", "created_at": "2025-03-01T12:00:00Z"}` + "\n"
+	if err := os.WriteFile(events, []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Embedder = config.EmbedderConfig{Kind: "hash", Dim: 64}
+	cfg.VectorStore.Kind = "memory"
+	cfg.EventSource = config.EventSourceConfig{Kind: "jsonl", Table: "event", JSONLPath: events}
+	cfg.Sync.DeadLetterPath = filepath.Join(dir, "deadletter.jsonl")
+	cfg.Sync.CursorPath = filepath.Join(dir, "synccursor.jsonl")
+	cfg.Feedback = config.FeedbackConfig{RecommendationsPath: filepath.Join(dir, "recommendations.jsonl"), Path: filepath.Join(dir, "feedback.jsonl")}
+	cfg.Preferences.Path = filepath.Join(dir, "preferences.jsonl")
+	cfg.Profiles.Path = filepath.Join(dir, "profiles.jsonl")
+	cfg.Audit.Path = filepath.Join(dir, "audit.jsonl")
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// cannedModel answers every request with the same content
+type cannedModel struct {
+	content string
+}
+
+func (m cannedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.AssistantMessage(m.content, nil), nil
+}
+
+func (m cannedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage(m.content, nil)}), nil
+}
+
+func agentInput(t *testing.T, userID string) string {
+	t.Helper()
+	input, err := sonic.MarshalString(pipeline.RetrieverInput{UserPrompt: "sushi tonight", UserID: userID, Username: "Alex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input
+}
+
+func TestNewOffline(t *testing.T) {
+	ctx := context.Background()
+	a, err := New(ctx, offlineConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close(ctx)
+
+	if a.Profiles != nil || a.Profiler != nil {
+		t.Error("taste profiles are enabled without a chat model")
+	}
+	if a.Catalog == nil || a.Audit == nil || a.Recommendations == nil || a.Preferences == nil {
+		t.Errorf("components missing from %+v", a)
+	}
+	if _, err := a.Database.ManuallySyncDatabase(ctx, models.SyncConfig{UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	// Everything but the chat model works offline
+	if _, err := a.Agent.Invoke(ctx, agentInput(t, "u1")); !errors.Is(err, ErrNoChatModel) {
+		t.Errorf("Invoke() error = %v, want ErrNoChatModel", err)
+	}
+}
+
+func TestAppsAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	answer := `[{"restaurant_name": "Sakura", "recommendation_rating": 4.5, "main_dishes": "Nigiri", "short_reason": "You loved it."}]`
+	first, err := New(ctx, offlineConfig(t), WithChatModel(cannedModel{answer}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close(ctx)
+	second, err := New(ctx, offlineConfig(t), WithChatModel(cannedModel{answer}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close(ctx)
+
+	if first.Profiles == nil {
+		t.Error("taste profiles are disabled with a chat model")
+	}
+	if _, err := first.Database.ManuallySyncDatabase(ctx, models.SyncConfig{UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := first.Store.IDs(ctx, vectorstore.Filter{UserID: "u1"}); len(ids) != 1 {
+		t.Errorf("first app indexed %v, want event 1", ids)
+	}
+	if ids, _ := second.Store.IDs(ctx, vectorstore.Filter{UserID: "u1"}); len(ids) != 0 {
+		t.Errorf("second app sees %v indexed by the first", ids)
+	}
+
+	output, err := first.Agent.Invoke(ctx, agentInput(t, "u1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recommendations []models.RestaurantRecommendation
+	if err := sonic.UnmarshalString(output, &recommendations); err != nil || len(recommendations) != 1 || recommendations[0].RestaurantName != "Sakura" {
+		t.Errorf("Invoke() = %s, %v", output, err)
+	}
+}
+
+func TestNewReleasesOnFailure(t *testing.T) {
+	cfg := offlineConfig(t)
+	cfg.EventSource.JSONLPath = filepath.Join(t.TempDir(), "missing.jsonl")
+	a, err := New(context.Background(), cfg)
+	if err == nil || a != nil {
+		t.Errorf("New() = %v, %v, want an event source error", a, err)
+	}
+}
@@ -0,0 +1,26 @@
+package app
+
+import (
+	"fmt"
+
+	"mealmate-agent/config"
+	"mealmate-agent/db"
+)
+
+// newAuditStore creates the audit store selected by audit.kind (jsonl or supabase), nil when off
+func newAuditStore(cfg *config.Config) (db.AuditStore, error) {
+	switch cfg.Audit.Kind {
+	case "jsonl":
+		return db.NewJSONLAuditStore(cfg.Audit.Path), nil
+	case "supabase":
+		client, err := db.NewSupabaseClient(cfg.EventSource.Supabase.URL, cfg.EventSource.Supabase.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewSupabaseAuditStore(client, cfg.Audit.Table)
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown audit kind %q", cfg.Audit.Kind)
+	}
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"mealmate-agent/config"
+	"mealmate-agent/pipeline"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// ErrNoChatModel is returned by the agent when ark.api_key is not set, everything that does not chat still works
+var ErrNoChatModel = errors.New("no chat model configured, set ark.api_key and ark.chat_model")
+
+// newChatModel creates the Ark chat model, or one failing every call when no key is configured
+func newChatModel(ctx context.Context, cfg *config.Config) (model.BaseChatModel, error) {
+	if cfg.Ark.APIKey == "" {
+		hlog.SystemLogger().Warn("ark.api_key is not set, the agent answers every request with an error")
+		return noChatModel{}, nil
+	}
+	return pipeline.NewChatModel(ctx, cfg.Ark.APIKey, cfg.Ark.ChatModel)
+}
+
+// noChatModel stands in for the chat model of an offline setup
+type noChatModel struct{}
+
+func (noChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return nil, ErrNoChatModel
+}
+
+func (noChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, ErrNoChatModel
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"mealmate-agent/config"
+	"mealmate-agent/embedder"
+
+	"github.com/cloudwego/eino-ext/components/embedding/ark"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// newEmbedder creates the embedder selected by embedder.kind (ark or hash)
+func newEmbedder(ctx context.Context, cfg *config.Config) (embedding.Embedder, error) {
+	switch kind := cfg.Embedder.Kind; kind {
+	case "ark":
+		return NewArkEmbedder(ctx, cfg.Ark)
+	case "hash":
+		hlog.SystemLogger().Warnf("Using the offline hash embedder (dim %d), results are not semantic", cfg.Embedder.Dim)
+		return embedder.NewHashEmbedder(cfg.Embedder.Dim)
+	default:
+		return nil, fmt.Errorf("unknown embedder kind %q", kind)
+	}
+}
+
+/**
+* @description: Create the Ark embedder
+* @param ctx context.Context
+* @param cfg Ark credentials and model
+* @return embedder instance and error
+ */
+func NewArkEmbedder(ctx context.Context, cfg config.ArkConfig) (*ark.Embedder, error) {
+	return ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
+		APIKey: cfg.APIKey,
+		Model:  cfg.EmbedderModel,
+	})
+}
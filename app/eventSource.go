@@ -0,0 +1,28 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"mealmate-agent/config"
+	"mealmate-agent/db"
+)
+
+// newEventSource creates the event source selected by event_source.kind (supabase, postgres or jsonl)
+func newEventSource(ctx context.Context, cfg *config.Config) (db.EventSource, error) {
+	source := cfg.EventSource
+	switch source.Kind {
+	case "supabase":
+		client, err := db.NewSupabaseClient(source.Supabase.URL, source.Supabase.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewSupabaseEventSource(client, source.Table)
+	case "postgres":
+		return db.NewPostgresEventSource(ctx, source.PostgresDSN, source.Table)
+	case "jsonl":
+		return db.NewJSONLEventSource(source.JSONLPath)
+	default:
+		return nil, fmt.Errorf("unknown event source kind %q", source.Kind)
+	}
+}
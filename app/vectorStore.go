@@ -0,0 +1,40 @@
+package app
+
+import (
+	"context"
+
+	"mealmate-agent/config"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+)
+
+// newVectorStore creates the vector store selected by vector_store.kind (milvus or memory) on one collection
+func newVectorStore(ctx context.Context, cfg *config.Config, embedder embedding.Embedder, collection string, topK int) (vectorstore.Store, error) {
+	if cfg.VectorStore.Kind == vectorstore.KindMemory {
+		hlog.SystemLogger().Warn("Using the in-memory vector store, documents are lost on restart")
+		return vectorstore.NewMemoryStore(embedder)
+	}
+	milvusClient, err := client.NewClient(ctx, client.Config{
+		Address: cfg.VectorStore.Milvus.Address,
+		DBName:  cfg.VectorStore.Milvus.DBName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hlog.SystemLogger().Info("Milvus client initialized")
+	store, err := vectorstore.NewMilvusStore(ctx, milvusClient, embedder, vectorstore.MilvusConfig{
+		Collection: collection,
+		Dim:        cfg.Embedder.Dim,
+		TopK:       topK,
+		SearchEf:   cfg.VectorStore.Milvus.SearchEf,
+	})
+	if err != nil {
+		// The store owns the client only once it is created
+		milvusClient.Close()
+		return nil, err
+	}
+	return store, nil
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"mealmate-agent/db"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// Register adds the trace lookup route for the holder of adminToken.
+// Traces hold full prompts, so nothing is registered when the audit log is off or there is no token.
+func Register(h *server.Hertz, store db.AuditStore, adminToken string) {
+	if store == nil || adminToken == "" {
+		return
+	}
+	admin := h.Group("/v1", AdminOnly(adminToken))
+	admin.GET("/audit/:request_id", func(ctx context.Context, c *app.RequestContext) {
+		TraceHandler(ctx, c, store)
+	})
+}
+
+// AdminOnly rejects the requests whose Authorization header is not the bearer token
+func AdminOnly(token string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		given, ok := strings.CutPrefix(string(c.GetHeader("Authorization")), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.H{
+				"error":  "Unauthorized",
+				"detail": "an admin bearer token is required",
+			})
+			return
+		}
+		c.Next(ctx)
+	}
+}
+
+// TraceHandler returns the trace of one agent call by the request ID returned with its response
+func TraceHandler(ctx context.Context, c *app.RequestContext, store db.AuditStore) {
+	requestID := c.Param("request_id")
+	trace, err := store.GetTrace(ctx, requestID)
+	if errors.Is(err, db.ErrTraceNotFound) {
+		c.JSON(http.StatusNotFound, utils.H{
+			"error":  "Trace not found",
+			"detail": requestID,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to load trace",
+			"detail": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, trace)
+}
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+func TestTraceRoute(t *testing.T) {
+	store := db.NewJSONLAuditStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+	trace := models.AgentTrace{RequestID: "req-1", UserID: "u1", Status: http.StatusOK, CreatedAt: time.Now().UTC()}
+	if err := store.SaveTrace(context.Background(), trace); err != nil {
+		t.Fatal(err)
+	}
+	h := server.New()
+	Register(h, store, "secret")
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{"admin", "/v1/audit/req-1", "Bearer secret", http.StatusOK},
+		{"unknown request", "/v1/audit/req-2", "Bearer secret", http.StatusNotFound},
+		{"wrong token", "/v1/audit/req-1", "Bearer guess", http.StatusUnauthorized},
+		{"not a bearer", "/v1/audit/req-1", "secret", http.StatusUnauthorized},
+		{"no token", "/v1/audit/req-1", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ut.PerformRequest(h.Engine, http.MethodGet, tt.path, nil, ut.Header{Key: "Authorization", Value: tt.authorization})
+			if w.Code != tt.want {
+				t.Errorf("GET %s = %d %s, want %d", tt.path, w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNoRouteWithoutToken(t *testing.T) {
+	for _, store := range []db.AuditStore{nil, db.NewJSONLAuditStore(filepath.Join(t.TempDir(), "audit.jsonl"))} {
+		h := server.New()
+		Register(h, store, "")
+		if routes := h.Routes(); len(routes) != 0 {
+			t.Errorf("Register() without a token added %v", routes)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// requestIDHeader carries the ID of an agent call, its trace is fetched with GET /v1/audit/:request_id.
+// The ID is always generated here, the one a client sends is only recorded next to it.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern accepts client request IDs that are safe to store
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// AgentHandler binds a typed AgentRequest, runs the agent and returns parsed recommendations, each saved with an ID for feedback.
+// Every call is traced in the audit store when there is one.
+func AgentHandler(ctx context.Context, c *app.RequestContext, recommendations db.RecommendationStore, audit db.AuditStore, runnable *compose.Runnable[string, string]) {
+	started := time.Now()
+	requestID, clientRequestID := newRequestID(), clientRequestIDOf(c)
+	c.Header(requestIDHeader, requestID)
+	var req models.AgentRequest
+	recordTrace, agentTrace := pipeline.RecordTrace()
+	var response *models.EventAgentResponse
+	var callErr error
+	if audit != nil {
+		defer func() {
+			trace := agentTrace()
+			trace.RequestID, trace.ClientRequestID = requestID, clientRequestID
+			trace.UserID, trace.Request = req.UserID, req
+			trace.Output, trace.Status = response, c.Response.StatusCode()
+			if callErr != nil {
+				trace.Error = callErr.Error()
+			}
+			trace.LatencyMs, trace.CreatedAt = time.Since(started).Milliseconds(), started.UTC()
+			if err := audit.SaveTrace(context.WithoutCancel(ctx), trace); err != nil {
+				hlog.SystemLogger().Errorf("Failed to save trace %s: %v", requestID, err)
+			}
+		}()
+	}
+
+	// Validate and bind the request body to the AgentRequest struct
+	if callErr = c.BindAndValidate(&req); callErr != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": callErr.Error(),
+		})
+		return
+	}
+
+	input, err := sonic.MarshalString(pipeline.RetrieverInput{
+		UserPrompt: req.Prompt,
+		UserID:     req.UserID,
+		Username:   req.Username,
+		Locale:     req.Locale,
+		Location:   req.Location.Coordinates(),
+		MaxResults: req.Options.MaxResults,
+		Timezone:   req.Timezone,
+		// Participants make it a group request
+		Participants: req.Participants,
+	})
+	if callErr = err; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to process request",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	detectColdStart, coldStart := pipeline.DetectColdStart()
+	output, err := (*runnable).Invoke(ctx, input, detectColdStart, recordTrace)
+	callErr = err
+	if errors.Is(err, pipeline.ErrInvalidInput) {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, pipeline.ErrNotShared) {
+		c.JSON(http.StatusForbidden, utils.H{
+			"error":  "Participant not allowed",
+			"detail": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to process request",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	response = &models.EventAgentResponse{RequestID: requestID}
+	if callErr = sonic.UnmarshalString(output, &response.Recommendations); callErr != nil {
+		hlog.SystemLogger().Errorf("Model output is not a valid recommendation list: %v", callErr)
+		c.JSON(http.StatusBadGateway, utils.H{
+			"error":  "Model returned an invalid response",
+			"detail": callErr.Error(),
+		})
+		response = nil
+		return
+	}
+	if recommendations != nil {
+		saveRecommendations(ctx, recommendations, req, response.Recommendations)
+	}
+	if coldStart() {
+		response.ColdStart = true
+		response.OnboardingQuestions = pipeline.OnboardingQuestions
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// clientRequestIDOf returns the request ID sent by the client, empty when it is missing or invalid
+func clientRequestIDOf(c *app.RequestContext) string {
+	if id := string(c.GetHeader(requestIDHeader)); requestIDPattern.MatchString(id) {
+		return id
+	}
+	return ""
+}
+
+// newRequestID returns a random request ID, clients cannot choose it so they cannot collide with or overwrite another trace
+func newRequestID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "req-" + hex.EncodeToString(b)
+}
+
+// saveRecommendations assigns an ID to each recommendation and stores them, they are returned without ID if storing fails
+func saveRecommendations(ctx context.Context, store db.RecommendationStore, req models.AgentRequest, recommendations []models.RestaurantRecommendation) {
+	now := time.Now().UTC()
+	records := make([]models.RecommendationRecord, 0, len(recommendations))
+	for i := range recommendations {
+		recommendations[i].ID = db.NewRecommendationID()
+		records = append(records, models.RecommendationRecord{
+			ID:             recommendations[i].ID,
+			UserID:         req.UserID,
+			Prompt:         req.Prompt,
+			Recommendation: recommendations[i],
+			CreatedAt:      now,
+		})
+	}
+	if err := store.SaveRecommendations(ctx, records); err != nil {
+		hlog.SystemLogger().Errorf("Failed to save recommendations of user %s: %v", req.UserID, err)
+		for i := range recommendations {
+			recommendations[i].ID = ""
+		}
+	}
+}
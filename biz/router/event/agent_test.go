@@ -0,0 +1,200 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mealmate-agent/db"
+	"mealmate-agent/embedder"
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+const sakura = `[{"restaurant_name": "Sakura", "recommendation_rating": 4.5, "main_dishes": "Nigiri", "short_reason": "You loved it."}]`
+
+// cannedModel answers every request with the same content
+type cannedModel struct {
+	content string
+}
+
+func (m cannedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.AssistantMessage(m.content, nil), nil
+}
+
+func (m cannedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage(m.content, nil)}), nil
+}
+
+// newTestAgent builds the MealMateAgent on a memory store where u1 dined at Sakura, the model answers answer
+func newTestAgent(t *testing.T, answer string) *compose.Runnable[string, string] {
+	t.Helper()
+	ctx := context.Background()
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := vectorstore.NewMemoryStore(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := &schema.Document{ID: "1", Content: "Sakura salmon nigiri", MetaData: map[string]any{"user_id": "u1", "restaurant_name": "Sakura"}}
+	if _, err := store.Store(ctx, []*schema.Document{doc}); err != nil {
+		t.Fatal(err)
+	}
+	agent, err := pipeline.BuildMealMateAgent(ctx, store, pipeline.WithChatModel(cannedModel{answer}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &agent
+}
+
+// testServer serves the event routes, recommendations and traces are kept in a temporary directory
+func testServer(t *testing.T, answer string) (*server.Hertz, *db.JSONLRecommendationStore, *db.JSONLAuditStore) {
+	t.Helper()
+	dir := t.TempDir()
+	recommendations := db.NewJSONLRecommendationStore(filepath.Join(dir, "recommendations.jsonl"), filepath.Join(dir, "feedback.jsonl"))
+	audit := db.NewJSONLAuditStore(filepath.Join(dir, "audit.jsonl"))
+	h := server.New()
+	Register(h, &db.MilvusDatabase{}, recommendations, audit, newTestAgent(t, answer))
+	return h, recommendations, audit
+}
+
+func postJSON(h *server.Hertz, path, body string, headers ...ut.Header) *ut.ResponseRecorder {
+	headers = append(headers, ut.Header{Key: "Content-Type", Value: "application/json"})
+	return ut.PerformRequest(h.Engine, http.MethodPost, path, &ut.Body{Body: strings.NewReader(body), Len: len(body)}, headers...)
+}
+
+func TestAgentHandler(t *testing.T) {
+	ctx := context.Background()
+	h, recommendations, audit := testServer(t, sakura)
+	w := postJSON(h, "/v1/events/ai", `{"user_id": "u1", "username": "Alex", "prompt": "sushi tonight"}`,
+		ut.Header{Key: "X-Request-ID", Value: "client-42"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /v1/events/ai = %d %s", w.Code, w.Body.String())
+	}
+	var response models.EventAgentResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Recommendations) != 1 || response.Recommendations[0].RestaurantName != "Sakura" {
+		t.Fatalf("recommendations = %+v, want Sakura", response.Recommendations)
+	}
+
+	// Every recommendation is saved so feedback can refer to it
+	id := response.Recommendations[0].ID
+	if record, err := recommendations.GetRecommendation(ctx, id); err != nil || record.UserID != "u1" || record.Prompt != "sushi tonight" {
+		t.Errorf("GetRecommendation(%q) = %+v, %v", id, record, err)
+	}
+
+	// The request ID is the server's, the client's is only recorded
+	requestID := string(w.Header().Get("X-Request-ID"))
+	if !strings.HasPrefix(requestID, "req-") || response.RequestID != requestID {
+		t.Errorf("request ID = %q in the header and %q in the body, want the same server ID", requestID, response.RequestID)
+	}
+	trace, err := audit.GetTrace(ctx, requestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.ClientRequestID != "client-42" || trace.UserID != "u1" || trace.Status != http.StatusOK || len(trace.RawOutputs) != 1 || trace.Output == nil {
+		t.Errorf("trace = %+v", trace)
+	}
+}
+
+// TestLegacyAgentRoute checks that the deprecated route only adds the deprecation headers to /v1/events/ai
+func TestLegacyAgentRoute(t *testing.T) {
+	ctx := context.Background()
+	h, recommendations, audit := testServer(t, sakura)
+	w := postJSON(h, "/events/ai", `{"user_id": "u1", "username": "Alex", "prompt": "sushi tonight"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /events/ai = %d %s", w.Code, w.Body.String())
+	}
+	if deprecation, link := string(w.Header().Get("Deprecation")), string(w.Header().Get("Link")); deprecation != "true" || !strings.Contains(link, "</v1/events/ai>") {
+		t.Errorf("Deprecation = %q, Link = %q", deprecation, link)
+	}
+	var response models.EventAgentResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Recommendations) != 1 {
+		t.Fatalf("recommendations = %+v, want one", response.Recommendations)
+	}
+	id := response.Recommendations[0].ID
+	if record, err := recommendations.GetRecommendation(ctx, id); err != nil || record.UserID != "u1" {
+		t.Errorf("GetRecommendation(%q) = %+v, %v", id, record, err)
+	}
+
+	// Legacy calls are traced like the others
+	trace, err := audit.GetTrace(ctx, string(w.Header().Get("X-Request-ID")))
+	if err != nil || trace.UserID != "u1" || trace.Status != http.StatusOK || trace.Output == nil {
+		t.Errorf("GetTrace() = %+v, %v", trace, err)
+	}
+}
+
+func TestAgentHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		body   string
+		want   int
+	}{
+		{"no prompt", sakura, `{"user_id": "u1", "username": "Alex"}`, http.StatusBadRequest},
+		{"prompt too long", sakura, `{"user_id": "u1", "username": "Alex", "prompt": "` + strings.Repeat("a", 2001) + `"}`, http.StatusBadRequest},
+		{"location out of range", sakura, `{"user_id": "u1", "username": "Alex", "prompt": "sushi", "location": {"latitude": 91, "longitude": 0}}`, http.StatusBadRequest},
+		{"too many results", sakura, `{"user_id": "u1", "username": "Alex", "prompt": "sushi", "options": {"max_results": 6}}`, http.StatusBadRequest},
+		{"invalid model output", "Sakura is great!", `{"user_id": "u1", "username": "Alex", "prompt": "sushi"}`, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _, audit := testServer(t, tt.answer)
+			w := postJSON(h, "/v1/events/ai", tt.body)
+			if w.Code != tt.want {
+				t.Fatalf("POST /v1/events/ai = %d %s, want %d", w.Code, w.Body.String(), tt.want)
+			}
+			// Failed calls are traced too
+			trace, err := audit.GetTrace(context.Background(), string(w.Header().Get("X-Request-ID")))
+			if err != nil || trace.Status != tt.want || trace.Error == "" {
+				t.Errorf("trace = %+v, %v, want status %d with an error", trace, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRequestID(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"client-42", "client-42"},
+		{"abc.DEF_1-2", "abc.DEF_1-2"},
+		{"", ""},
+		{"has space", ""},
+		{"line\nbreak", ""},
+		{strings.Repeat("a", 129), ""},
+	}
+	for _, tt := range tests {
+		h := server.New()
+		var got string
+		h.GET("/", func(ctx context.Context, c *app.RequestContext) {
+			got = clientRequestIDOf(c)
+		})
+		ut.PerformRequest(h.Engine, http.MethodGet, "/", nil, ut.Header{Key: "X-Request-ID", Value: tt.header})
+		if got != tt.want {
+			t.Errorf("clientRequestIDOf(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	if a, b := newRequestID(), newRequestID(); a == b {
+		t.Errorf("newRequestID() returned %q twice", a)
+	}
+}
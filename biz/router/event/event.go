@@ -6,6 +6,7 @@ import (
 
 	"mealmate-agent/db"
 	"mealmate-agent/models"
+	"mealmate-agent/schedule"
 
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/hertz/pkg/app"
@@ -14,17 +15,37 @@ import (
 	"github.com/cloudwego/hertz/pkg/common/utils"
 )
 
-func Register(h *server.Hertz, milvusDB *db.MilvusDatabase, runnable *compose.Runnable[string, string]) {
-	// Register event-related routes here in the future
-	h.POST("/events", func(ctx context.Context, c *app.RequestContext) {
+func Register(h *server.Hertz, milvusDB *db.MilvusDatabase, recommendations db.RecommendationStore, audit db.AuditStore, runnable *compose.Runnable[string, string]) {
+	v1 := h.Group("/v1")
+	v1.POST("/events", func(ctx context.Context, c *app.RequestContext) {
 		EventPostHandler(ctx, c, milvusDB)
 	})
-	h.POST("/events/sync", func(ctx context.Context, c *app.RequestContext) {
+	v1.POST("/events/sync", func(ctx context.Context, c *app.RequestContext) {
 		EventSyncHandler(ctx, c, milvusDB)
 	})
-	h.POST("/events/ai", func(ctx context.Context, c *app.RequestContext) {
-		CallEventAgent(ctx, c, runnable)
+	v1.POST("/events/ai", func(ctx context.Context, c *app.RequestContext) {
+		AgentHandler(ctx, c, recommendations, audit, runnable)
 	})
+
+	// Deprecated unversioned aliases, kept for existing clients
+	h.POST("/events", deprecated("/v1/events"), func(ctx context.Context, c *app.RequestContext) {
+		EventPostHandler(ctx, c, milvusDB)
+	})
+	h.POST("/events/sync", deprecated("/v1/events/sync"), func(ctx context.Context, c *app.RequestContext) {
+		EventSyncHandler(ctx, c, milvusDB)
+	})
+	h.POST("/events/ai", deprecated("/v1/events/ai"), func(ctx context.Context, c *app.RequestContext) {
+		AgentHandler(ctx, c, recommendations, audit, runnable)
+	})
+}
+
+// deprecated marks a legacy route and points clients to its successor
+func deprecated(successor string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next(ctx)
+	}
 }
 
 func EventPostHandler(ctx context.Context, c *app.RequestContext, milvusDB *db.MilvusDatabase) {
@@ -40,6 +61,16 @@ func EventPostHandler(ctx context.Context, c *app.RequestContext, milvusDB *db.M
 		return
 	}
 
+	if event.ScheduleTime != "" {
+		if _, err = schedule.Parse(event.ScheduleTime); err != nil {
+			c.JSON(http.StatusBadRequest, utils.H{
+				"error":  "Invalid request body",
+				"detail": err.Error(),
+			})
+			return
+		}
+	}
+
 	hlog.SystemLogger().Info("Event received:", event)
 
 	err = milvusDB.SyncEventToMilvus(ctx, &[]models.Event{event})
@@ -90,29 +121,3 @@ func EventSyncHandler(ctx context.Context, c *app.RequestContext, milvusDB *db.M
 
 	hlog.SystemLogger().Info("Event sync completed for user:", config.UserID, "Count:", count)
 }
-
-func CallEventAgent(ctx context.Context, c *app.RequestContext, runnable *compose.Runnable[string, string]) {
-	// Get raw request body
-	body := c.Request.Body()
-
-	// Validate body length
-	if len(body) == 0 {
-		c.JSON(http.StatusBadRequest, utils.H{
-			"error": "Request body cannot be empty",
-		})
-		return
-	}
-
-	output, err := (*runnable).Invoke(ctx, string(body))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.H{
-			"error":  "Failed to process request",
-			"detail": err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, utils.H{
-		"response": output,
-	})
-}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mealmate-agent/db"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// checkTimeout bounds each dependency check so a hanging dependency cannot stall the probe
+const checkTimeout = 3 * time.Second
+
+type dependencyCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type dependencyStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+func Register(h *server.Hertz, milvusDB *db.MilvusDatabase) {
+	h.GET("/healthz", LivenessHandler)
+	h.GET("/readyz", func(ctx context.Context, c *app.RequestContext) {
+		ReadinessHandler(ctx, c, milvusDB)
+	})
+}
+
+// LivenessHandler reports that the process is up and serving requests
+func LivenessHandler(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, utils.H{"status": "ok"})
+}
+
+// ReadinessHandler actively checks every dependency and answers 503 if any of them is unavailable
+func ReadinessHandler(ctx context.Context, c *app.RequestContext, milvusDB *db.MilvusDatabase) {
+	checks := []dependencyCheck{
+		{name: "vector_store", check: milvusDB.CheckVectorStore},
+		{name: "event_source", check: milvusDB.CheckEventSource},
+		{name: "embedder", check: milvusDB.CheckEmbedder},
+		{name: "sync", check: func(ctx context.Context) error { return milvusDB.CheckSyncFreshness() }},
+	}
+
+	results := make(map[string]dependencyStatus, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, dep := range checks {
+		wg.Add(1)
+		go func(dep dependencyCheck) {
+			defer wg.Done()
+			result := runCheck(ctx, dep)
+			mu.Lock()
+			results[dep.name] = result
+			mu.Unlock()
+		}(dep)
+	}
+	wg.Wait()
+
+	ready := true
+	for name, result := range results {
+		if result.Status != "ok" {
+			ready = false
+			hlog.SystemLogger().Warnf("Readiness check %s failed: %s", name, result.Error)
+		}
+	}
+
+	if !ready {
+		c.JSON(consts.StatusServiceUnavailable, utils.H{
+			"status": "unavailable",
+			"checks": results,
+		})
+		return
+	}
+	c.JSON(consts.StatusOK, utils.H{
+		"status": "ok",
+		"checks": results,
+	})
+}
+
+func runCheck(ctx context.Context, dep dependencyCheck) dependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dep.check(ctx)
+	result := dependencyStatus{
+		Status:    "ok",
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = "unavailable"
+		result.Error = err.Error()
+	}
+	return result
+}
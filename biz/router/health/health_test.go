@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mealmate-agent/db"
+	"mealmate-agent/embedder"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+type readiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]dependencyStatus `json:"checks"`
+}
+
+func getReadiness(t *testing.T, milvusDB *db.MilvusDatabase) (int, readiness) {
+	t.Helper()
+	h := server.New()
+	Register(h, milvusDB)
+	w := ut.PerformRequest(h.Engine, http.MethodGet, "/readyz", nil)
+	var body readiness
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, body
+}
+
+func TestLiveness(t *testing.T) {
+	h := server.New()
+	Register(h, &db.MilvusDatabase{})
+	if w := ut.PerformRequest(h.Engine, http.MethodGet, "/healthz", nil); w.Code != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want 200", w.Code)
+	}
+}
+
+func TestReadinessUnavailable(t *testing.T) {
+	code, body := getReadiness(t, &db.MilvusDatabase{})
+	if code != http.StatusServiceUnavailable || body.Status != "unavailable" {
+		t.Errorf("GET /readyz = %d %q, want 503 unavailable", code, body.Status)
+	}
+	for _, name := range []string{"vector_store", "event_source", "embedder", "sync"} {
+		if check := body.Checks[name]; check.Status != "unavailable" || check.Error == "" {
+			t.Errorf("check %s = %+v, want unavailable with an error", name, check)
+		}
+	}
+}
+
+func TestReadinessReady(t *testing.T) {
+	ctx := context.Background()
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := vectorstore.NewMemoryStore(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := db.NewJSONLEventSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	milvusDB := db.NewMilvusDatabase(ctx, store, source, emb)
+	if err := milvusDB.AutomaticSyncDatabase(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	code, body := getReadiness(t, milvusDB)
+	if code != http.StatusOK || body.Status != "ok" || len(body.Checks) != 4 {
+		t.Errorf("GET /readyz = %d %+v, want 200 with 4 checks", code, body)
+	}
+}
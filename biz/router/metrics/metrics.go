@@ -0,0 +1,11 @@
+package metrics
+
+import (
+	"mealmate-agent/telemetry"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+)
+
+func Register(h *server.Hertz) {
+	h.GET("/metrics", telemetry.MetricsHandler())
+}
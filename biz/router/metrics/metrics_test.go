@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+)
+
+// The exposition handler writes through a net/http adaptor that needs a live connection, so only the route is checked
+func TestRegister(t *testing.T) {
+	h := server.New()
+	Register(h)
+	for _, route := range h.Routes() {
+		if route.Method == http.MethodGet && route.Path == "/metrics" {
+			return
+		}
+	}
+	t.Errorf("GET /metrics is not registered in %v", h.Routes())
+}
@@ -0,0 +1,18 @@
+package ping
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+func TestPing(t *testing.T) {
+	h := server.New()
+	Register(h)
+	w := ut.PerformRequest(h.Engine, http.MethodGet, "/ping", nil)
+	if w.Code != http.StatusOK || w.Body.String() != `{"message":"pong"}` {
+		t.Errorf("GET /ping = %d %s, want 200 pong", w.Code, w.Body.String())
+	}
+}
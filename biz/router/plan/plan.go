@@ -0,0 +1,199 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"mealmate-agent/ical"
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+	"mealmate-agent/schedule"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+const (
+	// defaultDays is the length of a plan when the request does not set one
+	defaultDays = 7
+	// defaultPrompt asks for the plan when the user has no constraints
+	defaultPrompt = "please plan my meals at restaurants I would enjoy"
+	// mealDuration is how long a planned meal blocks the calendar
+	mealDuration = time.Hour
+	// calendarContentType is the media type of the iCalendar export
+	calendarContentType = "text/calendar; charset=utf-8"
+)
+
+// defaultSlots are planned when the request names none
+var defaultSlots = []string{string(schedule.Lunch), string(schedule.Dinner)}
+
+// Register adds the meal plan route, answered as JSON or as an iCalendar file
+func Register(h *server.Hertz, planner *compose.Runnable[string, string]) {
+	v1 := h.Group("/v1")
+	v1.POST("/plans", func(ctx context.Context, c *app.RequestContext) {
+		MealPlanHandler(ctx, c, planner)
+	})
+}
+
+// MealPlanHandler plans the meals of a user for the next days. The plan is an .ics file with ?format=ics or
+// Accept: text/calendar, JSON otherwise.
+func MealPlanHandler(ctx context.Context, c *app.RequestContext, planner *compose.Runnable[string, string]) {
+	var req models.MealPlanRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+	plan, location, err := planInput(req, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	prompt := req.Prompt
+	if strings.TrimSpace(prompt) == "" {
+		prompt = defaultPrompt
+	}
+	input, err := sonic.MarshalString(pipeline.RetrieverInput{
+		UserPrompt: prompt,
+		UserID:     req.UserID,
+		Username:   req.Username,
+		Locale:     req.Locale,
+		Location:   req.Location.Coordinates(),
+		Timezone:   req.Timezone,
+		Plan:       &plan,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to process request",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	output, err := (*planner).Invoke(ctx, input)
+	if errors.Is(err, pipeline.ErrInvalidInput) {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to process request",
+			"detail": err.Error(),
+		})
+		return
+	}
+	entries, unplanned, err := pipeline.ParseMealPlan(output, plan)
+	if err != nil {
+		hlog.SystemLogger().Errorf("Model output is not a valid meal plan: %v", err)
+		c.JSON(http.StatusBadGateway, utils.H{
+			"error":  "Model returned an invalid response",
+			"detail": err.Error(),
+		})
+		return
+	}
+	response := models.MealPlan{UserID: req.UserID, Start: plan.Start, Days: plan.Days, Timezone: req.Timezone, Entries: entries, Unplanned: unplanned}
+
+	if !wantsCalendar(c) {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	var ics bytes.Buffer
+	if err := calendarOf(response, location).Write(&ics, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to export the plan",
+			"detail": err.Error(),
+		})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="meal-plan.ics"`)
+	c.Data(http.StatusOK, calendarContentType, ics.Bytes())
+}
+
+// planInput applies the defaults of the request, the start is tomorrow on the clock of the user.
+// The location is nil when the request has no timezone.
+func planInput(req models.MealPlanRequest, now time.Time) (pipeline.PlanInput, *time.Location, error) {
+	var location *time.Location
+	if req.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(req.Timezone); err != nil {
+			return pipeline.PlanInput{}, nil, errors.New("unknown timezone " + req.Timezone)
+		}
+		now = now.In(location)
+	}
+	plan := pipeline.PlanInput{Start: req.Start, Days: req.Days}
+	if plan.Start == "" {
+		plan.Start = now.AddDate(0, 0, 1).Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, plan.Start); err != nil {
+		return pipeline.PlanInput{}, nil, errors.New("invalid start " + plan.Start + ", use YYYY-MM-DD")
+	}
+	if plan.Days == 0 {
+		plan.Days = defaultDays
+	}
+	slots := req.Slots
+	if len(slots) == 0 {
+		slots = defaultSlots
+	}
+	for _, s := range slots {
+		slot, err := schedule.ParseSlot(s)
+		if err != nil {
+			return pipeline.PlanInput{}, nil, err
+		}
+		plan.Slots = append(plan.Slots, string(slot))
+	}
+	return plan, location, nil
+}
+
+func wantsCalendar(c *app.RequestContext) bool {
+	return c.Query("format") == "ics" || strings.Contains(string(c.GetHeader("Accept")), "text/calendar")
+}
+
+// calendarOf converts the plan, times are floating when the plan has no timezone
+func calendarOf(plan models.MealPlan, location *time.Location) ical.Calendar {
+	calendar := ical.Calendar{ProdID: "-//MealMate//Meal Plan//EN", Name: "MealMate meal plan"}
+	for _, entry := range plan.Entries {
+		zone := location
+		if zone == nil {
+			zone = time.UTC
+		}
+		start, err := time.ParseInLocation(time.DateOnly+" 15:04", entry.Date+" "+entry.Time, zone)
+		if err != nil {
+			continue
+		}
+		// The same meal of the same user keeps its UID, importing a new plan updates it
+		sum := sha256.Sum256([]byte(plan.UserID + "|" + entry.Date + "|" + entry.Slot))
+		description := entry.Reason
+		if entry.MainDishes != "" {
+			description = "Try: " + entry.MainDishes + "\n" + description
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         hex.EncodeToString(sum[:8]) + "@mealmate",
+			Summary:     strings.ToUpper(entry.Slot[:1]) + entry.Slot[1:] + " at " + entry.RestaurantName,
+			Description: description,
+			Location:    entry.RestaurantName,
+			Geo:         entry.Coordinates,
+			Start:       start,
+			End:         start.Add(mealDuration),
+			Floating:    location == nil,
+		})
+	}
+	return calendar
+}
@@ -0,0 +1,78 @@
+package plan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+)
+
+func TestPlanInput(t *testing.T) {
+	// 23:30 in UTC is already the next day in Tokyo
+	now := time.Date(2025, 3, 14, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		req     models.MealPlanRequest
+		want    pipeline.PlanInput
+		wantErr string
+	}{
+		{"defaults", models.MealPlanRequest{}, pipeline.PlanInput{Start: "2025-03-15", Days: defaultDays, Slots: []string{"lunch", "dinner"}}, ""},
+		{"timezone", models.MealPlanRequest{Timezone: "Asia/Tokyo"}, pipeline.PlanInput{Start: "2025-03-16", Days: defaultDays, Slots: []string{"lunch", "dinner"}}, ""},
+		{"explicit", models.MealPlanRequest{Start: "2025-04-01", Days: 3, Slots: []string{"Breakfast"}}, pipeline.PlanInput{Start: "2025-04-01", Days: 3, Slots: []string{"breakfast"}}, ""},
+		{"unknown timezone", models.MealPlanRequest{Timezone: "Mars/Olympus"}, pipeline.PlanInput{}, "unknown timezone"},
+		{"invalid start", models.MealPlanRequest{Start: "15/03/2025"}, pipeline.PlanInput{}, "invalid start"},
+		{"unknown slot", models.MealPlanRequest{Slots: []string{"midnight"}}, pipeline.PlanInput{}, "midnight"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, location, err := planInput(tt.req, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("planInput() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planInput() = %+v, want %+v", got, tt.want)
+			}
+			if (location != nil) != (tt.req.Timezone != "") {
+				t.Errorf("planInput() location = %v for timezone %q", location, tt.req.Timezone)
+			}
+		})
+	}
+}
+
+func TestCalendarOf(t *testing.T) {
+	plan := models.MealPlan{UserID: "u1", Entries: []models.MealPlanEntry{
+		{Date: "2025-03-15", Slot: "lunch", Time: "12:30", RestaurantName: "Sakura", MainDishes: "Nigiri", Reason: "You loved it."},
+		{Date: "2025-03-15", Slot: "dinner", Time: "late", RestaurantName: "Le Bistrot"},
+	}}
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar := calendarOf(plan, paris)
+	// Entries without a valid time are left out
+	if len(calendar.Events) != 1 {
+		t.Fatalf("calendarOf() has %d events, want 1", len(calendar.Events))
+	}
+	event := calendar.Events[0]
+	if event.Summary != "Lunch at Sakura" || event.Description != "Try: Nigiri\nYou loved it." || event.Floating {
+		t.Errorf("event = %+v", event)
+	}
+	if want := time.Date(2025, 3, 15, 12, 30, 0, 0, paris); !event.Start.Equal(want) || event.End.Sub(event.Start) != mealDuration {
+		t.Errorf("event runs %v to %v, want an hour from %v", event.Start, event.End, want)
+	}
+	// The UID depends on the user, the day and the slot only
+	again := calendarOf(plan, nil)
+	if again.Events[0].UID != event.UID || !again.Events[0].Floating {
+		t.Errorf("floating event = %+v, want UID %s", again.Events[0], event.UID)
+	}
+}
@@ -0,0 +1,142 @@
+package preference
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// maxValueLength bounds each allergen, diet, cuisine and restaurant name
+const maxValueLength = 100
+
+// Register adds the routes reading, replacing and deleting the preferences of a user
+func Register(h *server.Hertz, preferences db.PreferenceStore) {
+	v1 := h.Group("/v1")
+	v1.GET("/users/:user_id/preferences", func(ctx context.Context, c *app.RequestContext) {
+		GetPreferencesHandler(ctx, c, preferences)
+	})
+	v1.PUT("/users/:user_id/preferences", func(ctx context.Context, c *app.RequestContext) {
+		PutPreferencesHandler(ctx, c, preferences)
+	})
+	v1.DELETE("/users/:user_id/preferences", func(ctx context.Context, c *app.RequestContext) {
+		DeletePreferencesHandler(ctx, c, preferences)
+	})
+}
+
+// GetPreferencesHandler returns the preferences of a user, 404 when they have set none
+func GetPreferencesHandler(ctx context.Context, c *app.RequestContext, preferences db.PreferenceStore) {
+	userID := c.Param("user_id")
+	found, err := preferences.GetPreferences(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to load preferences",
+			"detail": err.Error(),
+		})
+		return
+	}
+	if found == nil {
+		c.JSON(http.StatusNotFound, utils.H{
+			"error":  "Preferences not found",
+			"detail": userID,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, found)
+}
+
+// PutPreferencesHandler creates or replaces the preferences of a user
+func PutPreferencesHandler(ctx context.Context, c *app.RequestContext, preferences db.PreferenceStore) {
+	var req models.PreferencesRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+	userID := c.Param("user_id")
+	saved := models.UserPreferences{
+		UserID:        userID,
+		MaxPriceLevel: req.MaxPriceLevel,
+		MaxDistanceKm: req.MaxDistanceKm,
+		Language:      strings.TrimSpace(req.Language),
+		UpdatedAt:     time.Now().UTC(),
+	}
+	var err error
+	for _, list := range []struct {
+		name   string
+		values []string
+		target *[]string
+	}{
+		{"dietary_restrictions", req.DietaryRestrictions, &saved.DietaryRestrictions},
+		{"allergens", req.Allergens, &saved.Allergens},
+		{"disliked_cuisines", req.DislikedCuisines, &saved.DislikedCuisines},
+		{"disliked_restaurants", req.DislikedRestaurants, &saved.DislikedRestaurants},
+		{"share_with", req.ShareWith, &saved.ShareWith},
+	} {
+		if *list.target, err = cleanList(list.name, list.values); err != nil {
+			c.JSON(http.StatusBadRequest, utils.H{
+				"error":  "Invalid request body",
+				"detail": err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := preferences.SavePreferences(ctx, saved); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to save preferences",
+			"detail": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeletePreferencesHandler removes the preferences of a user, 404 when they had none
+func DeletePreferencesHandler(ctx context.Context, c *app.RequestContext, preferences db.PreferenceStore) {
+	userID := c.Param("user_id")
+	deleted, err := preferences.DeletePreferences(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to delete preferences",
+			"detail": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, utils.H{
+			"error":  "Preferences not found",
+			"detail": userID,
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// cleanList trims the values and drops empty ones and repeats, whatever their case
+func cleanList(name string, values []string) ([]string, error) {
+	cleaned := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) > maxValueLength {
+			return nil, fmt.Errorf("%s: %q is longer than %d characters", name, value, maxValueLength)
+		}
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		cleaned = append(cleaned, value)
+	}
+	return cleaned, nil
+}
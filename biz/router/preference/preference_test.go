@@ -0,0 +1,82 @@
+package preference
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+func TestPreferenceRoutes(t *testing.T) {
+	h := server.New()
+	Register(h, db.NewJSONLPreferenceStore(filepath.Join(t.TempDir(), "preferences.jsonl")))
+	path := "/v1/users/u1/preferences"
+
+	if w := ut.PerformRequest(h.Engine, http.MethodGet, path, nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET before PUT = %d, want 404", w.Code)
+	}
+
+	body := `{"allergens": [" Peanuts ", "peanuts", ""], "disliked_cuisines": ["Thai"], "max_price_level": 2, "language": " fr ", "share_with": ["u2"]}`
+	w := ut.PerformRequest(h.Engine, http.MethodPut, path, &ut.Body{Body: strings.NewReader(body), Len: len(body)},
+		ut.Header{Key: "Content-Type", Value: "application/json"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT = %d %s", w.Code, w.Body.String())
+	}
+
+	w = ut.PerformRequest(h.Engine, http.MethodGet, path, nil)
+	var saved models.UserPreferences
+	if err := json.Unmarshal(w.Body.Bytes(), &saved); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET = %d %s", w.Code, w.Body.String())
+	}
+	if saved.UserID != "u1" || !reflect.DeepEqual(saved.Allergens, []string{"Peanuts"}) || saved.MaxPriceLevel != 2 || saved.Language != "fr" ||
+		!reflect.DeepEqual(saved.ShareWith, []string{"u2"}) {
+		t.Errorf("GET = %+v", saved)
+	}
+
+	if w := ut.PerformRequest(h.Engine, http.MethodDelete, path, nil); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d, want 204", w.Code)
+	}
+	if w := ut.PerformRequest(h.Engine, http.MethodDelete, path, nil); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d, want 404", w.Code)
+	}
+}
+
+func TestPutPreferencesInvalid(t *testing.T) {
+	h := server.New()
+	Register(h, db.NewJSONLPreferenceStore(filepath.Join(t.TempDir(), "preferences.jsonl")))
+	for _, body := range []string{
+		`{"max_price_level": 5}`,
+		`{"max_distance_km": -1}`,
+		`{"allergens": ["` + strings.Repeat("a", maxValueLength+1) + `"]}`,
+	} {
+		w := ut.PerformRequest(h.Engine, http.MethodPut, "/v1/users/u1/preferences", &ut.Body{Body: strings.NewReader(body), Len: len(body)},
+			ut.Header{Key: "Content-Type", Value: "application/json"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestCleanList(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{nil, []string{}},
+		{[]string{" vegan ", "Vegan", "halal", "  "}, []string{"vegan", "halal"}},
+	}
+	for _, tt := range tests {
+		got, err := cleanList("diets", tt.values)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cleanList(%q) = %q, %v, want %q", tt.values, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// Register adds the feedback route of recommendations returned by /v1/events/ai
+func Register(h *server.Hertz, recommendations db.RecommendationStore) {
+	v1 := h.Group("/v1")
+	v1.POST("/recommendations/:id/feedback", func(ctx context.Context, c *app.RequestContext) {
+		FeedbackHandler(ctx, c, recommendations)
+	})
+}
+
+// FeedbackHandler stores the feedback of a user on one of their recommendations
+func FeedbackHandler(ctx context.Context, c *app.RequestContext, recommendations db.RecommendationStore) {
+	var req models.FeedbackRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	record, err := recommendations.GetRecommendation(ctx, id)
+	// Recommendations of other users are not revealed to exist
+	if errors.Is(err, db.ErrRecommendationNotFound) || (err == nil && record.UserID != req.UserID) {
+		c.JSON(http.StatusNotFound, utils.H{
+			"error":  "Recommendation not found",
+			"detail": id,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to load recommendation",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	feedback := models.Feedback{
+		RecommendationID: record.ID,
+		UserID:           record.UserID,
+		RestaurantName:   record.Recommendation.RestaurantName,
+		RestaurantID:     record.Recommendation.RestaurantID,
+		Verdict:          req.Verdict,
+		Rating:           req.Rating,
+		Comment:          req.Comment,
+		CreatedAt:        time.Now().UTC(),
+	}
+	if err := recommendations.AddFeedback(ctx, feedback); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to store feedback",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	hlog.SystemLogger().Infof("Feedback %s on %s from user %s", feedback.Verdict, feedback.RestaurantName, feedback.UserID)
+	c.JSON(http.StatusCreated, feedback)
+}
@@ -0,0 +1,77 @@
+package recommendation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+func TestFeedbackHandler(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := db.NewJSONLRecommendationStore(filepath.Join(dir, "recommendations.jsonl"), filepath.Join(dir, "feedback.jsonl"))
+	record := models.RecommendationRecord{
+		ID:             "rec-1",
+		UserID:         "u1",
+		Prompt:         "sushi",
+		Recommendation: models.RestaurantRecommendation{RestaurantName: "Sakura"},
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := store.SaveRecommendations(ctx, []models.RecommendationRecord{record}); err != nil {
+		t.Fatal(err)
+	}
+	h := server.New()
+	Register(h, store)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"accepted", "/v1/recommendations/rec-1/feedback", `{"user_id": "u1", "verdict": "accepted", "rating": 5}`, http.StatusCreated},
+		{"another user", "/v1/recommendations/rec-1/feedback", `{"user_id": "u2", "verdict": "accepted"}`, http.StatusNotFound},
+		{"unknown recommendation", "/v1/recommendations/rec-2/feedback", `{"user_id": "u1", "verdict": "accepted"}`, http.StatusNotFound},
+		{"unknown verdict", "/v1/recommendations/rec-1/feedback", `{"user_id": "u1", "verdict": "loved"}`, http.StatusBadRequest},
+		{"rating out of range", "/v1/recommendations/rec-1/feedback", `{"user_id": "u1", "verdict": "visited", "rating": 6}`, http.StatusBadRequest},
+		{"no user", "/v1/recommendations/rec-1/feedback", `{"verdict": "accepted"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ut.PerformRequest(h.Engine, http.MethodPost, tt.path, &ut.Body{Body: strings.NewReader(tt.body), Len: len(tt.body)},
+				ut.Header{Key: "Content-Type", Value: "application/json"})
+			if w.Code != tt.want {
+				t.Fatalf("POST %s = %d %s, want %d", tt.path, w.Code, w.Body.String(), tt.want)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			var feedback models.Feedback
+			if err := json.Unmarshal(w.Body.Bytes(), &feedback); err != nil {
+				t.Fatal(err)
+			}
+			if feedback.RestaurantName != "Sakura" || feedback.RecommendationID != "rec-1" || feedback.Rating != 5 {
+				t.Errorf("feedback = %+v", feedback)
+			}
+		})
+	}
+
+	// Only the accepted feedback was stored
+	feedback, err := store.FeedbackByUser(ctx, "u1")
+	if err != nil || len(feedback) != 1 || feedback[0].Verdict != models.FeedbackAccepted {
+		t.Errorf("FeedbackByUser() = %+v, %v, want the accepted feedback", feedback, err)
+	}
+	if feedback, _ := store.FeedbackByUser(ctx, "u2"); len(feedback) != 0 {
+		t.Errorf("FeedbackByUser(u2) = %+v, want none", feedback)
+	}
+}
@@ -1,15 +1,33 @@
 package router
 
 import (
+	"mealmate-agent/app"
+	"mealmate-agent/biz/router/audit"
 	"mealmate-agent/biz/router/event"
+	"mealmate-agent/biz/router/health"
+	"mealmate-agent/biz/router/metrics"
 	"mealmate-agent/biz/router/ping"
-	"mealmate-agent/db"
+	"mealmate-agent/biz/router/plan"
+	"mealmate-agent/biz/router/preference"
+	"mealmate-agent/biz/router/recommendation"
+	"mealmate-agent/biz/router/restaurant"
+	"mealmate-agent/telemetry"
 
-	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/hertz/pkg/app/server"
 )
 
-func RegisterRoutes(h *server.Hertz, milvusDB *db.MilvusDatabase, runnable *compose.Runnable[string, string]) {
+// RegisterRoutes serves the components of application on h
+func RegisterRoutes(h *server.Hertz, application *app.App) {
+	// Middlewares must be registered before the routes they apply to
+	h.Use(telemetry.TracingMiddleware(), telemetry.MetricsMiddleware())
+
 	ping.Register(h)
-	event.Register(h, milvusDB, runnable)
+	health.Register(h, application.Database)
+	metrics.Register(h)
+	event.Register(h, application.Database, application.Recommendations, application.Audit, &application.Agent)
+	restaurant.Register(h, application.Catalog)
+	recommendation.Register(h, application.Recommendations)
+	preference.Register(h, application.Preferences)
+	audit.Register(h, application.Audit, application.Config.Audit.AdminToken)
+	plan.Register(h, &application.Planner)
 }
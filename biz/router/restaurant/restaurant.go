@@ -0,0 +1,76 @@
+package restaurant
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"mealmate-agent/catalog"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// Register adds the catalog routes, nothing is registered when the catalog is disabled
+func Register(h *server.Hertz, restaurants *catalog.Catalog) {
+	if restaurants == nil {
+		return
+	}
+	v1 := h.Group("/v1")
+	v1.POST("/restaurants", func(ctx context.Context, c *app.RequestContext) {
+		ImportHandler(ctx, c, restaurants)
+	})
+}
+
+// ImportHandler imports restaurants from a JSON array, or from CSV when the content type is text/csv
+func ImportHandler(ctx context.Context, c *app.RequestContext, restaurants *catalog.Catalog) {
+	body := c.Request.Body()
+	if len(body) == 0 {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "Request body cannot be empty",
+		})
+		return
+	}
+
+	var parsed []models.Restaurant
+	var err error
+	if strings.HasPrefix(string(c.ContentType()), "text/csv") {
+		parsed, err = catalog.ReadCSV(bytes.NewReader(body))
+	} else {
+		parsed, err = catalog.ReadJSON(bytes.NewReader(body))
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	count, err := restaurants.Import(ctx, parsed)
+	if errors.Is(err, catalog.ErrInvalidRestaurant) {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":  "Invalid restaurant",
+			"detail": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error":  "Failed to import restaurants",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	hlog.SystemLogger().Infof("Imported %d restaurants into the catalog", count)
+	c.JSON(http.StatusOK, utils.H{
+		"message": "Restaurants imported successfully",
+		"count":   count,
+	})
+}
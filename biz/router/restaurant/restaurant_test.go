@@ -0,0 +1,67 @@
+package restaurant
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"mealmate-agent/catalog"
+	"mealmate-agent/embedder"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+func TestImportHandler(t *testing.T) {
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := vectorstore.NewMemoryStore(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restaurants, err := catalog.New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := server.New()
+	Register(h, restaurants)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"json", "application/json", `[{"name": "Sakura", "cuisine": "japanese", "coordinates": {"latitude": 48.86, "longitude": 2.35}}]`, http.StatusOK},
+		{"csv", "text/csv", "name,latitude,longitude,dishes\nLe Bistrot,48.85,2.34,Steak|Tarte\n", http.StatusOK},
+		{"empty", "application/json", "", http.StatusBadRequest},
+		{"unknown field", "application/json", `[{"name": "Sakura", "stars": 3}]`, http.StatusBadRequest},
+		{"csv without coordinates", "text/csv", "name\nSakura\n", http.StatusBadRequest},
+		{"invalid restaurant", "application/json", `[{"name": "Sakura", "price_level": 9}]`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ut.PerformRequest(h.Engine, http.MethodPost, "/v1/restaurants", &ut.Body{Body: strings.NewReader(tt.body), Len: len(tt.body)},
+				ut.Header{Key: "Content-Type", Value: tt.contentType})
+			if w.Code != tt.want {
+				t.Errorf("POST /v1/restaurants = %d %s, want %d", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+
+	if ids, err := store.IDs(context.Background(), vectorstore.Filter{}); err != nil || len(ids) != 2 {
+		t.Errorf("catalog holds %v, %v, want the 2 valid restaurants", ids, err)
+	}
+}
+
+func TestNoRouteWithoutCatalog(t *testing.T) {
+	h := server.New()
+	Register(h, nil)
+	if routes := h.Routes(); len(routes) != 0 {
+		t.Errorf("Register(nil) added %v", routes)
+	}
+}
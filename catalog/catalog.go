@@ -0,0 +1,201 @@
+// Package catalog keeps the restaurant catalog in its own vector store collection so recommendations name real places
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"mealmate-agent/models"
+	"mealmate-agent/telemetry"
+	"mealmate-agent/vectorstore"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// importBatchSize bounds how many restaurants are embedded and stored in one call
+	importBatchSize = 100
+	// nearbyRadiusKm is the half width of the box searched around a location
+	nearbyRadiusKm = 10.0
+	// kmPerDegree is the length of one degree of latitude
+	kmPerDegree = 111.0
+)
+
+// ErrInvalidRestaurant is wrapped by every validation error of an imported restaurant
+var ErrInvalidRestaurant = errors.New("invalid restaurant")
+
+// Catalog imports restaurants into a vector store and searches them
+type Catalog struct {
+	store vectorstore.Store
+}
+
+/**
+* @description: Create a catalog on a vector store holding only catalog documents
+* @param store vector store of the catalog collection
+* @return the catalog, error if store is nil
+ */
+func New(store vectorstore.Store) (*Catalog, error) {
+	if store == nil {
+		return nil, fmt.Errorf("catalog vector store not initialized")
+	}
+	return &Catalog{store: store}, nil
+}
+
+/**
+* @description: Validate and index restaurants, a restaurant with an existing ID replaces it
+* @param ctx context.Context
+* @param restaurants restaurants to import, IDs are filled in when empty
+* @return number of imported restaurants, error if a restaurant is invalid or a batch failed
+ */
+func (c *Catalog) Import(ctx context.Context, restaurants []models.Restaurant) (count int, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "catalog.import", attribute.Int("restaurants", len(restaurants)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	docs := make([]*schema.Document, 0, len(restaurants))
+	for i, restaurant := range restaurants {
+		if err := Validate(restaurant); err != nil {
+			return 0, fmt.Errorf("restaurant %d: %w", i+1, err)
+		}
+		doc, err := Document(restaurant)
+		if err != nil {
+			return 0, err
+		}
+		docs = append(docs, doc)
+	}
+
+	// Report embedding calls and indexer spans made during the store
+	ctx = callbacks.InitCallbacks(ctx, nil, telemetry.CallbackHandlers()...)
+	for start := 0; start < len(docs); start += importBatchSize {
+		batch := docs[start:min(start+importBatchSize, len(docs))]
+		if _, err := c.store.Store(ctx, batch); err != nil {
+			return count, err
+		}
+		count += len(batch)
+	}
+	return count, nil
+}
+
+/**
+* @description: Search the restaurants matching a query, preferring the ones around a location
+* @param ctx context.Context
+* @param query free text request
+* @param near search within nearbyRadiusKm of this location first, nil to search everywhere
+* @param topK maximum number of restaurants, the store default when 0
+* @return catalog documents, decode them with FromDocument
+ */
+func (c *Catalog) Search(ctx context.Context, query string, near *models.Coordinates, topK int) ([]*schema.Document, error) {
+	opts := make([]retriever.Option, 0, 2)
+	if topK > 0 {
+		opts = append(opts, retriever.WithTopK(topK))
+	}
+	if near != nil {
+		docs, err := c.store.Retrieve(ctx, query, append(opts, vectorstore.WithFilter(vectorstore.Filter{Where: nearby(*near)}))...)
+		// A location outside the catalog still gets the best matches anywhere
+		if err != nil || len(docs) > 0 {
+			return docs, err
+		}
+	}
+	return c.store.Retrieve(ctx, query, opts...)
+}
+
+// nearby matches restaurants inside a box of nearbyRadiusKm around location, cheaper than a distance in the filter
+func nearby(location models.Coordinates) vectorstore.Expr {
+	dLat := nearbyRadiusKm / kmPerDegree
+	dLon := nearbyRadiusKm / (kmPerDegree * math.Max(math.Cos(location.Latitude*math.Pi/180), 0.01))
+	return vectorstore.And(
+		vectorstore.Meta("coordinates", "latitude").Range(location.Latitude-dLat, location.Latitude+dLat),
+		vectorstore.Meta("coordinates", "longitude").Range(location.Longitude-dLon, location.Longitude+dLon),
+	)
+}
+
+/**
+* @description: Check the fields of a restaurant
+* @param restaurant restaurant to check
+* @return nil if valid, error wrapping ErrInvalidRestaurant if not
+ */
+func Validate(restaurant models.Restaurant) error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(strings.TrimSpace(restaurant.Name) != "", "name is required")
+	check(len(restaurant.Name) <= 256, "name is longer than 256 bytes")
+	check(len(restaurant.ID) <= 256, "id is longer than 256 bytes")
+	check(restaurant.PriceLevel >= 0 && restaurant.PriceLevel <= 4, "price_level %d is not between 0 and 4", restaurant.PriceLevel)
+	check(restaurant.Coordinates.Latitude >= -90 && restaurant.Coordinates.Latitude <= 90, "latitude %f is out of range", restaurant.Coordinates.Latitude)
+	check(restaurant.Coordinates.Longitude >= -180 && restaurant.Coordinates.Longitude <= 180, "longitude %f is out of range", restaurant.Coordinates.Longitude)
+	for _, period := range restaurant.OpeningHours {
+		check(validPeriod(period), "opening period %+v is invalid", period)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidRestaurant, errors.Join(errs...))
+	}
+	return nil
+}
+
+// RestaurantID derives a stable ID from the name and the coordinates rounded to about 10 meters
+func RestaurantID(restaurant models.Restaurant) string {
+	key := fmt.Sprintf("%s|%.4f|%.4f", strings.ToLower(strings.TrimSpace(restaurant.Name)),
+		restaurant.Coordinates.Latitude, restaurant.Coordinates.Longitude)
+	sum := sha256.Sum256([]byte(key))
+	return "restaurant-" + hex.EncodeToString(sum[:8])
+}
+
+/**
+* @description: Convert a restaurant into a catalog document, its metadata is the restaurant JSON
+* @param restaurant restaurant to convert, the ID is derived when empty
+* @return the document, error if the restaurant cannot be encoded
+ */
+func Document(restaurant models.Restaurant) (*schema.Document, error) {
+	if restaurant.ID == "" {
+		restaurant.ID = RestaurantID(restaurant)
+	}
+	raw, err := sonic.Marshal(restaurant)
+	if err != nil {
+		return nil, err
+	}
+	meta := make(map[string]any)
+	if err := sonic.Unmarshal(raw, &meta); err != nil {
+		return nil, err
+	}
+	// Catalog documents belong to no user
+	meta["user_id"] = ""
+
+	content := restaurant.Name
+	if restaurant.Cuisine != "" {
+		content += " " + restaurant.Cuisine
+	}
+	if len(restaurant.Dishes) > 0 {
+		content += " " + strings.Join(restaurant.Dishes, ", ")
+	}
+	return &schema.Document{ID: restaurant.ID, Content: content, MetaData: meta}, nil
+}
+
+/**
+* @description: Decode a document returned by Search
+* @param doc catalog document
+* @return the restaurant, error if the metadata is not a restaurant
+ */
+func FromDocument(doc *schema.Document) (models.Restaurant, error) {
+	var restaurant models.Restaurant
+	raw, err := sonic.Marshal(doc.MetaData)
+	if err != nil {
+		return restaurant, err
+	}
+	if err := sonic.Unmarshal(raw, &restaurant); err != nil {
+		return restaurant, fmt.Errorf("catalog document %s: %w", doc.ID, err)
+	}
+	restaurant.ID = doc.ID
+	return restaurant, nil
+}
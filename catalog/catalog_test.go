@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"mealmate-agent/embedder"
+	"mealmate-agent/models"
+	"mealmate-agent/vectorstore"
+)
+
+func TestValidate(t *testing.T) {
+	valid := models.Restaurant{Name: "Sakura", PriceLevel: 2, Coordinates: models.Coordinates{Latitude: 48.86, Longitude: 2.35}}
+	tests := []struct {
+		name   string
+		modify func(*models.Restaurant)
+		valid  bool
+	}{
+		{"valid", func(r *models.Restaurant) {}, true},
+		{"no name", func(r *models.Restaurant) { r.Name = "  " }, false},
+		{"price level", func(r *models.Restaurant) { r.PriceLevel = 5 }, false},
+		{"latitude", func(r *models.Restaurant) { r.Coordinates.Latitude = 91 }, false},
+		{"longitude", func(r *models.Restaurant) { r.Coordinates.Longitude = -181 }, false},
+		{"opening day", func(r *models.Restaurant) {
+			r.OpeningHours = []models.OpeningPeriod{{Day: "monday", Open: "12:00", Close: "14:00"}}
+		}, false},
+		{"opening time", func(r *models.Restaurant) {
+			r.OpeningHours = []models.OpeningPeriod{{Day: "mon", Open: "12h", Close: "14:00"}}
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restaurant := valid
+			tt.modify(&restaurant)
+			err := Validate(restaurant)
+			if tt.valid != (err == nil) {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRestaurant) {
+				t.Errorf("Validate() = %v, want ErrInvalidRestaurant", err)
+			}
+		})
+	}
+}
+
+func TestRestaurantID(t *testing.T) {
+	sakura := models.Restaurant{Name: "Sakura", Coordinates: models.Coordinates{Latitude: 48.86001, Longitude: 2.35}}
+	// Case, spaces and a meter of difference give the same restaurant
+	same := models.Restaurant{Name: " sakura ", Coordinates: models.Coordinates{Latitude: 48.86002, Longitude: 2.35}}
+	elsewhere := models.Restaurant{Name: "Sakura", Coordinates: models.Coordinates{Latitude: 45.76, Longitude: 4.83}}
+	if RestaurantID(sakura) != RestaurantID(same) {
+		t.Errorf("RestaurantID() differs for %+v and %+v", sakura, same)
+	}
+	if RestaurantID(sakura) == RestaurantID(elsewhere) {
+		t.Errorf("RestaurantID() is the same for restaurants in different cities")
+	}
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	restaurant := models.Restaurant{
+		Name:         "Sakura",
+		Cuisine:      "japanese",
+		Dishes:       []string{"nigiri", "ramen"},
+		PriceLevel:   2,
+		Coordinates:  models.Coordinates{Latitude: 48.86, Longitude: 2.35},
+		OpeningHours: []models.OpeningPeriod{{Day: "mon", Open: "12:00", Close: "14:00"}},
+	}
+	doc, err := Document(restaurant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != RestaurantID(restaurant) || doc.Content != "Sakura japanese nigiri, ramen" || doc.MetaData["user_id"] != "" {
+		t.Errorf("Document() = %+v", doc)
+	}
+	got, err := FromDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restaurant.ID = doc.ID
+	if !reflect.DeepEqual(got, restaurant) {
+		t.Errorf("FromDocument() = %+v, want %+v", got, restaurant)
+	}
+}
+
+func TestImportAndSearch(t *testing.T) {
+	ctx := context.Background()
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := vectorstore.NewMemoryStore(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paris := models.Coordinates{Latitude: 48.86, Longitude: 2.35}
+	lyon := models.Coordinates{Latitude: 45.76, Longitude: 4.83}
+	count, err := c.Import(ctx, []models.Restaurant{
+		{Name: "Sakura", Cuisine: "sushi", Coordinates: paris},
+		{Name: "Bouchon", Cuisine: "lyonnais", Coordinates: lyon},
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("Import() = %d, %v", count, err)
+	}
+	if _, err := c.Import(ctx, []models.Restaurant{{Name: ""}}); !errors.Is(err, ErrInvalidRestaurant) {
+		t.Errorf("Import() of an invalid restaurant = %v, want ErrInvalidRestaurant", err)
+	}
+
+	tests := []struct {
+		name string
+		near *models.Coordinates
+		want []string
+	}{
+		{"near paris", &paris, []string{"Sakura"}},
+		{"near lyon", &lyon, []string{"Bouchon"}},
+		// Nothing around the location, the best matches anywhere are returned
+		{"nowhere near", &models.Coordinates{Latitude: -33.87, Longitude: 151.21}, []string{"Sakura", "Bouchon"}},
+		{"anywhere", nil, []string{"Sakura", "Bouchon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := c.Search(ctx, "sushi", tt.near, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(docs))
+			for _, doc := range docs {
+				restaurant, err := FromDocument(doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				names = append(names, restaurant.Name)
+			}
+			if len(names) != len(tt.want) || names[0] != tt.want[0] {
+				t.Errorf("Search() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWithoutStore(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("New(nil) succeeded")
+	}
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+// weekdays are the day names of OpeningPeriod.Day, in week order
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+/**
+* @description: Parse compact opening hours, e.g. "mon-fri 11:30-14:00,18:00-22:00; sat,sun 12:00-23:00"
+* @param s semicolon separated groups of days followed by comma separated HH:MM-HH:MM slots, empty for unknown hours
+* @return one period per day and slot, error if a day or time is malformed
+ */
+func ParseOpeningHours(s string) ([]models.OpeningPeriod, error) {
+	periods := make([]models.OpeningPeriod, 0)
+	for _, group := range strings.Split(s, ";") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		daysPart, slotsPart, ok := strings.Cut(group, " ")
+		if !ok {
+			return nil, fmt.Errorf("opening hours %q: expected days followed by time slots", group)
+		}
+		days, err := parseDays(daysPart)
+		if err != nil {
+			return nil, fmt.Errorf("opening hours %q: %w", group, err)
+		}
+		for _, slot := range strings.Split(slotsPart, ",") {
+			opens, closes, ok := strings.Cut(strings.TrimSpace(slot), "-")
+			if !ok || !validClock(opens) || !validClock(closes) {
+				return nil, fmt.Errorf("opening hours %q: invalid slot %q, use HH:MM-HH:MM", group, slot)
+			}
+			for _, day := range days {
+				periods = append(periods, models.OpeningPeriod{Day: day, Open: opens, Close: closes})
+			}
+		}
+	}
+	return periods, nil
+}
+
+// parseDays expands "mon-fri" or "sat,sun" into day names
+func parseDays(s string) ([]string, error) {
+	days := make([]string, 0, len(weekdays))
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		start := slices.Index(weekdays, from)
+		if start < 0 {
+			return nil, fmt.Errorf("unknown day %q", from)
+		}
+		if !isRange {
+			days = append(days, from)
+			continue
+		}
+		end := slices.Index(weekdays, to)
+		if end < 0 {
+			return nil, fmt.Errorf("unknown day %q", to)
+		}
+		// A range may wrap around the week, e.g. fri-mon
+		for i := start; ; i = (i + 1) % len(weekdays) {
+			days = append(days, weekdays[i])
+			if i == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+/**
+* @description: Format opening hours compactly, days sharing the same slots are grouped
+* @param periods opening periods
+* @return e.g. "mon,tue,wed,thu,fri 11:30-22:00; sat,sun 12:00-23:00", empty when unknown
+ */
+func FormatOpeningHours(periods []models.OpeningPeriod) string {
+	slots := make(map[string][]string, len(weekdays))
+	for _, p := range periods {
+		slots[p.Day] = append(slots[p.Day], p.Open+"-"+p.Close)
+	}
+	groups := make([]string, 0)
+	daysBySlots := make(map[string][]string)
+	for _, day := range weekdays {
+		if len(slots[day]) == 0 {
+			continue
+		}
+		key := strings.Join(slots[day], ",")
+		if _, ok := daysBySlots[key]; !ok {
+			groups = append(groups, key)
+		}
+		daysBySlots[key] = append(daysBySlots[key], day)
+	}
+	parts := make([]string, 0, len(groups))
+	for _, key := range groups {
+		parts = append(parts, strings.Join(daysBySlots[key], ",")+" "+key)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func validPeriod(p models.OpeningPeriod) bool {
+	return slices.Contains(weekdays, p.Day) && validClock(p.Open) && validClock(p.Close)
+}
+
+func validClock(s string) bool {
+	_, err := time.Parse("15:04", s)
+	return err == nil
+}
@@ -0,0 +1,66 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	"mealmate-agent/models"
+)
+
+func TestParseOpeningHours(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []models.OpeningPeriod
+		wantErr bool
+	}{
+		{"", []models.OpeningPeriod{}, false},
+		{"mon 12:00-14:00", []models.OpeningPeriod{{Day: "mon", Open: "12:00", Close: "14:00"}}, false},
+		{"sat,SUN 12:00-14:00,19:00-23:00", []models.OpeningPeriod{
+			{Day: "sat", Open: "12:00", Close: "14:00"},
+			{Day: "sun", Open: "12:00", Close: "14:00"},
+			{Day: "sat", Open: "19:00", Close: "23:00"},
+			{Day: "sun", Open: "19:00", Close: "23:00"},
+		}, false},
+		// A range may wrap around the week, a slot may end after midnight
+		{"sat-mon 20:00-02:00", []models.OpeningPeriod{
+			{Day: "sat", Open: "20:00", Close: "02:00"},
+			{Day: "sun", Open: "20:00", Close: "02:00"},
+			{Day: "mon", Open: "20:00", Close: "02:00"},
+		}, false},
+		{"mon", nil, true},
+		{"someday 12:00-14:00", nil, true},
+		{"mon-someday 12:00-14:00", nil, true},
+		{"mon 12-14", nil, true},
+		{"mon 12:00-25:00", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOpeningHours(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOpeningHours(%q) error = %v, want error %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOpeningHours(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatOpeningHours(t *testing.T) {
+	input := "mon-fri 11:30-14:00,18:00-22:00; sat,sun 12:00-23:00"
+	periods, err := ParseOpeningHours(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mon,tue,wed,thu,fri 11:30-14:00,18:00-22:00; sat,sun 12:00-23:00"
+	if got := FormatOpeningHours(periods); got != want {
+		t.Errorf("FormatOpeningHours() = %q, want %q", got, want)
+	}
+	// The formatted hours parse back to the same periods, grouped by day
+	again, err := ParseOpeningHours(want)
+	if err != nil || FormatOpeningHours(again) != want {
+		t.Errorf("FormatOpeningHours() does not round trip: %q, %v", FormatOpeningHours(again), err)
+	}
+	if got := FormatOpeningHours(nil); got != "" {
+		t.Errorf("FormatOpeningHours(nil) = %q, want empty", got)
+	}
+}
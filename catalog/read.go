@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"mealmate-agent/models"
+)
+
+// csvColumns are the columns ReadCSV understands, name, latitude and longitude are required
+var csvColumns = []string{"id", "name", "cuisine", "dishes", "price_level", "latitude", "longitude", "opening_hours"}
+
+/**
+* @description: Read restaurants from a CSV file with a header row, see csvColumns.
+* Dishes are separated by "|" and opening hours use the ParseOpeningHours format.
+* @param r CSV input
+* @return restaurants, error naming the line of the first malformed row
+ */
+func ReadCSV(r io.Reader) ([]models.Restaurant, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"name", "latitude", "longitude"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing column %q, columns are %s", required, strings.Join(csvColumns, ","))
+		}
+	}
+
+	restaurants := make([]models.Restaurant, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return restaurants, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		restaurant, err := restaurantFromRecord(record, index)
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		restaurants = append(restaurants, restaurant)
+	}
+}
+
+func restaurantFromRecord(record []string, index map[string]int) (models.Restaurant, error) {
+	field := func(name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	restaurant := models.Restaurant{
+		ID:      field("id"),
+		Name:    field("name"),
+		Cuisine: field("cuisine"),
+		Dishes:  make([]string, 0),
+	}
+	for _, dish := range strings.Split(field("dishes"), "|") {
+		if dish = strings.TrimSpace(dish); dish != "" {
+			restaurant.Dishes = append(restaurant.Dishes, dish)
+		}
+	}
+	var err error
+	if price := field("price_level"); price != "" {
+		if restaurant.PriceLevel, err = strconv.Atoi(price); err != nil {
+			return restaurant, fmt.Errorf("invalid price_level %q", price)
+		}
+	}
+	if restaurant.Coordinates.Latitude, err = strconv.ParseFloat(field("latitude"), 64); err != nil {
+		return restaurant, fmt.Errorf("invalid latitude %q", field("latitude"))
+	}
+	if restaurant.Coordinates.Longitude, err = strconv.ParseFloat(field("longitude"), 64); err != nil {
+		return restaurant, fmt.Errorf("invalid longitude %q", field("longitude"))
+	}
+	if restaurant.OpeningHours, err = ParseOpeningHours(field("opening_hours")); err != nil {
+		return restaurant, err
+	}
+	return restaurant, nil
+}
+
+/**
+* @description: Read restaurants from a JSON array of models.Restaurant
+* @param r JSON input
+* @return restaurants, error if the input is not such an array
+ */
+func ReadJSON(r io.Reader) ([]models.Restaurant, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var restaurants []models.Restaurant
+	if err := decoder.Decode(&restaurants); err != nil {
+		return nil, fmt.Errorf("read restaurants json: %w", err)
+	}
+	return restaurants, nil
+}
@@ -0,0 +1,69 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mealmate-agent/models"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "Name,Latitude,Longitude,Dishes,Price_Level,Opening_Hours\n" +
+		"Sakura, 48.86, 2.35, nigiri | ramen |, 2, mon-tue 12:00-14:00\n" +
+		"Bouchon,45.76,4.83,,,\n"
+	got, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Restaurant{
+		{
+			Name:        "Sakura",
+			Dishes:      []string{"nigiri", "ramen"},
+			PriceLevel:  2,
+			Coordinates: models.Coordinates{Latitude: 48.86, Longitude: 2.35},
+			OpeningHours: []models.OpeningPeriod{
+				{Day: "mon", Open: "12:00", Close: "14:00"},
+				{Day: "tue", Open: "12:00", Close: "14:00"},
+			},
+		},
+		{Name: "Bouchon", Dishes: []string{}, Coordinates: models.Coordinates{Latitude: 45.76, Longitude: 4.83}, OpeningHours: []models.OpeningPeriod{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCSV() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "csv header"},
+		{"missing column", "name,latitude\nSakura,48.86\n", `"longitude"`},
+		{"latitude", "name,latitude,longitude\nSakura,north,2.35\n", "csv line 2: invalid latitude"},
+		{"price level", "name,latitude,longitude,price_level\nSakura,48.86,2.35,cheap\n", "invalid price_level"},
+		{"opening hours", "name,latitude,longitude,opening_hours\nSakura,48.86,2.35,always\n", "opening hours"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSV(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadCSV() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	got, err := ReadJSON(strings.NewReader(`[{"name": "Sakura", "coordinates": {"latitude": 48.86, "longitude": 2.35}}]`))
+	if err != nil || len(got) != 1 || got[0].Name != "Sakura" || got[0].Coordinates.Latitude != 48.86 {
+		t.Errorf("ReadJSON() = %+v, %v", got, err)
+	}
+	for _, input := range []string{`{"name": "Sakura"}`, `[{"name": "Sakura", "stars": 3}]`, `not json`} {
+		if _, err := ReadJSON(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadJSON(%s) succeeded", input)
+		}
+	}
+}
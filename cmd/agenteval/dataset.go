@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+// Case is one user, their history, a request to the agent and the properties a good answer has
+type Case struct {
+	Name       string              `json:"name"`
+	UserID     string              `json:"user_id"`
+	Username   string              `json:"username"`
+	Prompt     string              `json:"prompt"`
+	Locale     string              `json:"locale,omitempty"`
+	Location   *models.Coordinates `json:"location,omitempty"`
+	MaxResults int                 `json:"max_results,omitempty"`
+	Timezone   string              `json:"timezone,omitempty"`
+	// Now is the current time of the case, evalNow when empty
+	Now    time.Time      `json:"now,omitempty"`
+	Events []models.Event `json:"events"`
+	// RelevantEventIDs are the history events retrieval should surface for the prompt, used for recall@k
+	RelevantEventIDs []int `json:"relevant_event_ids"`
+	// Exclude lists name fragments (e.g. a cuisine) no recommendation may contain
+	Exclude []string `json:"exclude"`
+	// KnownRestaurants are restaurants outside the history that are still legitimate answers
+	KnownRestaurants []string `json:"known_restaurants"`
+}
+
+// evalNow is the clock of cases without now, fixed so recorded prompts keep matching their cassettes
+var evalNow = time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+// clock returns the current time of the case
+func (c Case) clock() time.Time {
+	if c.Now.IsZero() {
+		return evalNow
+	}
+	return c.Now
+}
+
+// Config is one variant of the agent to evaluate
+type Config struct {
+	Name string `json:"name"`
+	// Embedder is hash or ark
+	Embedder    string `json:"embedder"`
+	EmbedderDim int    `json:"embedder_dim"`
+	// TopK is the retrieval depth, 0 keeps the store default
+	TopK int `json:"top_k"`
+	// PromptFile holds a system prompt template, empty keeps pipeline.DefaultSystemPrompt
+	PromptFile string `json:"prompt_file"`
+
+	systemPrompt string
+}
+
+// defaultConfig is evaluated when no -config is given
+var defaultConfig = Config{Name: "default", Embedder: "hash", EmbedderDim: 256}
+
+func loadCases(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, c := range cases {
+		if c.Name == "" || c.UserID == "" || c.Username == "" || c.Prompt == "" {
+			return nil, fmt.Errorf("case %d: name, user_id, username and prompt are required", i)
+		}
+	}
+	return cases, nil
+}
+
+/**
+* @description: Load an evaluation config, the prompt file is resolved relative to the config file
+* @param path config file, empty for defaultConfig
+* @return the config, error if it cannot be read or is invalid
+ */
+func loadConfig(path string) (Config, error) {
+	if path == "" {
+		return defaultConfig, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	config := defaultConfig
+	// Named after the file unless it sets a name, so two configs never share cassettes
+	config.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	switch config.Embedder {
+	case "hash", "ark":
+	default:
+		return Config{}, fmt.Errorf("%s: unknown embedder %q", path, config.Embedder)
+	}
+	if config.TopK < 0 {
+		return Config{}, fmt.Errorf("%s: top_k must not be negative", path)
+	}
+	if config.PromptFile != "" {
+		promptPath := config.PromptFile
+		if !filepath.IsAbs(promptPath) {
+			promptPath = filepath.Join(filepath.Dir(path), promptPath)
+		}
+		prompt, err := os.ReadFile(promptPath)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: read prompt: %w", path, err)
+		}
+		config.systemPrompt = string(prompt)
+	}
+	return config, nil
+}
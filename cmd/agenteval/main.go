@@ -0,0 +1,296 @@
+// Command agenteval runs a dataset of users through the MealMateAgent and reports recommendation quality:
+// retrieval recall@k, JSON validity, constraint satisfaction, hallucinated restaurants and latency.
+//
+// Evaluate the default configuration against the live chat model (Ark settings from .env or $MEALMATE_CONFIG):
+//
+//	go run ./cmd/agenteval
+//
+// Compare two configurations, e.g. a prompt change, and write the full report:
+//
+//	go run ./cmd/agenteval -config testdata/eval/configs/baseline.json -compare testdata/eval/configs/concise.json -out report.json
+//
+// With -mode record the model answers are captured under -cassettes so the same run can later be repeated offline with -mode replay.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"mealmate-agent/app"
+	"mealmate-agent/config"
+	"mealmate-agent/db"
+	"mealmate-agent/embedder"
+	"mealmate-agent/pipeline"
+	"mealmate-agent/replay"
+	"mealmate-agent/vectorstore"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	ucb "github.com/cloudwego/eino/utils/callbacks"
+)
+
+// Report is written by -out
+type Report struct {
+	Baseline  Summary  `json:"baseline"`
+	Candidate *Summary `json:"candidate,omitempty"`
+}
+
+func main() {
+	datasetPath := flag.String("dataset", "testdata/eval/dataset.json", "evaluation cases")
+	configPath := flag.String("config", "", "baseline config file, defaults to the hash embedder with the default prompt")
+	comparePath := flag.String("compare", "", "candidate config file to compare against the baseline")
+	modeFlag := flag.String("mode", string(replay.ModeOff), "off (live), record or replay")
+	cassetteDir := flag.String("cassettes", "testdata/eval/cassettes", "cassette directory, one subdirectory per config")
+	outPath := flag.String("out", "", "write the full report as JSON to this file")
+	flag.Parse()
+
+	mode, err := replay.ParseMode(*modeFlag)
+	if err != nil {
+		exit(err)
+	}
+	// The live chat model and the ark embedder use the Ark settings of the service configuration
+	serviceConfig, err := config.Resolve()
+	if err != nil {
+		exit(err)
+	}
+	arkConfig := serviceConfig.Ark
+	cases, err := loadCases(*datasetPath)
+	if err != nil {
+		exit(err)
+	}
+	baseline, err := loadConfig(*configPath)
+	if err != nil {
+		exit(err)
+	}
+
+	ctx := context.Background()
+	report := Report{Baseline: evaluate(ctx, arkConfig, baseline, cases, mode, *cassetteDir)}
+	if *comparePath != "" {
+		candidate, err := loadConfig(*comparePath)
+		if err != nil {
+			exit(err)
+		}
+		if candidate.Name == baseline.Name {
+			exit(fmt.Errorf("both configs are named %q", candidate.Name))
+		}
+		summary := evaluate(ctx, arkConfig, candidate, cases, mode, *cassetteDir)
+		report.Candidate = &summary
+	}
+
+	printReport(report)
+	if *outPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*outPath, data, 0o644)
+		}
+		if err != nil {
+			exit(err)
+		}
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
+
+// evaluate runs every case under config, each case gets a fresh store holding only its own history
+func evaluate(ctx context.Context, arkConfig config.ArkConfig, evalConfig Config, cases []Case, mode replay.Mode, cassetteDir string) Summary {
+	results := make([]CaseResult, 0, len(cases))
+	for _, c := range cases {
+		result, err := runCase(ctx, arkConfig, evalConfig, c, mode, filepath.Join(cassetteDir, evalConfig.Name, c.Name+".json"))
+		if err != nil {
+			result.Case = c.Name
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return summarize(evalConfig.Name, results)
+}
+
+func runCase(ctx context.Context, arkConfig config.ArkConfig, evalConfig Config, c Case, mode replay.Mode, cassettePath string) (CaseResult, error) {
+	cassette, err := replay.OpenCassette(cassettePath, mode)
+	if err != nil {
+		return CaseResult{}, err
+	}
+	emb, err := newEmbedder(ctx, arkConfig, evalConfig, cassette)
+	if err != nil {
+		return CaseResult{}, err
+	}
+	var chatModel model.BaseChatModel
+	if mode != replay.ModeReplay {
+		if chatModel, err = pipeline.NewChatModel(ctx, arkConfig.APIKey, arkConfig.ChatModel); err != nil {
+			return CaseResult{}, err
+		}
+	}
+
+	store, err := vectorstore.NewMemoryStore(emb)
+	if err != nil {
+		return CaseResult{}, err
+	}
+	if err := db.NewMilvusDatabase(ctx, store, nil, emb).SyncEventToMilvus(ctx, &c.Events); err != nil {
+		return CaseResult{}, fmt.Errorf("index history: %w", err)
+	}
+	runnable, err := pipeline.BuildMealMateAgent(ctx, store,
+		pipeline.WithChatModel(replay.NewChatModel(chatModel, cassette)),
+		pipeline.WithTopK(evalConfig.TopK),
+		pipeline.WithSystemPrompt(evalConfig.systemPrompt),
+		pipeline.WithClock(c.clock),
+	)
+	if err != nil {
+		return CaseResult{}, err
+	}
+	input, err := sonic.MarshalString(pipeline.RetrieverInput{
+		UserPrompt: c.Prompt,
+		UserID:     c.UserID,
+		Username:   c.Username,
+		Locale:     c.Locale,
+		Location:   c.Location,
+		MaxResults: c.MaxResults,
+		Timezone:   c.Timezone,
+	})
+	if err != nil {
+		return CaseResult{}, err
+	}
+
+	var retrieved []string
+	start := time.Now()
+	// Only the history retriever counts, the schedule retriever reads a wider window of the same history
+	output, err := runnable.Invoke(ctx, input, compose.WithCallbacks(retrievalRecorder(&retrieved)).DesignateNode("UserProfileRetriever"))
+	latency := time.Since(start)
+	if err != nil {
+		return CaseResult{Latency: float64(latency.Microseconds()) / 1000}, err
+	}
+	if err := cassette.Save(); err != nil {
+		return CaseResult{}, err
+	}
+	return score(c, retrieved, output, latency), nil
+}
+
+// newEmbedder builds the config's embedder, ark calls go through the cassette so replay needs no network
+func newEmbedder(ctx context.Context, arkConfig config.ArkConfig, evalConfig Config, cassette *replay.Cassette) (embedding.Embedder, error) {
+	if evalConfig.Embedder == "hash" {
+		return embedder.NewHashEmbedder(evalConfig.EmbedderDim)
+	}
+	var inner embedding.Embedder
+	if cassette.Mode() != replay.ModeReplay {
+		arkEmbedder, err := app.NewArkEmbedder(ctx, arkConfig)
+		if err != nil {
+			return nil, err
+		}
+		inner = arkEmbedder
+	}
+	return replay.NewEmbedder(inner, cassette), nil
+}
+
+// retrievalRecorder collects the IDs of the documents returned by the retriever node
+func retrievalRecorder(ids *[]string) callbacks.Handler {
+	return ucb.NewHandlerHelper().
+		Retriever(&ucb.RetrieverCallbackHandler{
+			OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *retriever.CallbackOutput) context.Context {
+				for _, doc := range output.Docs {
+					*ids = append(*ids, doc.ID)
+				}
+				return ctx
+			},
+		}).
+		Handler()
+}
+
+func printReport(report Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	base := report.Baseline
+	if report.Candidate == nil {
+		fmt.Fprintf(w, "metric\t%s\n", base.Config)
+		for _, row := range metricRows(base.Metrics) {
+			fmt.Fprintf(w, "%s\t%s\n", row.name, row.format(row.value))
+		}
+		fmt.Fprintf(w, "errors\t%d/%d\n", base.Errors, base.Cases)
+		printCaseProblems(w, base)
+		return
+	}
+
+	cand := *report.Candidate
+	fmt.Fprintf(w, "metric\t%s\t%s\tdelta\n", base.Config, cand.Config)
+	candRows := metricRows(cand.Metrics)
+	for i, row := range metricRows(base.Metrics) {
+		delta := row.format(candRows[i].value - row.value)
+		if candRows[i].value > row.value {
+			delta = "+" + delta
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.name, row.format(row.value), row.format(candRows[i].value), delta)
+	}
+	fmt.Fprintf(w, "errors\t%d/%d\t%d/%d\t\n", base.Errors, base.Cases, cand.Errors, cand.Cases)
+	printCaseDiffs(w, base, cand)
+}
+
+type metricRow struct {
+	name   string
+	value  float64
+	format func(float64) string
+}
+
+func metricRows(m Metrics) []metricRow {
+	rate := func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) }
+	ms := func(v float64) string { return fmt.Sprintf("%.0fms", v) }
+	return []metricRow{
+		{"recall@k", m.RecallAtK, func(v float64) string { return fmt.Sprintf("%.3f", v) }},
+		{"json_validity", m.JSONValidity, rate},
+		{"constraint_satisfaction", m.ConstraintSatisfaction, rate},
+		{"hallucination_rate", m.HallucinationRate, rate},
+		{"latency_p50", m.LatencyP50, ms},
+		{"latency_p95", m.LatencyP95, ms},
+	}
+}
+
+// printCaseProblems lists the cases that errored or broke a constraint
+func printCaseProblems(w *tabwriter.Writer, summary Summary) {
+	for _, r := range summary.Results {
+		if problem := caseProblem(r); problem != "" {
+			fmt.Fprintf(w, "\n%s\t%s", r.Case, problem)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// printCaseDiffs lists the cases whose outcome differs between the two configs
+func printCaseDiffs(w *tabwriter.Writer, base, cand Summary) {
+	for i, r := range base.Results {
+		before, after := caseProblem(r), caseProblem(cand.Results[i])
+		if before == after {
+			continue
+		}
+		if before == "" {
+			before = "ok"
+		}
+		if after == "" {
+			after = "ok"
+		}
+		fmt.Fprintf(w, "\n%s\t%s\t%s\t", r.Case, before, after)
+	}
+	fmt.Fprintln(w)
+}
+
+func caseProblem(r CaseResult) string {
+	switch {
+	case r.Error != "":
+		return "error: " + r.Error
+	case len(r.Violations) > 0:
+		return fmt.Sprint(r.Violations)
+	case len(r.Hallucinated) > 0:
+		return fmt.Sprintf("hallucinated %v", r.Hallucinated)
+	}
+	return ""
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+
+	"github.com/bytedance/sonic"
+)
+
+// CaseResult is the outcome of one case under one config
+type CaseResult struct {
+	Case    string  `json:"case"`
+	Error   string  `json:"error,omitempty"`
+	Latency float64 `json:"latency_ms"`
+	// Recall is nil when the case lists no relevant events
+	Recall          *float64 `json:"recall_at_k,omitempty"`
+	ValidJSON       bool     `json:"valid_json"`
+	Violations      []string `json:"violations,omitempty"`
+	Recommendations int      `json:"recommendations"`
+	Hallucinated    []string `json:"hallucinated,omitempty"`
+}
+
+// Summary aggregates the results of one config over the dataset
+type Summary struct {
+	Config  string       `json:"config"`
+	Cases   int          `json:"cases"`
+	Errors  int          `json:"errors"`
+	Metrics Metrics      `json:"metrics"`
+	Results []CaseResult `json:"results"`
+}
+
+// Metrics are the headline numbers compared between configs, rates are in [0,1]
+type Metrics struct {
+	RecallAtK              float64 `json:"recall_at_k"`
+	JSONValidity           float64 `json:"json_validity"`
+	ConstraintSatisfaction float64 `json:"constraint_satisfaction"`
+	HallucinationRate      float64 `json:"hallucination_rate"`
+	LatencyP50             float64 `json:"latency_p50_ms"`
+	LatencyP95             float64 `json:"latency_p95_ms"`
+}
+
+/**
+* @description: Score one agent run against the expectations of its case
+* @param c the case
+* @param retrieved IDs of the documents the retriever returned
+* @param output raw agent output
+* @param latency duration of the agent run
+* @return the case result
+ */
+func score(c Case, retrieved []string, output string, latency time.Duration) CaseResult {
+	result := CaseResult{Case: c.Name, Latency: float64(latency.Microseconds()) / 1000}
+
+	if len(c.RelevantEventIDs) > 0 {
+		got := make(map[string]bool, len(retrieved))
+		for _, id := range retrieved {
+			got[id] = true
+		}
+		hits := 0
+		for _, id := range c.RelevantEventIDs {
+			if got[strconv.Itoa(id)] {
+				hits++
+			}
+		}
+		recall := float64(hits) / float64(len(c.RelevantEventIDs))
+		result.Recall = &recall
+	}
+
+	var recommendations []models.RestaurantRecommendation
+	if err := sonic.UnmarshalString(output, &recommendations); err != nil {
+		result.Violations = append(result.Violations, "output is not a recommendation array")
+		return result
+	}
+	result.ValidJSON = true
+	result.Recommendations = len(recommendations)
+	result.Violations = constraintViolations(c, recommendations)
+
+	known := make(map[string]bool)
+	for _, event := range c.Events {
+		known[normalizeName(event.RestaurantName)] = true
+	}
+	for _, name := range c.KnownRestaurants {
+		known[normalizeName(name)] = true
+	}
+	for _, r := range recommendations {
+		if !known[normalizeName(r.RestaurantName)] {
+			result.Hallucinated = append(result.Hallucinated, r.RestaurantName)
+		}
+	}
+	return result
+}
+
+// constraintViolations lists every requirement of the prompt and the case the recommendations break
+func constraintViolations(c Case, recommendations []models.RestaurantRecommendation) []string {
+	var violations []string
+	maxResults := c.MaxResults
+	if maxResults <= 0 {
+		maxResults = 5
+	}
+	if len(recommendations) < 1 || len(recommendations) > maxResults {
+		violations = append(violations, fmt.Sprintf("got %d recommendations, want 1-%d", len(recommendations), maxResults))
+	}
+	for _, r := range recommendations {
+		violations = append(violations, pipeline.RecommendationViolations(r)...)
+		name := strings.ToLower(r.RestaurantName + " " + r.MainDishes)
+		for _, excluded := range c.Exclude {
+			if strings.Contains(name, strings.ToLower(excluded)) {
+				violations = append(violations, fmt.Sprintf("%s: matches excluded %q", r.RestaurantName, excluded))
+			}
+		}
+	}
+	return violations
+}
+
+// normalizeName makes restaurant names comparable regardless of case and spacing
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+/**
+* @description: Aggregate case results into the config summary
+* @param config config name
+* @param results results of every case
+* @return the summary
+ */
+func summarize(config string, results []CaseResult) Summary {
+	summary := Summary{Config: config, Cases: len(results), Results: results}
+	var recallSum float64
+	var recallCases, valid, satisfied, recommended, hallucinated int
+	latencies := make([]float64, 0, len(results))
+	for _, r := range results {
+		if r.Error != "" {
+			summary.Errors++
+		}
+		latencies = append(latencies, r.Latency)
+		if r.Recall != nil {
+			recallSum += *r.Recall
+			recallCases++
+		}
+		if r.ValidJSON {
+			valid++
+			if len(r.Violations) == 0 {
+				satisfied++
+			}
+		}
+		recommended += r.Recommendations
+		hallucinated += len(r.Hallucinated)
+	}
+	summary.Metrics = Metrics{
+		RecallAtK:              ratio(recallSum, recallCases),
+		JSONValidity:           ratio(float64(valid), len(results)),
+		ConstraintSatisfaction: ratio(float64(satisfied), len(results)),
+		HallucinationRate:      ratio(float64(hallucinated), recommended),
+		LatencyP50:             percentile(latencies, 0.50),
+		LatencyP95:             percentile(latencies, 0.95),
+	}
+	return summary
+}
+
+func ratio(n float64, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return n / float64(d)
+}
+
+// percentile uses the nearest rank method on a copy of values
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
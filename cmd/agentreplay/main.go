@@ -0,0 +1,250 @@
+// Command agentreplay drives the MealMateAgent through recorded scenarios and checks its structured output.
+//
+// Replay (default) serves the embedder and chat model from cassettes and needs no network:
+//
+//	go run ./cmd/agentreplay
+//
+// Record calls Ark with the service configuration (.env, $MEALMATE_CONFIG) and rewrites the cassettes:
+//
+//	go run ./cmd/agentreplay -mode record
+//
+// Model replies are found by the user's request and only served for the exact prompt they were recorded from,
+// after a prompt change replay fails with "record it again" until the cassettes are recorded again. go test runs
+// every scenario that has a cassette and skips the others.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mealmate-agent/app"
+	"mealmate-agent/config"
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+	"mealmate-agent/replay"
+	"mealmate-agent/vectorstore"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// replayNow is the clock of scenarios without now, fixed so recorded prompts keep matching their cassettes
+var replayNow = time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+// Expectation lists the properties a scenario's recommendations must have
+type Expectation struct {
+	MinResults int `json:"min_results"`
+	MaxResults int `json:"max_results"`
+	// IncludeAny requires at least one recommendation whose name contains one of these
+	IncludeAny []string `json:"include_any"`
+	// Exclude forbids recommendations whose name contains one of these
+	Exclude []string `json:"exclude"`
+	// MinRating is the lowest recommendation_rating accepted
+	MinRating float64 `json:"min_rating"`
+	// FromHistory requires every recommendation to name a restaurant of the scenario history
+	FromHistory bool `json:"from_history"`
+}
+
+// Scenario is one user with a history and a request to the agent
+type Scenario struct {
+	Name     string              `json:"name"`
+	UserID   string              `json:"user_id"`
+	Username string              `json:"username"`
+	Prompt   string              `json:"prompt"`
+	Locale   string              `json:"locale"`
+	Location *models.Coordinates `json:"location"`
+	Timezone string              `json:"timezone"`
+	// Now is the current time of the scenario, replayNow when empty
+	Now    time.Time      `json:"now"`
+	Events []models.Event `json:"events"`
+	Expect Expectation    `json:"expect"`
+}
+
+func main() {
+	scenariosPath := flag.String("scenarios", "testdata/replay/scenarios.json", "scenario file")
+	cassetteDir := flag.String("cassettes", "testdata/replay/cassettes", "directory of cassette files, one per scenario")
+	modeFlag := flag.String("mode", string(replay.ModeReplay), "replay or record")
+	run := flag.String("run", "", "only run scenarios whose name contains this")
+	flag.Parse()
+
+	mode, err := replay.ParseMode(*modeFlag)
+	if err != nil || mode == replay.ModeOff {
+		fmt.Fprintln(os.Stderr, "mode must be replay or record")
+		os.Exit(2)
+	}
+	// Recording needs the Ark settings of the service configuration
+	cfg, err := config.Resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	scenarios, err := loadScenarios(*scenariosPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	var innerEmbedder embedding.Embedder
+	var innerModel model.BaseChatModel
+	if mode == replay.ModeRecord {
+		if innerEmbedder, err = app.NewArkEmbedder(ctx, cfg.Ark); err == nil {
+			innerModel, err = pipeline.NewChatModel(ctx, cfg.Ark.APIKey, cfg.Ark.ChatModel)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	failed := 0
+	for _, scenario := range scenarios {
+		if *run != "" && !strings.Contains(scenario.Name, *run) {
+			continue
+		}
+		cassette, err := replay.OpenCassette(filepath.Join(*cassetteDir, scenario.Name+".json"), mode)
+		if err == nil {
+			err = runScenario(ctx, scenario, cassette, innerEmbedder, innerModel)
+		}
+		if err == nil {
+			err = cassette.Save()
+		}
+		if errors.Is(err, replay.ErrFixtureMissing) || errors.Is(err, replay.ErrFixtureStale) {
+			err = fmt.Errorf("%w (record it again with go run ./cmd/agentreplay -mode record -run %s)", err, scenario.Name)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", scenario.Name, err)
+			continue
+		}
+		fmt.Printf("PASS %s\n", scenario.Name)
+	}
+	if failed > 0 {
+		fmt.Printf("%d scenario(s) failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+func loadScenarios(path string) ([]Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenarios []Scenario
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return scenarios, nil
+}
+
+// runScenario runs the agent on a scenario, innerEmbedder and innerModel are only called in record mode
+func runScenario(ctx context.Context, scenario Scenario, cassette *replay.Cassette, innerEmbedder embedding.Embedder, innerModel model.BaseChatModel) error {
+	replayEmbedder := replay.NewEmbedder(innerEmbedder, cassette)
+
+	store, err := vectorstore.NewMemoryStore(replayEmbedder)
+	if err != nil {
+		return err
+	}
+	milvusDB := db.NewMilvusDatabase(ctx, store, nil, replayEmbedder)
+	if err := milvusDB.SyncEventToMilvus(ctx, &scenario.Events); err != nil {
+		return fmt.Errorf("index history: %w", err)
+	}
+
+	now := scenario.Now
+	if now.IsZero() {
+		now = replayNow
+	}
+	runnable, err := pipeline.BuildMealMateAgent(ctx, store,
+		pipeline.WithChatModel(replay.NewChatModel(innerModel, cassette)),
+		pipeline.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		return err
+	}
+	input, err := sonic.MarshalString(pipeline.RetrieverInput{
+		UserPrompt: scenario.Prompt,
+		UserID:     scenario.UserID,
+		Username:   scenario.Username,
+		Locale:     scenario.Locale,
+		Location:   scenario.Location,
+		MaxResults: scenario.Expect.MaxResults,
+		Timezone:   scenario.Timezone,
+	})
+	if err != nil {
+		return err
+	}
+	output, err := runnable.Invoke(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	var recommendations []models.RestaurantRecommendation
+	if err := sonic.UnmarshalString(output, &recommendations); err != nil {
+		return fmt.Errorf("output is not a recommendation array: %w", err)
+	}
+	return check(scenario.Expect, scenario.Events, recommendations)
+}
+
+// check returns an error describing every violated expectation, every recommendation must fill all of its fields
+func check(expect Expectation, history []models.Event, recommendations []models.RestaurantRecommendation) error {
+	var problems []string
+	if len(recommendations) < expect.MinResults {
+		problems = append(problems, fmt.Sprintf("got %d recommendations, want at least %d", len(recommendations), expect.MinResults))
+	}
+	if expect.MaxResults > 0 && len(recommendations) > expect.MaxResults {
+		problems = append(problems, fmt.Sprintf("got %d recommendations, want at most %d", len(recommendations), expect.MaxResults))
+	}
+	visited := make(map[string]bool, len(history))
+	for _, e := range history {
+		visited[strings.ToLower(e.RestaurantName)] = true
+	}
+	seen := make(map[string]bool, len(recommendations))
+	included := len(expect.IncludeAny) == 0
+	for _, r := range recommendations {
+		problems = append(problems, pipeline.RecommendationViolations(r)...)
+		name := strings.ToLower(r.RestaurantName)
+		if strings.TrimSpace(r.MainDishes) == "" {
+			problems = append(problems, fmt.Sprintf("%s: recommendation without main_dishes", r.RestaurantName))
+		}
+		if strings.TrimSpace(r.ShortReason) == "" {
+			problems = append(problems, fmt.Sprintf("%s: recommendation without short_reason", r.RestaurantName))
+		}
+		if r.RecommendationRating < expect.MinRating {
+			problems = append(problems, fmt.Sprintf("%s: rating %.1f below %.1f", r.RestaurantName, r.RecommendationRating, expect.MinRating))
+		}
+		if seen[name] {
+			problems = append(problems, fmt.Sprintf("%s: recommended twice", r.RestaurantName))
+		}
+		seen[name] = true
+		if expect.FromHistory && !visited[name] {
+			problems = append(problems, fmt.Sprintf("%s: not in the history", r.RestaurantName))
+		}
+		for _, excluded := range expect.Exclude {
+			if strings.Contains(name, strings.ToLower(excluded)) {
+				problems = append(problems, fmt.Sprintf("%s: excluded restaurant recommended", r.RestaurantName))
+			}
+		}
+		for _, want := range expect.IncludeAny {
+			if strings.Contains(name, strings.ToLower(want)) {
+				included = true
+			}
+		}
+	}
+	if !included {
+		problems = append(problems, fmt.Sprintf("none of %v recommended", expect.IncludeAny))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mealmate-agent/embedder"
+	"mealmate-agent/models"
+	"mealmate-agent/replay"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	testScenarios = "../../testdata/replay/scenarios.json"
+	testCassettes = "../../testdata/replay/cassettes"
+)
+
+// cannedModel answers every request with the same content
+type cannedModel struct {
+	content string
+}
+
+func (m cannedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.AssistantMessage(m.content, nil), nil
+}
+
+func (m cannedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage(m.content, nil)}), nil
+}
+
+// TestReplay runs the MealMateAgent through every scenario recorded from Ark. A scenario without a cassette is skipped,
+// a failure after a prompt change means it must be recorded again with go run ./cmd/agentreplay -mode record.
+func TestReplay(t *testing.T) {
+	scenarios, err := loadScenarios(testScenarios)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) == 0 {
+		t.Fatal("no scenario")
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			path := filepath.Join(testCassettes, scenario.Name+".json")
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+				t.Skipf("no cassette, record it against Ark with go run ./cmd/agentreplay -mode record -run %s", scenario.Name)
+			}
+			cassette, err := replay.OpenCassette(path, replay.ModeReplay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := runScenario(context.Background(), scenario, cassette, nil, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestReplayDetectsPromptChanges records a scenario and checks that a changed request or prompt fails instead of passing silently
+func TestReplayDetectsPromptChanges(t *testing.T) {
+	ctx := context.Background()
+	scenarios, err := loadScenarios(testScenarios)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := scenarios[0]
+	path := filepath.Join(t.TempDir(), scenario.Name+".json")
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer := `[{"restaurant_name": "` + scenario.Events[0].RestaurantName + `", "recommendation_rating": 4.5, "main_dishes": "Nigiri", "short_reason": "You loved it."}]`
+
+	recording, err := replay.OpenCassette(path, replay.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runScenario(ctx, scenario, recording, emb, cannedModel{answer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recording.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	changedRequest, changedPrompt := scenario, scenario
+	changedRequest.Prompt += " Somewhere new please."
+	// The locale only changes the system prompt, the user's request stays the same
+	changedPrompt.Locale = "de-DE"
+	tests := []struct {
+		name     string
+		scenario Scenario
+		want     error
+	}{
+		{"unchanged", scenario, nil},
+		{"changed request", changedRequest, replay.ErrFixtureMissing},
+		{"changed prompt", changedPrompt, replay.ErrFixtureStale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cassette, err := replay.OpenCassette(path, replay.ModeReplay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := runScenario(ctx, tt.scenario, cassette, nil, nil); !errors.Is(err, tt.want) {
+				t.Errorf("runScenario() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	history := []models.Event{{RestaurantName: "Sushi Zen"}, {RestaurantName: "Napoli Pizza"}}
+	sushi := models.RestaurantRecommendation{RestaurantName: "Sushi Zen", RecommendationRating: 4.5, MainDishes: "Nigiri", ShortReason: "You loved it."}
+	with := func(change func(r *models.RestaurantRecommendation)) models.RestaurantRecommendation {
+		r := sushi
+		change(&r)
+		return r
+	}
+	tests := []struct {
+		name            string
+		expect          Expectation
+		recommendations []models.RestaurantRecommendation
+		want            []string
+	}{
+		{"valid", Expectation{MinResults: 1, MaxResults: 2, IncludeAny: []string{"sushi"}, MinRating: 4, FromHistory: true}, []models.RestaurantRecommendation{sushi}, nil},
+		{"too few", Expectation{MinResults: 1}, nil, []string{"want at least 1"}},
+		{"too many", Expectation{MaxResults: 1}, []models.RestaurantRecommendation{sushi, with(func(r *models.RestaurantRecommendation) { r.RestaurantName = "Napoli Pizza" })}, []string{"want at most 1"}},
+		{"missing dishes", Expectation{}, []models.RestaurantRecommendation{with(func(r *models.RestaurantRecommendation) { r.MainDishes = " " })}, []string{"without main_dishes"}},
+		{"missing reason", Expectation{}, []models.RestaurantRecommendation{with(func(r *models.RestaurantRecommendation) { r.ShortReason = "" })}, []string{"without short_reason"}},
+		{"rating out of range", Expectation{}, []models.RestaurantRecommendation{with(func(r *models.RestaurantRecommendation) { r.RecommendationRating = 6 })}, []string{"outside 0-5"}},
+		{"low rating", Expectation{MinRating: 4.8}, []models.RestaurantRecommendation{sushi}, []string{"below 4.8"}},
+		{"duplicate", Expectation{}, []models.RestaurantRecommendation{sushi, sushi}, []string{"recommended twice"}},
+		{"not in history", Expectation{FromHistory: true}, []models.RestaurantRecommendation{with(func(r *models.RestaurantRecommendation) { r.RestaurantName = "Ramen Ichi" })}, []string{"not in the history"}},
+		{"excluded", Expectation{Exclude: []string{"pizza"}}, []models.RestaurantRecommendation{with(func(r *models.RestaurantRecommendation) { r.RestaurantName = "Napoli Pizza" })}, []string{"excluded restaurant"}},
+		{"not included", Expectation{IncludeAny: []string{"ramen"}}, []models.RestaurantRecommendation{sushi}, []string{"none of [ramen]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(tt.expect, history, tt.recommendations)
+			if (err != nil) != (len(tt.want) > 0) {
+				t.Fatalf("check() = %v, want %v", err, tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("check() = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
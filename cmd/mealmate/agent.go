@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mealmate-agent/config"
+	"mealmate-agent/models"
+	"mealmate-agent/pipeline"
+	"mealmate-agent/vectorstore"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/components/retriever"
+)
+
+func runSearch(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("search", "<query>")
+	userID := fs.String("user", "", "user whose history is searched (required)")
+	topK := fs.Int("k", cfg.VectorStore.TopK, "number of documents to return")
+	fs.Parse(args)
+	query := strings.Join(fs.Args(), " ")
+	if *userID == "" || query == "" {
+		fs.Usage()
+		return fmt.Errorf("search needs -user and a query")
+	}
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	docs, err := application.Store.Retrieve(ctx, query,
+		vectorstore.WithFilter(vectorstore.Filter{UserID: *userID}),
+		retriever.WithTopK(*topK),
+	)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		fmt.Println("no documents found")
+		return nil
+	}
+	for i, doc := range docs {
+		fmt.Printf("%d. [%s] score %.3f\n   %s\n", i+1, doc.ID, doc.Score(), doc.Content)
+	}
+	return nil
+}
+
+func runAsk(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("ask", "<prompt>")
+	userID := fs.String("user", "", "user asking (required)")
+	username := fs.String("name", "", "name the agent addresses the user by (required)")
+	locale := fs.String("locale", "", "language of the answer, e.g. fr-FR")
+	maxResults := fs.Int("max", 0, "maximum number of recommendations, 1-5")
+	timezone := fs.String("timezone", "", "IANA zone of the user, e.g. Europe/Paris, defaults to the zone of their scheduled meals")
+	with := fs.String("with", "", "other members of a group meal, comma separated <id>=<name>")
+	fs.Parse(args)
+	prompt := strings.Join(fs.Args(), " ")
+	if *userID == "" || *username == "" || prompt == "" {
+		fs.Usage()
+		return fmt.Errorf("ask needs -user, -name and a prompt")
+	}
+	participants, err := parseParticipants(*with)
+	if err != nil {
+		return err
+	}
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	input, err := sonic.MarshalString(pipeline.RetrieverInput{
+		UserPrompt: prompt,
+		UserID:     *userID,
+		Username:   *username,
+		Locale:     *locale,
+		MaxResults: *maxResults,
+		Timezone:   *timezone,
+		// A group request when -with is set
+		Participants: participants,
+	})
+	if err != nil {
+		return err
+	}
+	detectColdStart, coldStart := pipeline.DetectColdStart()
+	output, err := application.Agent.Invoke(ctx, input, detectColdStart)
+	if err != nil {
+		return err
+	}
+
+	var recommendations []models.RestaurantRecommendation
+	if err := sonic.UnmarshalString(output, &recommendations); err != nil {
+		fmt.Println(output)
+		return fmt.Errorf("model output is not a recommendation array: %w", err)
+	}
+	if coldStart() {
+		fmt.Println("no history for this user, recommending popular restaurants")
+	}
+	for i, r := range recommendations {
+		fmt.Printf("%d. %s  (%.1f/5)\n", i+1, r.RestaurantName, r.RecommendationRating)
+		if r.MainDishes != "" {
+			fmt.Printf("   dishes: %s\n", r.MainDishes)
+		}
+		if r.ShortReason != "" {
+			fmt.Printf("   why:    %s\n", r.ShortReason)
+		}
+		for _, p := range participants {
+			if fit := r.MemberFit[p.Username]; fit != "" {
+				fmt.Printf("   %s: %s\n", p.Username, fit)
+			}
+		}
+	}
+	return nil
+}
+
+// parseParticipants reads the -with flag of ask, e.g. "42=Sam,43=Alex"
+func parseParticipants(with string) ([]models.Participant, error) {
+	if with == "" {
+		return nil, nil
+	}
+	var participants []models.Participant
+	for _, member := range strings.Split(with, ",") {
+		userID, username, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok || userID == "" || username == "" {
+			return nil, fmt.Errorf("invalid -with member %q, use <id>=<name>", member)
+		}
+		participants = append(participants, models.Participant{UserID: userID, Username: username})
+	}
+	return participants, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mealmate-agent/catalog"
+	"mealmate-agent/config"
+	"mealmate-agent/models"
+)
+
+func runCatalog(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("catalog needs a subcommand: import or search")
+	}
+	switch action := args[0]; action {
+	case "import":
+		return runCatalogImport(ctx, cfg, args[1:])
+	case "search":
+		return runCatalogSearch(ctx, cfg, args[1:])
+	default:
+		return fmt.Errorf("unknown catalog subcommand %q, use import or search", action)
+	}
+}
+
+func runCatalogImport(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("catalog import", "")
+	in := fs.String("in", "", "CSV or JSON file of restaurants (required)")
+	format := fs.String("format", "", "csv or json, guessed from the file extension when empty")
+	fs.Parse(args)
+	if *in == "" {
+		fs.Usage()
+		return fmt.Errorf("catalog import needs -in")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*in)), ".")
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var restaurants []models.Restaurant
+	switch *format {
+	case "csv":
+		restaurants, err = catalog.ReadCSV(file)
+	case "json":
+		restaurants, err = catalog.ReadJSON(file)
+	default:
+		return fmt.Errorf("unknown format %q, use csv or json", *format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", *in, err)
+	}
+
+	restaurantCatalog, closeApp, err := openCatalog(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	count, err := restaurantCatalog.Import(ctx, restaurants)
+	fmt.Printf("imported %d restaurants\n", count)
+	return err
+}
+
+func runCatalogSearch(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("catalog search", "<query>")
+	topK := fs.Int("k", cfg.VectorStore.CatalogTopK, "number of restaurants to return")
+	var near coordinatesFlag
+	fs.Var(&near, "near", "prefer restaurants around this latitude,longitude")
+	fs.Parse(args)
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		fs.Usage()
+		return fmt.Errorf("catalog search needs a query")
+	}
+
+	restaurantCatalog, closeApp, err := openCatalog(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	docs, err := restaurantCatalog.Search(ctx, query, near.coordinates, *topK)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		fmt.Println("no restaurants found")
+		return nil
+	}
+	for i, doc := range docs {
+		restaurant, err := catalog.FromDocument(doc)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d. [%s] %s  score %.3f\n", i+1, restaurant.ID, restaurant.Name, doc.Score())
+		if restaurant.Cuisine != "" || len(restaurant.Dishes) > 0 {
+			fmt.Printf("   %s: %s\n", restaurant.Cuisine, strings.Join(restaurant.Dishes, ", "))
+		}
+		if hours := catalog.FormatOpeningHours(restaurant.OpeningHours); hours != "" {
+			fmt.Printf("   open %s\n", hours)
+		}
+	}
+	return nil
+}
+
+// openCatalog opens the application and returns its catalog, failing when the catalog is disabled
+func openCatalog(ctx context.Context, cfg *config.Config) (*catalog.Catalog, func(), error) {
+	if cfg.VectorStore.CatalogTopK <= 0 {
+		return nil, nil, fmt.Errorf("the catalog is disabled, set vector_store.catalog_top_k")
+	}
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return application.Catalog, closeApp, nil
+}
+
+// coordinatesFlag parses "latitude,longitude"
+type coordinatesFlag struct {
+	coordinates *models.Coordinates
+}
+
+func (f *coordinatesFlag) String() string {
+	if f.coordinates == nil {
+		return ""
+	}
+	return fmt.Sprintf("%g,%g", f.coordinates.Latitude, f.coordinates.Longitude)
+}
+
+func (f *coordinatesFlag) Set(s string) error {
+	var c models.Coordinates
+	if _, err := fmt.Sscanf(s, "%g,%g", &c.Latitude, &c.Longitude); err != nil {
+		return fmt.Errorf("not latitude,longitude: %q", s)
+	}
+	f.coordinates = &c
+	return nil
+}
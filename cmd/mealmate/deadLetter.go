@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mealmate-agent/config"
+)
+
+// intList collects a repeatable integer flag
+type intList []int
+
+func (l *intList) String() string {
+	parts := make([]string, 0, len(*l))
+	for _, v := range *l {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *intList) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("not an event id: %q", s)
+	}
+	*l = append(*l, v)
+	return nil
+}
+
+func runDeadLetter(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("deadletter needs a subcommand: list, retry or drop")
+	}
+	action := args[0]
+	fs := newFlagSet("deadletter "+action, "")
+	var ids intList
+	if action == "retry" || action == "drop" {
+		fs.Var(&ids, "id", "event id, repeatable")
+	}
+	fs.Parse(args[1:])
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	switch action {
+	case "list":
+		letters, err := application.Database.DeadLetters.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(letters) == 0 {
+			fmt.Println("no dead letters")
+			return nil
+		}
+		for _, letter := range letters {
+			fmt.Printf("%d  user %s  attempts %d  failed %s\n   %s\n",
+				letter.Event.ID, letter.Event.UserID, letter.Attempts, letter.FailedAt.Format(time.RFC3339), letter.Error)
+		}
+		return nil
+	case "retry":
+		count, err := application.Database.RetryDeadLetters(ctx, ids)
+		fmt.Printf("indexed %d events\n", count)
+		return err
+	case "drop":
+		if len(ids) == 0 {
+			return fmt.Errorf("drop needs at least one -id")
+		}
+		if err := application.Database.DeadLetters.Remove(ctx, ids); err != nil {
+			return err
+		}
+		fmt.Printf("dropped %d dead letters\n", len(ids))
+		return nil
+	default:
+		return fmt.Errorf("unknown deadletter subcommand %q", action)
+	}
+}
@@ -0,0 +1,127 @@
+// Command mealmate operates the service directly through the db and pipeline packages.
+// It reads the same configuration as the server, settings can be overridden with flags before the command.
+//
+//	mealmate [-config mealmate.yaml] [-<setting> value]... <command> [flags]
+//	mealmate sync -user <id> | -all | -since <date>
+//	mealmate search -user <id> [-k 3] <query>
+//	mealmate ask -user <id> -name <name> [-locale fr-FR] [-max 5] [-with <id>=<name>,...] <prompt>
+//	mealmate reindex [-user <id>]
+//	mealmate stats [-user <id>]
+//	mealmate deadletter list | retry [-id <event id>]... | drop -id <event id>...
+//	mealmate catalog import -in restaurants.csv|restaurants.json | search [-k 8] [-near lat,lon] <query>
+//	mealmate generate [-seed 42] [-users 10] [-events 8] [-cities new_york,london] [-out events.jsonl]
+//	mealmate seed [-in events.jsonl] [-into index|source]
+//	mealmate profile -user <id> | -all [-rebuild]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"mealmate-agent/app"
+	"mealmate-agent/config"
+)
+
+// command is one subcommand, run receives the arguments after its name
+type command struct {
+	summary string
+	run     func(ctx context.Context, cfg *config.Config, args []string) error
+	// offline commands connect to no backend, so their configuration is not validated
+	offline bool
+}
+
+var commands = map[string]command{
+	"sync":       {"index events from the event source", runSync, false},
+	"search":     {"show the history documents retrieved for a query", runSearch, false},
+	"ask":        {"invoke the agent and print its recommendations", runAsk, false},
+	"reindex":    {"rebuild the index from the event source", runReindex, false},
+	"stats":      {"compare the index with the event source", runStats, false},
+	"deadletter": {"list, retry or drop events that failed to index", runDeadLetter, false},
+	"catalog":    {"import restaurants into the catalog or search it", runCatalog, false},
+	"generate":   {"write a synthetic event dataset as JSONL", runGenerate, true},
+	"seed":       {"load a JSONL dataset into the index or the event source", runSeed, false},
+	"profile":    {"refresh taste profiles with the chat model and print them", runProfile, false},
+}
+
+// commandOrder is the order of the usage text
+var commandOrder = []string{"sync", "search", "ask", "reindex", "stats", "deadletter", "catalog", "generate", "seed", "profile"}
+
+func main() {
+	fs := flag.NewFlagSet("mealmate", flag.ExitOnError)
+	loader := config.NewLoader(fs)
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintln(os.Stderr, "\nglobal flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+	if fs.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", fs.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	load := loader.Load
+	if cmd.offline {
+		load = loader.Resolve
+	}
+	cfg, err := load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := cmd.run(ctx, cfg, fs.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: mealmate [global flags] <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun mealmate -h for the global flags, mealmate <command> -h for the flags of a command")
+}
+
+// newFlagSet creates the flag set of a subcommand, its usage line lists the positional arguments
+func newFlagSet(name, positional string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mealmate %s [flags] %s\n", name, positional)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+/**
+* @description: Build the application like the server does, without starting the auto sync
+* @param ctx context.Context
+* @param cfg service configuration
+* @return the application and a function closing its clients, error if a component failed to initialize
+ */
+func openApp(ctx context.Context, cfg *config.Config) (*app.App, func(), error) {
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeApp := func() {
+		if err := application.Close(context.WithoutCancel(ctx)); err != nil {
+			fmt.Fprintln(os.Stderr, "close:", err)
+		}
+	}
+	return application, closeApp, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"mealmate-agent/config"
+	"mealmate-agent/db"
+
+	"github.com/bytedance/sonic"
+)
+
+func runProfile(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("profile", "")
+	userID := fs.String("user", "", "refresh the taste profile of one user")
+	all := fs.Bool("all", false, "refresh the taste profile of every user with events")
+	rebuild := fs.Bool("rebuild", false, "summarise the whole history again instead of the events after the profile")
+	fs.Parse(args)
+	if (*userID == "") == !*all {
+		fs.Usage()
+		return fmt.Errorf("profile needs exactly one of -user or -all")
+	}
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+	if application.Profiler == nil {
+		return fmt.Errorf("taste profiles are off, they need taste_profiles.kind jsonl and ark.api_key")
+	}
+
+	users := []string{*userID}
+	if *all {
+		events, err := application.Source.ListSince(ctx, db.SyncCursor{})
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		users = users[:0]
+		for _, event := range events {
+			if event.UserID != "" && !seen[event.UserID] {
+				seen[event.UserID] = true
+				users = append(users, event.UserID)
+			}
+		}
+		sort.Strings(users)
+	}
+	for _, user := range users {
+		profile, err := application.Profiler.Refresh(ctx, user, *rebuild)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", user, err)
+		}
+		if profile == nil {
+			fmt.Printf("user %s has no event\n", user)
+			continue
+		}
+		out, err := sonic.ConfigStd.MarshalIndent(profile, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+	return nil
+}
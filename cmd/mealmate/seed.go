@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"mealmate-agent/config"
+	"mealmate-agent/db"
+	"mealmate-agent/fixtures"
+	"mealmate-agent/models"
+)
+
+// runGenerate writes a synthetic dataset as JSONL, it needs no backend
+func runGenerate(ctx context.Context, cfg *config.Config, args []string) error {
+	defaults := fixtures.DefaultGeneratorConfig()
+	fs := newFlagSet("generate", "")
+	seed := fs.Uint64("seed", defaults.Seed, "random seed, the same flags always give the same dataset")
+	users := fs.Int("users", defaults.Users, "number of users")
+	events := fs.Int("events", defaults.EventsPerUser, "number of events per user")
+	cities := fs.String("cities", strings.Join(defaults.Cities, ","), "comma separated cities, one of "+strings.Join(fixtures.CityKeys(), ", "))
+	start := fs.String("start", defaults.Start.Format("2006-01-02"), "earliest creation date (2006-01-02 or RFC 3339)")
+	days := fs.Int("days", defaults.Days, "number of days over which events are created")
+	firstID := fs.Int("first-id", defaults.FirstID, "ID of the first event")
+	radius := fs.Float64("radius", defaults.RadiusKm, "distance in km from the city center where restaurants are placed")
+	out := fs.String("out", "", "output file, standard output when empty")
+	fs.Parse(args)
+
+	from, err := parseDate(*start)
+	if err != nil {
+		return err
+	}
+	generated, err := fixtures.Generate(fixtures.GeneratorConfig{
+		Seed:                  *seed,
+		Users:                 *users,
+		EventsPerUser:         *events,
+		Cities:                strings.Split(*cities, ","),
+		Start:                 from,
+		Days:                  *days,
+		FirstID:               *firstID,
+		RadiusKm:              *radius,
+		RestaurantsPerCuisine: defaults.RestaurantsPerCuisine,
+	})
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := fixtures.WriteJSONL(w, generated); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "wrote %d events to %s\n", len(generated), *out)
+	}
+	return nil
+}
+
+// runSeed loads a JSONL dataset into the vector store or the event source
+func runSeed(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("seed", "")
+	in := fs.String("in", "", "JSONL file written by mealmate generate, standard input when empty")
+	into := fs.String("into", "index", "where to load the events: index to embed them into the vector store, source to insert them into the event source")
+	fs.Parse(args)
+
+	if *into != "index" && *into != "source" {
+		return fmt.Errorf("unknown -into %q, use index or source", *into)
+	}
+	events, err := readEvents(*in)
+	if err != nil {
+		return err
+	}
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	if *into == "index" {
+		count, err := application.Database.IndexEvents(ctx, events)
+		fmt.Printf("indexed %d events\n", count)
+		return err
+	}
+	writer, ok := application.Source.(db.EventWriter)
+	if !ok {
+		return fmt.Errorf("event source %q does not support inserting events", cfg.EventSource.Kind)
+	}
+	if err := writer.InsertEvents(ctx, events); err != nil {
+		return err
+	}
+	fmt.Printf("inserted %d events\n", len(events))
+	return nil
+}
+
+func readEvents(path string) ([]models.Event, error) {
+	if path == "" {
+		return fixtures.ReadJSONL(os.Stdin)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	events, err := fixtures.ReadJSONL(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return events, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mealmate-agent/config"
+	"mealmate-agent/models"
+)
+
+func runSync(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("sync", "")
+	userID := fs.String("user", "", "sync the events of one user")
+	all := fs.Bool("all", false, "sync every event")
+	since := fs.String("since", "", "sync events created at or after this date (2006-01-02 or RFC 3339)")
+	fs.Parse(args)
+
+	selected := 0
+	for _, set := range []bool{*userID != "", *all, *since != ""} {
+		if set {
+			selected++
+		}
+	}
+	if selected != 1 {
+		return fmt.Errorf("sync needs exactly one of -user, -all or -since")
+	}
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	var count int
+	switch {
+	case *userID != "":
+		count, err = application.Database.ManuallySyncDatabase(ctx, models.SyncConfig{UserID: *userID})
+	case *all:
+		count, err = application.Database.SyncSince(ctx, time.Time{})
+	default:
+		var from time.Time
+		if from, err = parseDate(*since); err != nil {
+			return err
+		}
+		count, err = application.Database.SyncSince(ctx, from)
+	}
+	fmt.Printf("indexed %d events\n", count)
+	return err
+}
+
+func runReindex(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("reindex", "")
+	userID := fs.String("user", "", "only rebuild the documents of this user")
+	fs.Parse(args)
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	removed, indexed, err := application.Database.Reindex(ctx, *userID)
+	fmt.Printf("removed %d documents, indexed %d events\n", removed, indexed)
+	return err
+}
+
+func runStats(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("stats", "")
+	userID := fs.String("user", "", "only count the events of this user")
+	fs.Parse(args)
+
+	application, closeApp, err := openApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	stats, err := application.Database.Stats(ctx, *userID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("indexed documents   %d\n", stats.Indexed)
+	fmt.Printf("source events       %d\n", stats.SourceEvents)
+	fmt.Printf("missing from index  %d\n", stats.MissingFromIndex)
+	fmt.Printf("dead letters        %d\n", stats.DeadLetters)
+	return nil
+}
+
+// parseDate accepts a calendar date in UTC or a full RFC 3339 timestamp
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use 2006-01-02 or RFC 3339", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,282 @@
+// Package config holds the typed service configuration, loaded from defaults, a YAML file, the environment and flags
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Ark         ArkConfig         `yaml:"ark"`
+	Embedder    EmbedderConfig    `yaml:"embedder"`
+	VectorStore VectorStoreConfig `yaml:"vector_store"`
+	EventSource EventSourceConfig `yaml:"event_source"`
+	Sync        SyncConfig        `yaml:"sync"`
+	Agent       AgentConfig       `yaml:"agent"`
+	Feedback    FeedbackConfig    `yaml:"feedback"`
+	Preferences PreferencesConfig `yaml:"preferences"`
+	Profiles    ProfilesConfig    `yaml:"taste_profiles"`
+	Audit       AuditConfig       `yaml:"audit"`
+	Telemetry   TelemetryConfig   `yaml:"telemetry"`
+}
+
+type ServerConfig struct {
+	Address string `yaml:"address"`
+	// DrainTimeout bounds how long in-flight HTTP requests may run after shutdown is requested
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	// ShutdownTimeout bounds the whole shutdown sequence after the HTTP server has drained
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type ArkConfig struct {
+	APIKey        string `yaml:"api_key"`
+	ChatModel     string `yaml:"chat_model"`
+	EmbedderModel string `yaml:"embedder_model"`
+}
+
+type EmbedderConfig struct {
+	// Kind is ark, or hash for the deterministic offline embedder
+	Kind string `yaml:"kind"`
+	// Dim is the vector dimension, it must match the milvus collection
+	Dim int `yaml:"dim"`
+}
+
+type VectorStoreConfig struct {
+	// Kind is milvus, or memory for an in-process store
+	Kind string `yaml:"kind"`
+	// TopK is how many history documents are retrieved per request
+	TopK int `yaml:"top_k"`
+	// CatalogTopK is how many catalog restaurants are offered to the model per request, 0 disables the catalog
+	CatalogTopK int          `yaml:"catalog_top_k"`
+	Milvus      MilvusConfig `yaml:"milvus"`
+}
+
+type MilvusConfig struct {
+	Address    string `yaml:"address"`
+	DBName     string `yaml:"db_name"`
+	Collection string `yaml:"collection"`
+	// CatalogCollection holds the restaurant catalog
+	CatalogCollection string `yaml:"catalog_collection"`
+	// SearchEf is the HNSW search breadth, it must be at least TopK and CatalogTopK
+	SearchEf int `yaml:"search_ef"`
+}
+
+type EventSourceConfig struct {
+	// Kind is supabase, postgres or jsonl
+	Kind        string         `yaml:"kind"`
+	Table       string         `yaml:"table"`
+	Supabase    SupabaseConfig `yaml:"supabase"`
+	PostgresDSN string         `yaml:"postgres_dsn"`
+	JSONLPath   string         `yaml:"jsonl_path"`
+}
+
+type SupabaseConfig struct {
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+}
+
+type SyncConfig struct {
+	// Interval is how often the event source is polled for new events
+	Interval       time.Duration `yaml:"interval"`
+	DeadLetterPath string        `yaml:"dead_letter_path"`
+	// CursorPath keeps the last synced event so a restart resumes from it
+	CursorPath string `yaml:"cursor_path"`
+}
+
+type AgentConfig struct {
+	// Guard is drop or flag for recommended restaurants that match no known place, or off
+	Guard string `yaml:"guard"`
+	// GuardMinValid is how many valid recommendations the guard wants before it asks the model again
+	GuardMinValid int `yaml:"guard_min_valid"`
+}
+
+type FeedbackConfig struct {
+	// RecommendationsPath keeps every recommendation returned by the API, feedback refers to them by ID
+	RecommendationsPath string `yaml:"recommendations_path"`
+	Path                string `yaml:"path"`
+}
+
+type PreferencesConfig struct {
+	// Path keeps the preferences set through /v1/users/:user_id/preferences
+	Path string `yaml:"path"`
+}
+
+type ProfilesConfig struct {
+	// Kind is jsonl or off, which leaves the prompt with the retrieved events only
+	Kind string `yaml:"kind"`
+	Path string `yaml:"path"`
+	// RefreshDelay gathers the events of a sync before the profiles of their users are refreshed
+	RefreshDelay time.Duration `yaml:"refresh_delay"`
+}
+
+type AuditConfig struct {
+	// Kind is jsonl, supabase, which uses the event_source.supabase credentials, or off
+	Kind  string `yaml:"kind"`
+	Path  string `yaml:"path"`
+	Table string `yaml:"table"`
+	// Retention is how long traces are kept, 0 keeps them forever
+	Retention time.Duration `yaml:"retention"`
+	// AdminToken is the bearer token of GET /v1/audit/:request_id, the route is not served without one
+	AdminToken string `yaml:"admin_token"`
+}
+
+type TelemetryConfig struct {
+	// TracesExporter is none, stdout or otlp, the otlp exporter reads the standard OTEL_EXPORTER_OTLP_* variables
+	TracesExporter string `yaml:"traces_exporter"`
+}
+
+/**
+* @description: Get the configuration used when nothing overrides it
+* @return the default configuration
+ */
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:         "127.0.0.1:8080",
+			DrainTimeout:    10 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Embedder: EmbedderConfig{
+			Kind: "ark",
+			Dim:  2560,
+		},
+		VectorStore: VectorStoreConfig{
+			Kind:        "milvus",
+			TopK:        3,
+			CatalogTopK: 8,
+			Milvus: MilvusConfig{
+				Address:           "localhost:19530",
+				CatalogCollection: "restaurant_catalog",
+				SearchEf:          10,
+			},
+		},
+		EventSource: EventSourceConfig{
+			Kind:  "supabase",
+			Table: "event",
+		},
+		Sync: SyncConfig{
+			Interval:       time.Minute,
+			DeadLetterPath: "deadletter.jsonl",
+			CursorPath:     "synccursor.jsonl",
+		},
+		Agent: AgentConfig{
+			Guard:         "drop",
+			GuardMinValid: 1,
+		},
+		Feedback: FeedbackConfig{
+			RecommendationsPath: "recommendations.jsonl",
+			Path:                "feedback.jsonl",
+		},
+		Preferences: PreferencesConfig{
+			Path: "preferences.jsonl",
+		},
+		Profiles: ProfilesConfig{
+			Kind:         "jsonl",
+			Path:         "profiles.jsonl",
+			RefreshDelay: 30 * time.Second,
+		},
+		Audit: AuditConfig{
+			Kind:      "jsonl",
+			Path:      "audit.jsonl",
+			Table:     "agent_trace",
+			Retention: 30 * 24 * time.Hour,
+		},
+		Telemetry: TelemetryConfig{
+			TracesExporter: "none",
+		},
+	}
+}
+
+/**
+* @description: Validate the configuration, reporting every problem at once
+* @return nil if valid, error listing each invalid setting
+ */
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s must be one of %v, got %q", key, allowed, value))
+	}
+
+	check(c.Server.Address != "", "server.address is required")
+	check(c.Server.DrainTimeout > 0, "server.drain_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+
+	// Without a key the service runs offline, the agent then answers with an error
+	check(c.Ark.APIKey == "" || c.Ark.ChatModel != "", "ark.chat_model is required with ark.api_key")
+
+	oneOf("embedder.kind", c.Embedder.Kind, "ark", "hash")
+	check(c.Embedder.Dim > 0, "embedder.dim must be positive")
+	if c.Embedder.Kind == "ark" {
+		check(c.Ark.APIKey != "", "ark.api_key is required by the ark embedder")
+		check(c.Ark.EmbedderModel != "", "ark.embedder_model is required by the ark embedder")
+	}
+
+	oneOf("vector_store.kind", c.VectorStore.Kind, "milvus", "memory")
+	check(c.VectorStore.TopK > 0, "vector_store.top_k must be positive")
+	check(c.VectorStore.CatalogTopK >= 0, "vector_store.catalog_top_k must not be negative")
+	if c.VectorStore.Kind == "milvus" {
+		check(c.VectorStore.Milvus.Address != "", "vector_store.milvus.address is required")
+		check(c.VectorStore.Milvus.Collection != "", "vector_store.milvus.collection is required")
+		check(c.VectorStore.Milvus.SearchEf >= c.VectorStore.TopK, "vector_store.milvus.search_ef must be at least vector_store.top_k")
+		if c.VectorStore.CatalogTopK > 0 {
+			check(c.VectorStore.Milvus.CatalogCollection != "", "vector_store.milvus.catalog_collection is required when vector_store.catalog_top_k is set")
+			check(c.VectorStore.Milvus.CatalogCollection != c.VectorStore.Milvus.Collection, "vector_store.milvus.catalog_collection must differ from vector_store.milvus.collection")
+			check(c.VectorStore.Milvus.SearchEf >= c.VectorStore.CatalogTopK, "vector_store.milvus.search_ef must be at least vector_store.catalog_top_k")
+		}
+	}
+
+	oneOf("event_source.kind", c.EventSource.Kind, "supabase", "postgres", "jsonl")
+	check(c.EventSource.Table != "", "event_source.table is required")
+	switch c.EventSource.Kind {
+	case "supabase":
+		check(c.EventSource.Supabase.URL != "", "event_source.supabase.url is required")
+		check(c.EventSource.Supabase.APIKey != "", "event_source.supabase.api_key is required")
+	case "postgres":
+		check(c.EventSource.PostgresDSN != "", "event_source.postgres_dsn is required")
+	case "jsonl":
+		check(c.EventSource.JSONLPath != "", "event_source.jsonl_path is required")
+	}
+
+	check(c.Sync.Interval > 0, "sync.interval must be positive")
+	check(c.Sync.DeadLetterPath != "", "sync.dead_letter_path is required")
+	check(c.Sync.CursorPath != "", "sync.cursor_path is required")
+
+	oneOf("agent.guard", c.Agent.Guard, "drop", "flag", "off")
+	check(c.Agent.GuardMinValid >= 1 && c.Agent.GuardMinValid <= 5, "agent.guard_min_valid must be between 1 and 5")
+
+	check(c.Feedback.RecommendationsPath != "", "feedback.recommendations_path is required")
+	check(c.Feedback.Path != "", "feedback.path is required")
+	check(c.Preferences.Path != "", "preferences.path is required")
+
+	oneOf("taste_profiles.kind", c.Profiles.Kind, "jsonl", "off")
+	if c.Profiles.Kind == "jsonl" {
+		check(c.Profiles.Path != "", "taste_profiles.path is required")
+		check(c.Profiles.RefreshDelay >= 0, "taste_profiles.refresh_delay must not be negative")
+	}
+
+	oneOf("audit.kind", c.Audit.Kind, "jsonl", "supabase", "off")
+	check(c.Audit.Retention >= 0, "audit.retention must not be negative")
+	switch c.Audit.Kind {
+	case "jsonl":
+		check(c.Audit.Path != "", "audit.path is required")
+	case "supabase":
+		check(c.Audit.Table != "", "audit.table is required")
+		check(c.EventSource.Supabase.URL != "", "event_source.supabase.url is required by the supabase audit log")
+		check(c.EventSource.Supabase.APIKey != "", "event_source.supabase.api_key is required by the supabase audit log")
+	}
+
+	oneOf("telemetry.traces_exporter", c.Telemetry.TracesExporter, "none", "stdout", "otlp")
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// offline is a valid configuration without any credential
+	offline := func(c *Config) {
+		c.Embedder.Kind = "hash"
+		c.VectorStore.Kind = "memory"
+		c.EventSource.Kind = "jsonl"
+		c.EventSource.JSONLPath = "events.jsonl"
+		c.Ark.ChatModel = ""
+	}
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		// want is a substring of the error, empty for a valid configuration
+		want string
+	}{
+		{"offline", offline, ""},
+		{"offline with chat model", func(c *Config) {
+			offline(c)
+			c.Ark.APIKey, c.Ark.ChatModel = "key", "model"
+		}, ""},
+		{"key without chat model", func(c *Config) {
+			offline(c)
+			c.Ark.APIKey = "key"
+		}, "ark.chat_model is required"},
+		{"ark embedder without key", func(c *Config) {
+			offline(c)
+			c.Embedder.Kind = "ark"
+			c.Ark.EmbedderModel = "embedder"
+		}, "ark.api_key is required by the ark embedder"},
+		{"unknown embedder", func(c *Config) {
+			offline(c)
+			c.Embedder.Kind = "word2vec"
+		}, "embedder.kind must be one of"},
+		{"milvus search_ef below top_k", func(c *Config) {
+			offline(c)
+			c.VectorStore.Kind = "milvus"
+			c.VectorStore.TopK = 20
+		}, "search_ef must be at least vector_store.top_k"},
+		{"no sync cursor path", func(c *Config) {
+			offline(c)
+			c.Sync.CursorPath = ""
+		}, "sync.cursor_path is required"},
+		{"supabase audit without credentials", func(c *Config) {
+			offline(c)
+			c.Audit.Kind = "supabase"
+		}, "required by the supabase audit log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.modify(c)
+			err := c.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate() error = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
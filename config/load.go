@@ -0,0 +1,239 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secret values in Dump
+const redacted = "<redacted>"
+
+// setting binds one configuration field to its key, environment variable and flag
+type setting struct {
+	// key is the dotted YAML path, also used as the flag name
+	key    string
+	env    string
+	secret bool
+	// target points to a string, int or time.Duration field
+	target any
+}
+
+// settings lists every configurable field, the order is the order of Dump
+func (c *Config) settings() []setting {
+	return []setting{
+		{"server.address", "LISTEN_ADDRESS", false, &c.Server.Address},
+		{"server.drain_timeout", "SERVER_DRAIN_TIMEOUT", false, &c.Server.DrainTimeout},
+		{"server.shutdown_timeout", "SERVER_SHUTDOWN_TIMEOUT", false, &c.Server.ShutdownTimeout},
+		{"ark.api_key", "ARK_API_KEY", true, &c.Ark.APIKey},
+		{"ark.chat_model", "ARK_CHAT_MODEL", false, &c.Ark.ChatModel},
+		{"ark.embedder_model", "ARK_EMBEDDER_MODEL", false, &c.Ark.EmbedderModel},
+		{"embedder.kind", "EMBEDDER", false, &c.Embedder.Kind},
+		{"embedder.dim", "EMBEDDER_DIM", false, &c.Embedder.Dim},
+		{"vector_store.kind", "VECTOR_STORE", false, &c.VectorStore.Kind},
+		{"vector_store.top_k", "RETRIEVER_TOP_K", false, &c.VectorStore.TopK},
+		{"vector_store.catalog_top_k", "CATALOG_TOP_K", false, &c.VectorStore.CatalogTopK},
+		{"vector_store.milvus.address", "MILVUS_ADDRESS", false, &c.VectorStore.Milvus.Address},
+		{"vector_store.milvus.db_name", "MILVUS_DBNAME", false, &c.VectorStore.Milvus.DBName},
+		{"vector_store.milvus.collection", "MILVUS_EVENT_COLLECTION", false, &c.VectorStore.Milvus.Collection},
+		{"vector_store.milvus.catalog_collection", "MILVUS_CATALOG_COLLECTION", false, &c.VectorStore.Milvus.CatalogCollection},
+		{"vector_store.milvus.search_ef", "MILVUS_SEARCH_EF", false, &c.VectorStore.Milvus.SearchEf},
+		{"event_source.kind", "EVENT_SOURCE", false, &c.EventSource.Kind},
+		{"event_source.table", "EVENT_TABLE", false, &c.EventSource.Table},
+		{"event_source.supabase.url", "SUPABASE_API_URL", false, &c.EventSource.Supabase.URL},
+		{"event_source.supabase.api_key", "SUPABASE_API_KEY", true, &c.EventSource.Supabase.APIKey},
+		{"event_source.postgres_dsn", "POSTGRES_DSN", true, &c.EventSource.PostgresDSN},
+		{"event_source.jsonl_path", "EVENTS_JSONL_PATH", false, &c.EventSource.JSONLPath},
+		{"sync.interval", "SYNC_INTERVAL", false, &c.Sync.Interval},
+		{"sync.dead_letter_path", "DEAD_LETTER_PATH", false, &c.Sync.DeadLetterPath},
+		{"sync.cursor_path", "SYNC_CURSOR_PATH", false, &c.Sync.CursorPath},
+		{"agent.guard", "AGENT_GUARD", false, &c.Agent.Guard},
+		{"agent.guard_min_valid", "AGENT_GUARD_MIN_VALID", false, &c.Agent.GuardMinValid},
+		{"feedback.recommendations_path", "RECOMMENDATIONS_PATH", false, &c.Feedback.RecommendationsPath},
+		{"feedback.path", "FEEDBACK_PATH", false, &c.Feedback.Path},
+		{"preferences.path", "PREFERENCES_PATH", false, &c.Preferences.Path},
+		{"taste_profiles.kind", "TASTE_PROFILES", false, &c.Profiles.Kind},
+		{"taste_profiles.path", "TASTE_PROFILES_PATH", false, &c.Profiles.Path},
+		{"taste_profiles.refresh_delay", "TASTE_PROFILES_REFRESH_DELAY", false, &c.Profiles.RefreshDelay},
+		{"audit.kind", "AUDIT_LOG", false, &c.Audit.Kind},
+		{"audit.path", "AUDIT_LOG_PATH", false, &c.Audit.Path},
+		{"audit.table", "AUDIT_TABLE", false, &c.Audit.Table},
+		{"audit.retention", "AUDIT_RETENTION", false, &c.Audit.Retention},
+		{"audit.admin_token", "AUDIT_ADMIN_TOKEN", true, &c.Audit.AdminToken},
+		{"telemetry.traces_exporter", "OTEL_TRACES_EXPORTER", false, &c.Telemetry.TracesExporter},
+	}
+}
+
+func (s setting) set(value string) error {
+	switch target := s.target.(type) {
+	case *string:
+		*target = value
+	case *int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", s.key, value)
+		}
+		*target = v
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration", s.key, value)
+		}
+		*target = v
+	}
+	return nil
+}
+
+func (s setting) String() string {
+	switch target := s.target.(type) {
+	case *string:
+		if s.secret && *target != "" {
+			return redacted
+		}
+		return *target
+	case *int:
+		return strconv.Itoa(*target)
+	case *time.Duration:
+		return target.String()
+	}
+	return ""
+}
+
+// Loader registers the configuration flags on a flag set and loads the configuration once it is parsed
+type Loader struct {
+	file  *string
+	flags map[string]*string
+}
+
+/**
+* @description: Register -config and one flag per setting on fs, named after the setting key
+* @param fs flag set parsed by the caller before Load
+* @return the loader
+ */
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{
+		file:  fs.String("config", "", "YAML configuration file, defaults to $MEALMATE_CONFIG"),
+		flags: make(map[string]*string),
+	}
+	for _, s := range Default().settings() {
+		usage := "overrides $" + s.env
+		if value := s.String(); value != "" {
+			usage += " (default " + value + ")"
+		}
+		l.flags[s.key] = fs.String(s.key, "", usage)
+	}
+	return l
+}
+
+/**
+* @description: Load and validate the configuration
+* @return the validated configuration, error if a source is malformed or the result is invalid
+ */
+func (l *Loader) Load() (*Config, error) {
+	c, err := l.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return c, nil
+}
+
+/**
+* @description: Merge the configuration sources without validating, for tools that only need part of it.
+* Each source overrides the previous one: defaults, file, environment (.env included), flags
+* @return the configuration, error if a source is malformed
+ */
+func (l *Loader) Resolve() (*Config, error) {
+	// A missing .env is normal in containers where the environment is set directly
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	c := Default()
+	path := *l.file
+	if path == "" {
+		path = os.Getenv("MEALMATE_CONFIG")
+	}
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	settings := c.settings()
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("$%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if value := *l.flags[s.key]; value != "" {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("-%s: %w", s.key, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+/**
+* @description: Load the configuration with flags parsed from args
+* @param args command line arguments without the program name
+* @return the validated configuration, error if failed
+ */
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("mealmate-agent", flag.ContinueOnError)
+	loader := NewLoader(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+/**
+* @description: Merge the configuration sources without flags or validation
+* @return the configuration, error if a source is malformed
+ */
+func Resolve() (*Config, error) {
+	return NewLoader(flag.NewFlagSet("mealmate-agent", flag.ContinueOnError)).Resolve()
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	// Misspelled keys would otherwise be ignored silently
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+/**
+* @description: Render every setting as key=value lines with secrets redacted, for logging at startup
+* @return the dump
+ */
+func (c *Config) Dump() string {
+	var b strings.Builder
+	for _, s := range c.settings() {
+		fmt.Fprintf(&b, "%s=%s\n", s.key, s)
+	}
+	return b.String()
+}
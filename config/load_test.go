@@ -0,0 +1,138 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolvePrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, c *Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, c *Config) {
+				if c.VectorStore.TopK != 3 || c.Sync.Interval != time.Minute {
+					t.Errorf("top_k = %d, sync.interval = %v, want the defaults", c.VectorStore.TopK, c.Sync.Interval)
+				}
+			},
+		},
+		{
+			name: "file over defaults",
+			file: "vector_store:\n  top_k: 5\nsync:\n  interval: 2m\n",
+			check: func(t *testing.T, c *Config) {
+				if c.VectorStore.TopK != 5 || c.Sync.Interval != 2*time.Minute {
+					t.Errorf("top_k = %d, sync.interval = %v, want the file values", c.VectorStore.TopK, c.Sync.Interval)
+				}
+				if c.VectorStore.CatalogTopK != 8 {
+					t.Errorf("catalog_top_k = %d, want the default kept", c.VectorStore.CatalogTopK)
+				}
+			},
+		},
+		{
+			name: "environment over file",
+			file: "vector_store:\n  top_k: 5\n",
+			env:  map[string]string{"RETRIEVER_TOP_K": "7"},
+			check: func(t *testing.T, c *Config) {
+				if c.VectorStore.TopK != 7 {
+					t.Errorf("top_k = %d, want 7", c.VectorStore.TopK)
+				}
+			},
+		},
+		{
+			name: "empty environment keeps file",
+			file: "vector_store:\n  top_k: 5\n",
+			env:  map[string]string{"RETRIEVER_TOP_K": ""},
+			check: func(t *testing.T, c *Config) {
+				if c.VectorStore.TopK != 5 {
+					t.Errorf("top_k = %d, want 5", c.VectorStore.TopK)
+				}
+			},
+		},
+		{
+			name: "flag over environment",
+			file: "vector_store:\n  top_k: 5\n",
+			env:  map[string]string{"RETRIEVER_TOP_K": "7"},
+			args: []string{"-vector_store.top_k", "9"},
+			check: func(t *testing.T, c *Config) {
+				if c.VectorStore.TopK != 9 {
+					t.Errorf("top_k = %d, want 9", c.VectorStore.TopK)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := resolve(t, tt.file, tt.env, tt.args)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestResolveRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+	}{
+		{"unknown file key", "vector_store:\n  topk: 5\n", nil, nil},
+		{"malformed environment int", "", map[string]string{"RETRIEVER_TOP_K": "five"}, nil},
+		{"malformed environment duration", "", map[string]string{"SYNC_INTERVAL": "60"}, nil},
+		{"malformed flag", "", nil, []string{"-sync.interval", "soon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := resolve(t, tt.file, tt.env, tt.args); err == nil {
+				t.Error("Resolve() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	c := Default()
+	c.Ark.APIKey = "sk-secret"
+	dump := c.Dump()
+	if strings.Contains(dump, "sk-secret") || !strings.Contains(dump, "ark.api_key="+redacted) {
+		t.Errorf("Dump() does not redact ark.api_key:\n%s", dump)
+	}
+}
+
+// resolve runs the loader in an empty directory, so no .env is read, with only the given variables and file set
+func resolve(t *testing.T, file string, env map[string]string, args []string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, s := range Default().settings() {
+		t.Setenv(s.env, "")
+	}
+	t.Setenv("MEALMATE_CONFIG", "")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	if file != "" {
+		path := filepath.Join(dir, "mealmate.yaml")
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		args = append([]string{"-config", path}, args...)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return loader.Resolve()
+}
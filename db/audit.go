@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/telemetry"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/supabase-community/supabase-go"
+)
+
+// ErrTraceNotFound is returned by AuditStore.GetTrace when no trace has the request ID
+var ErrTraceNotFound = errors.New("trace not found")
+
+// auditPruneInterval is how often traces older than the retention are deleted
+const auditPruneInterval = time.Hour
+
+// AuditStore keeps one trace per agent call for debugging
+type AuditStore interface {
+	// SaveTrace stores the trace of one call
+	SaveTrace(ctx context.Context, trace models.AgentTrace) error
+	// GetTrace returns the trace of a request, ErrTraceNotFound if it does not exist
+	GetTrace(ctx context.Context, requestID string) (*models.AgentTrace, error)
+	// DeleteBefore deletes the traces created before a time and returns how many were deleted
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+/**
+* @description: Delete traces older than the retention now and then every auditPruneInterval, until ctx is cancelled
+* @param ctx context.Context
+* @param store audit store
+* @param retention how long traces are kept, 0 keeps them forever
+ */
+func StartAuditRetention(ctx context.Context, store AuditStore, retention time.Duration) {
+	if store == nil || retention <= 0 {
+		return
+	}
+	prune := func() {
+		deleted, err := store.DeleteBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			hlog.SystemLogger().Errorf("Failed to prune the audit log: %v", err)
+			return
+		}
+		if deleted > 0 {
+			hlog.SystemLogger().Infof("Pruned %d traces older than %s from the audit log", deleted, retention)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(auditPruneInterval)
+		defer ticker.Stop()
+		for {
+			prune()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// JSONLAuditStore appends traces to a file with one AgentTrace JSON object per line
+type JSONLAuditStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+/**
+* @description: Create an audit store on a JSONL file, the file is created on the first trace
+* @param path file path
+* @return audit store
+ */
+func NewJSONLAuditStore(path string) *JSONLAuditStore {
+	return &JSONLAuditStore{path: path}
+}
+
+func (s *JSONLAuditStore) SaveTrace(ctx context.Context, trace models.AgentTrace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendJSONL(s.path, []models.AgentTrace{trace})
+}
+
+func (s *JSONLAuditStore) GetTrace(ctx context.Context, requestID string) (*models.AgentTrace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found *models.AgentTrace
+	err := readJSONL(s.path, func(trace models.AgentTrace) {
+		if trace.RequestID == requestID {
+			found = &trace
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrTraceNotFound
+	}
+	return found, nil
+}
+
+func (s *JSONLAuditStore) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := make([]models.AgentTrace, 0)
+	deleted := 0
+	err := readJSONL(s.path, func(trace models.AgentTrace) {
+		if trace.CreatedAt.Before(before) {
+			deleted++
+			return
+		}
+		kept = append(kept, trace)
+	})
+	if err != nil || deleted == 0 {
+		return 0, err
+	}
+	return deleted, rewriteJSONL(s.path, kept)
+}
+
+// SupabaseAuditStore keeps traces in a Supabase table whose columns are the JSON fields of models.AgentTrace,
+// request_id is the primary key, created_at a timestamptz and request, documents, prompt, usage, raw_outputs and output jsonb
+type SupabaseAuditStore struct {
+	client *supabase.Client
+	table  string
+}
+
+/**
+* @description: Create an audit store on a Supabase table
+* @param client supabase client
+* @param table trace table name
+* @return audit store and error
+ */
+func NewSupabaseAuditStore(client *supabase.Client, table string) (*SupabaseAuditStore, error) {
+	if err := validateTableName(table); err != nil {
+		return nil, err
+	}
+	return &SupabaseAuditStore{client: client, table: table}, nil
+}
+
+func (s *SupabaseAuditStore) SaveTrace(ctx context.Context, trace models.AgentTrace) (err error) {
+	_, span := startSourceSpan(ctx, "supabase", "insert", s.table)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	_, _, err = s.client.From(s.table).Insert(trace, false, "", "minimal", "").Execute()
+	return err
+}
+
+func (s *SupabaseAuditStore) GetTrace(ctx context.Context, requestID string) (trace *models.AgentTrace, err error) {
+	_, span := startSourceSpan(ctx, "supabase", "get_by_id", s.table)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	data, _, err := s.client.From(s.table).Select("*", "", false).Eq("request_id", requestID).Execute()
+	if err != nil {
+		return nil, err
+	}
+	var traces []models.AgentTrace
+	if err := json.Unmarshal(data, &traces); err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, ErrTraceNotFound
+	}
+	return &traces[0], nil
+}
+
+func (s *SupabaseAuditStore) DeleteBefore(ctx context.Context, before time.Time) (deleted int, err error) {
+	_, span := startSourceSpan(ctx, "supabase", "delete_before", s.table)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	_, count, err := s.client.From(s.table).Delete("minimal", "exact").Lt("created_at", before.UTC().Format(time.RFC3339)).Execute()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+func TestJSONLAuditStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewJSONLAuditStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 12, 0, 0, 0, time.UTC) }
+
+	if _, err := store.GetTrace(ctx, "req-1"); !errors.Is(err, ErrTraceNotFound) {
+		t.Errorf("GetTrace() of an empty store error = %v, want ErrTraceNotFound", err)
+	}
+	for i, trace := range []models.AgentTrace{
+		{RequestID: "req-1", UserID: "u1", RawOutputs: []string{"[]"}, CreatedAt: day(1)},
+		{RequestID: "req-2", UserID: "u2", CreatedAt: day(2)},
+		{RequestID: "req-3", UserID: "u1", Error: "timeout", CreatedAt: day(3)},
+	} {
+		if err := store.SaveTrace(ctx, trace); err != nil {
+			t.Fatalf("SaveTrace(%d) error = %v", i, err)
+		}
+	}
+	trace, err := store.GetTrace(ctx, "req-3")
+	if err != nil || trace.UserID != "u1" || trace.Error != "timeout" {
+		t.Errorf("GetTrace() = %+v, %v, want the saved trace", trace, err)
+	}
+
+	deleted, err := store.DeleteBefore(ctx, day(3))
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteBefore() = %d, %v, want 2", deleted, err)
+	}
+	if _, err := store.GetTrace(ctx, "req-1"); !errors.Is(err, ErrTraceNotFound) {
+		t.Errorf("GetTrace() of a pruned trace error = %v, want ErrTraceNotFound", err)
+	}
+	if _, err := store.GetTrace(ctx, "req-3"); err != nil {
+		t.Errorf("GetTrace() of a kept trace error = %v", err)
+	}
+	if deleted, err := store.DeleteBefore(ctx, day(3)); err != nil || deleted != 0 {
+		t.Errorf("DeleteBefore() again = %d, %v, want 0", deleted, err)
+	}
+}
@@ -2,37 +2,53 @@ package db
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"mealmate-agent/models"
-	"os"
+	"mealmate-agent/schedule"
+	"mealmate-agent/telemetry"
+	"mealmate-agent/vectorstore"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/cloudwego/eino-ext/components/embedding/ark"
-	"github.com/cloudwego/eino-ext/components/indexer/milvus"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/embedding"
 	"github.com/cloudwego/eino/schema"
 	"github.com/cloudwego/hertz/pkg/common/hlog"
-	"github.com/milvus-io/milvus-sdk-go/v2/client"
-	"github.com/supabase-community/supabase-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type MilvusDatabase struct {
-	Client   *client.Client
-	Embedder *ark.Embedder
-	Indexer  *milvus.Indexer
-	Supabase *supabase.Client
+	Store    vectorstore.Store
+	Embedder embedding.Embedder
+	Source   EventSource
+	// DeadLetters keeps events that failed to index, nil disables dead lettering
+	DeadLetters DeadLetterStore
+	// SyncCursors keeps the position of the automatic sync across restarts, nil keeps it in memory only
+	SyncCursors SyncCursorStore
+	// SyncInterval is how often StartAutoSync polls the event source, defaultSyncInterval when zero
+	SyncInterval time.Duration
+	// OnIndexed is called with the events of every successful store, e.g. to refresh taste profiles, it must not block
+	OnIndexed func(events []models.Event)
+
+	health healthState
+	// popular caches the restaurant popularity used for cold starts
+	popular popularCache
+	// syncWG tracks the auto sync goroutine so shutdown can wait for a running batch
+	syncWG sync.WaitGroup
+	// cursor is the position of the automatic sync, nil until its first run
+	cursor *SyncCursor
 }
 
-func NewMilvusDatabase(ctx context.Context, milvusClient *client.Client, embedder *ark.Embedder) *MilvusDatabase {
-	indexer := NewEventIndexer(ctx, milvusClient, embedder)
-	SupabaseApiUrl := os.Getenv("SUPABASE_API_URL")
-	SupabaseApiKey := os.Getenv("SUPABASE_API_KEY")
-	supabaseClient := NewSupabaseClient(SupabaseApiUrl, SupabaseApiKey)
+// defaultSyncInterval is how often StartAutoSync polls the event source unless SyncInterval is set
+const defaultSyncInterval = 1 * time.Minute
+
+func NewMilvusDatabase(ctx context.Context, store vectorstore.Store, source EventSource, embedder embedding.Embedder) *MilvusDatabase {
 	return &MilvusDatabase{
-		Client:   milvusClient,
+		Store:    store,
 		Embedder: embedder,
-		Indexer:  indexer,
-		Supabase: supabaseClient,
+		Source:   source,
 	}
 }
 
@@ -41,28 +57,33 @@ func NewMilvusDatabase(ctx context.Context, milvusClient *client.Client, embedde
 * @param ctx context.Context
 * @return nil if success, error if failed
  */
-func (db *MilvusDatabase) ManuallySyncDatabase(ctx context.Context, config models.SyncConfig) (int, error) {
-	data, _, err := db.Supabase.From("event").Select("*", "", false).Filter("user_id", "eq", config.UserID).Execute()
-	if err != nil {
-		return 0, err
-	}
-	fmt.Println("result:", string(data))
+func (db *MilvusDatabase) ManuallySyncDatabase(ctx context.Context, config models.SyncConfig) (count int, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "sync.manual", attribute.String("user_id", config.UserID))
+	defer func() { telemetry.EndSpan(span, err) }()
 
-	var events []models.Event
-	err = json.Unmarshal(data, &events)
+	events, err := db.Source.ListByUser(ctx, config.UserID)
 	if err != nil {
 		return 0, err
 	}
-	if len(events) < 1 {
-		return 0, fmt.Errorf("no event found")
+
+	if len(events) > 0 {
+		if err = db.SyncEventToMilvus(ctx, &events); err != nil {
+			return 0, err
+		}
 	}
 
-	if db.Indexer == nil {
-		db.Indexer = NewEventIndexer(ctx, db.Client, db.Embedder)
+	// Drop indexed events that were deleted from the source since the last sync, after indexing so a failure here
+	// does not hold back new events
+	deleted, deleteErr := db.RemoveDeletedEvents(ctx, config.UserID)
+	if deleteErr != nil {
+		hlog.SystemLogger().Errorf("Failed to remove deleted events of user %s: %v", config.UserID, deleteErr)
 	}
-	err = db.SyncEventToMilvus(ctx, &events)
-	if err != nil {
-		return 0, err
+	if deleted > 0 {
+		hlog.SystemLogger().Infof("Removed %d deleted events of user %s", deleted, config.UserID)
+	}
+
+	if len(events) < 1 {
+		return 0, fmt.Errorf("no event found")
 	}
 	return len(events), nil
 }
@@ -76,75 +97,167 @@ func (db *MilvusDatabase) SyncEventToMilvus(ctx context.Context, events *[]model
 			ID:      eventId,
 			Content: text,
 			MetaData: map[string]any{
-				"user_id":   event.UserID,
-				"latitude":  event.RestaurantCoordinates.Latitude,
-				"longitude": event.RestaurantCoordinates.Longitude,
-				"create_at": event.CreatedAt,
-				"schedule":  event.ScheduleTime,
+				"user_id":         event.UserID,
+				"restaurant_name": event.RestaurantName,
+				"latitude":        event.RestaurantCoordinates.Latitude,
+				"longitude":       event.RestaurantCoordinates.Longitude,
+				"create_at":       event.CreatedAt,
+				"schedule":        event.ScheduleTime,
 			},
 		}
+		// The parsed time lets the agent filter upcoming meals, the string keeps the zone of the user
+		if scheduled, err := schedule.Parse(event.ScheduleTime); err == nil {
+			doc.MetaData["schedule_unix"] = scheduled.Unix()
+		} else if event.ScheduleTime != "" {
+			hlog.SystemLogger().Warnf("Event %d: %v", event.ID, err)
+		}
 		docs = append(docs, doc)
 	}
 	if len(docs) == 0 {
 		return nil
 	}
-	_, err := db.Indexer.Store(ctx, docs)
+	// Report embedding calls and indexer spans made during the store
+	ctx = callbacks.InitCallbacks(ctx, nil, telemetry.CallbackHandlers()...)
+	_, err := db.Store.Store(ctx, docs)
 	hlog.SystemLogger().Debug("Indexed events to Milvus:", len(docs))
+	if err == nil && db.OnIndexed != nil {
+		db.OnIndexed(*events)
+	}
 	return err
 }
 
-func (db *MilvusDatabase) AutomaticSyncDatabase(ctx context.Context) error {
-	// Calculate the time one minute ago
-	oneMinuteAgo := time.Now().UTC().Add(-1 * time.Minute).Format(time.RFC3339)
-	hlog.SystemLogger().Infof("Fetching events created after: %s (UTC)", oneMinuteAgo)
+/**
+* @description: Remove a user's indexed events that no longer exist in the event source
+* @param ctx context.Context
+* @param userID user whose events are checked
+* @return number of removed events, error if failed
+ */
+func (db *MilvusDatabase) RemoveDeletedEvents(ctx context.Context, userID string) (int, error) {
+	indexed, err := db.Store.IDs(ctx, vectorstore.Filter{UserID: userID})
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int, 0, len(indexed))
+	for _, id := range indexed {
+		eventID, err := strconv.Atoi(id)
+		if err != nil {
+			// Only event documents have numeric IDs
+			continue
+		}
+		ids = append(ids, eventID)
+	}
+	deleted, err := db.Source.DetectDeletions(ctx, ids)
+	if err != nil || len(deleted) == 0 {
+		return 0, err
+	}
+	docIDs := make([]string, 0, len(deleted))
+	for _, id := range deleted {
+		docIDs = append(docIDs, strconv.Itoa(id))
+	}
+	if err := db.Store.Delete(ctx, docIDs); err != nil {
+		return 0, err
+	}
+	return len(docIDs), nil
+}
+
+func (db *MilvusDatabase) AutomaticSyncDatabase(ctx context.Context) (err error) {
+	start := time.Now()
+	ctx, span := telemetry.StartSpan(ctx, "sync.automatic")
+	defer func() {
+		telemetry.EndSpan(span, err)
+		telemetry.SyncDuration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			telemetry.SyncRuns.WithLabelValues("failure").Inc()
+		} else {
+			telemetry.SyncRuns.WithLabelValues("success").Inc()
+		}
+	}()
 
-	// Fetch events created within the last minute from Supabase
-	data, _, err := db.Supabase.From("event").Select("*", "", false).
-		Filter("created_at", "gte", oneMinuteAgo).
-		Execute()
+	cursor, err := db.syncCursor(ctx)
 	if err != nil {
-		hlog.SystemLogger().Errorf("Failed to fetch events from Supabase: %v", err)
+		hlog.SystemLogger().Errorf("Failed to load the sync cursor: %v", err)
 		return err
 	}
+	hlog.SystemLogger().Infof("Fetching events after: %s (UTC), ID %d", cursor.CreatedAt.Format(time.RFC3339), cursor.ID)
 
-	var events []models.Event
-	err = json.Unmarshal(data, &events)
+	// Fetch the events created after the cursor from the event source
+	events, err := db.Source.ListSince(ctx, cursor)
 	if err != nil {
-		hlog.SystemLogger().Errorf("Failed to unmarshal events: %v", err)
+		hlog.SystemLogger().Errorf("Failed to fetch events from the event source: %v", err)
 		return err
 	}
 
 	if len(events) == 0 {
 		hlog.SystemLogger().Info("No new events to sync")
+		db.health.markSynced()
 		return nil
 	}
 
 	hlog.SystemLogger().Infof("Found %d new events to sync", len(events))
 
-	// Sync events to Milvus
-	err = db.SyncEventToMilvus(ctx, &events)
+	// Sync events to Milvus, failed batches are dead lettered and the cursor stays so the next run fetches them again
+	indexed, err := db.indexBatches(ctx, events)
+	telemetry.SyncedEvents.Add(float64(indexed))
 	if err != nil {
 		hlog.SystemLogger().Errorf("Failed to sync events to Milvus: %v", err)
 		return err
 	}
+	if err := db.advanceCursor(ctx, cursorAfter(cursor, events)); err != nil {
+		hlog.SystemLogger().Errorf("Failed to save the sync cursor: %v", err)
+		return err
+	}
 
+	db.health.markSynced()
 	hlog.SystemLogger().Infof("Successfully synced %d events to Milvus", len(events))
 	return nil
 }
 
-// StartAutoSync starts a background goroutine that automatically syncs the database every minute.
+// syncCursor returns the position of the automatic sync, the saved one on the first run or the start of the last interval
+func (db *MilvusDatabase) syncCursor(ctx context.Context) (SyncCursor, error) {
+	if db.cursor != nil {
+		return *db.cursor, nil
+	}
+	cursor := SyncCursor{CreatedAt: time.Now().UTC().Add(-db.syncInterval())}
+	if db.SyncCursors != nil {
+		saved, ok, err := db.SyncCursors.LoadCursor(ctx)
+		if err != nil {
+			return SyncCursor{}, err
+		}
+		if ok {
+			cursor = saved
+		}
+	}
+	db.cursor = &cursor
+	return cursor, nil
+}
+
+// advanceCursor moves the automatic sync past indexed events, saving the position when there is a store
+func (db *MilvusDatabase) advanceCursor(ctx context.Context, cursor SyncCursor) error {
+	db.cursor = &cursor
+	if db.SyncCursors == nil {
+		return nil
+	}
+	return db.SyncCursors.SaveCursor(ctx, cursor)
+}
+
+// StartAutoSync starts a background goroutine that automatically syncs the database every SyncInterval.
 func (db *MilvusDatabase) StartAutoSync(ctx context.Context) {
 	hlog.SystemLogger().Info("Starting automatic sync task...")
 
-	// Create a ticker that triggers every minute for testing purposes
-	ticker := time.NewTicker(1 * time.Minute)
+	// Create a ticker that triggers every interval
+	ticker := time.NewTicker(db.syncInterval())
+
+	// A batch that has started is allowed to finish after ctx is cancelled, so Store is never cut off midway
+	runCtx := context.WithoutCancel(ctx)
 
 	// Run the scheduled task in the background
+	db.syncWG.Add(1)
 	go func() {
+		defer db.syncWG.Done()
 		defer ticker.Stop()
 
 		// Run sync immediately
-		if err := db.AutomaticSyncDatabase(ctx); err != nil {
+		if err := db.AutomaticSyncDatabase(runCtx); err != nil {
 			hlog.SystemLogger().Errorf("Initial sync failed: %v", err)
 		} else {
 			hlog.SystemLogger().Info("Initial sync completed successfully")
@@ -158,7 +271,7 @@ func (db *MilvusDatabase) StartAutoSync(ctx context.Context) {
 				return
 			case <-ticker.C:
 				hlog.SystemLogger().Info("Running scheduled sync...")
-				if err := db.AutomaticSyncDatabase(ctx); err != nil {
+				if err := db.AutomaticSyncDatabase(runCtx); err != nil {
 					hlog.SystemLogger().Errorf("Scheduled sync failed: %v", err)
 				} else {
 					hlog.SystemLogger().Info("Scheduled sync completed successfully")
@@ -167,5 +280,54 @@ func (db *MilvusDatabase) StartAutoSync(ctx context.Context) {
 		}
 	}()
 
-	hlog.SystemLogger().Info("Automatic sync task started, will run every minute")
+	hlog.SystemLogger().Infof("Automatic sync task started, will run every %s", db.syncInterval())
+}
+
+func (db *MilvusDatabase) syncInterval() time.Duration {
+	if db.SyncInterval > 0 {
+		return db.SyncInterval
+	}
+	return defaultSyncInterval
+}
+
+/**
+* @description: Wait for the auto sync goroutine to exit, call after cancelling the context passed to StartAutoSync
+* @param ctx context.Context, its deadline bounds the wait
+* @return nil if the running batch finished, ctx error if the deadline passed first
+ */
+func (db *MilvusDatabase) WaitForSync(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		db.syncWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sync still running at shutdown deadline: %w", ctx.Err())
+	}
+}
+
+/**
+* @description: Flush pending writes of the vector store, then close the vector store and event source
+* @param ctx context.Context
+* @return nil if success, error if failed
+ */
+func (db *MilvusDatabase) Close(ctx context.Context) error {
+	var errs []error
+	if db.Store != nil {
+		if err := db.Store.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush vector store: %w", err))
+		}
+		if err := db.Store.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close vector store: %w", err))
+		}
+	}
+	if db.Source != nil {
+		if err := db.Source.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close event source: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"mealmate-agent/embedder"
+	"mealmate-agent/models"
+	"mealmate-agent/vectorstore"
+)
+
+// newTestDatabase indexes nothing yet, its event source is a JSONL file holding events
+func newTestDatabase(t *testing.T, events ...models.Event) (*MilvusDatabase, *JSONLEventSource) {
+	t.Helper()
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := vectorstore.NewMemoryStore(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewJSONLEventSource(writeEvents(t, events...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMilvusDatabase(context.Background(), store, source, emb), source
+}
+
+// indexedIDs returns the sorted IDs of the documents of a user, every user when userID is empty
+func indexedIDs(t *testing.T, db *MilvusDatabase, userID string) []string {
+	t.Helper()
+	ids, err := db.Store.IDs(context.Background(), vectorstore.Filter{UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestManuallySyncDatabase(t *testing.T) {
+	ctx := context.Background()
+	db, source := newTestDatabase(t, testEvents()...)
+	var notified []int
+	db.OnIndexed = func(events []models.Event) { notified = append(notified, eventIDs(events)...) }
+
+	count, err := db.ManuallySyncDatabase(ctx, models.SyncConfig{UserID: "u1"})
+	if err != nil || count != 3 {
+		t.Fatalf("ManuallySyncDatabase() = %d, %v, want 3 events", count, err)
+	}
+	if got := indexedIDs(t, db, ""); !reflect.DeepEqual(got, []string{"1", "3", "4"}) {
+		t.Errorf("indexed %v, want the events of u1", got)
+	}
+	if !reflect.DeepEqual(notified, []int{1, 3, 4}) {
+		t.Errorf("OnIndexed got %v, want the events of u1", notified)
+	}
+
+	// An event deleted from the source is dropped by the next sync
+	if err := rewriteJSONL(source.path, testEvents()[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ManuallySyncDatabase(ctx, models.SyncConfig{UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := indexedIDs(t, db, "u1"); !reflect.DeepEqual(got, []string{"1", "3"}) {
+		t.Errorf("indexed %v after deleting event 4, want [1 3]", got)
+	}
+
+	if _, err := db.ManuallySyncDatabase(ctx, models.SyncConfig{UserID: "u3"}); err == nil {
+		t.Error("ManuallySyncDatabase() of a user without events succeeded")
+	}
+}
+
+func TestSyncEventToMilvusMetadata(t *testing.T) {
+	ctx := context.Background()
+	db, _ := newTestDatabase(t)
+	event := models.Event{
+		ID: 7, UserID: "u1", RestaurantName: "Sakura", Message: "Omakase",
+		ScheduleTime:          "2025-03-14T19:30:00+01:00",
+		RestaurantCoordinates: models.Coordinates{Latitude: 48.85, Longitude: 2.35},
+	}
+	if err := db.SyncEventToMilvus(ctx, &[]models.Event{event}); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := db.Store.Retrieve(ctx, "Sakura Omakase", vectorstore.WithFilter(vectorstore.Filter{UserID: "u1"}))
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("Retrieve() = %d documents, %v", len(docs), err)
+	}
+	meta := docs[0].MetaData
+	if docs[0].Content != "Sakura Omakase" || meta["restaurant_name"] != "Sakura" || meta["schedule"] != event.ScheduleTime {
+		t.Errorf("document = %q %v", docs[0].Content, meta)
+	}
+	if got, _ := meta["schedule_unix"].(int64); got != 1741977000 {
+		t.Errorf("schedule_unix = %v, want 1741977000", meta["schedule_unix"])
+	}
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+// DeadLetter is an event that could not be indexed, kept so it can be inspected and retried
+type DeadLetter struct {
+	Event    models.Event `json:"event"`
+	Error    string       `json:"error"`
+	FailedAt time.Time    `json:"failed_at"`
+	Attempts int          `json:"attempts"`
+}
+
+// DeadLetterStore keeps events that failed to index, at most one entry per event ID
+type DeadLetterStore interface {
+	// Add records failed events, an event already present has its error updated and attempts incremented
+	Add(ctx context.Context, letters []DeadLetter) error
+	// List returns every dead letter, oldest failure first
+	List(ctx context.Context) ([]DeadLetter, error)
+	// Remove drops the dead letters of the given event IDs
+	Remove(ctx context.Context, eventIDs []int) error
+}
+
+// JSONLDeadLetterStore keeps dead letters in a file with one DeadLetter JSON object per line
+type JSONLDeadLetterStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+/**
+* @description: Create a dead letter store on a JSONL file, the file is created on the first failure
+* @param path file path
+* @return dead letter store
+ */
+func NewJSONLDeadLetterStore(path string) *JSONLDeadLetterStore {
+	return &JSONLDeadLetterStore{path: path}
+}
+
+func (s *JSONLDeadLetterStore) Add(ctx context.Context, letters []DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.read()
+	if err != nil {
+		return err
+	}
+	index := make(map[int]int, len(existing))
+	for i, letter := range existing {
+		index[letter.Event.ID] = i
+	}
+	for _, letter := range letters {
+		if letter.Attempts == 0 {
+			letter.Attempts = 1
+		}
+		if i, ok := index[letter.Event.ID]; ok {
+			letter.Attempts += existing[i].Attempts
+			existing[i] = letter
+			continue
+		}
+		index[letter.Event.ID] = len(existing)
+		existing = append(existing, letter)
+	}
+	return rewriteJSONL(s.path, existing)
+}
+
+func (s *JSONLDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+func (s *JSONLDeadLetterStore) Remove(ctx context.Context, eventIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drop := make(map[int]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		drop[id] = true
+	}
+	_, err := replaceJSONL(s.path, func(letter DeadLetter) bool { return drop[letter.Event.ID] })
+	return err
+}
+
+func (s *JSONLDeadLetterStore) read() ([]DeadLetter, error) {
+	letters := make([]DeadLetter, 0)
+	err := readJSONL(s.path, func(letter DeadLetter) {
+		letters = append(letters, letter)
+	})
+	return letters, err
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+func TestJSONLDeadLetterStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewJSONLDeadLetterStore(filepath.Join(t.TempDir(), "dead_letters.jsonl"))
+	letter := func(id int, cause string) DeadLetter {
+		return DeadLetter{Event: models.Event{ID: id}, Error: cause, FailedAt: time.Date(2025, 3, 1, 0, 0, id, 0, time.UTC)}
+	}
+
+	if letters, err := store.List(ctx); err != nil || len(letters) != 0 {
+		t.Fatalf("List() of a new store = %v, %v, want none", letters, err)
+	}
+	if err := store.Add(ctx, []DeadLetter{letter(1, "timeout"), letter(2, "timeout")}); err != nil {
+		t.Fatal(err)
+	}
+	// A second failure of event 1 updates its entry
+	if err := store.Add(ctx, []DeadLetter{letter(1, "quota"), letter(3, "quota")}); err != nil {
+		t.Fatal(err)
+	}
+	letters, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id       int
+		cause    string
+		attempts int
+	}{{1, "quota", 2}, {2, "timeout", 1}, {3, "quota", 1}}
+	if len(letters) != len(want) {
+		t.Fatalf("List() = %+v, want %d letters", letters, len(want))
+	}
+	for i, w := range want {
+		if got := letters[i]; got.Event.ID != w.id || got.Error != w.cause || got.Attempts != w.attempts {
+			t.Errorf("letter %d = %+v, want event %d, %q, %d attempts", i, got, w.id, w.cause, w.attempts)
+		}
+	}
+
+	if err := store.Remove(ctx, []int{1, 3, 9}); err != nil {
+		t.Fatal(err)
+	}
+	if letters, err := store.List(ctx); err != nil || len(letters) != 1 || letters[0].Event.ID != 2 {
+		t.Errorf("List() after Remove = %+v, %v, want event 2 only", letters, err)
+	}
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrEventNotFound is returned by EventSource.GetByID when no event has the ID
+var ErrEventNotFound = errors.New("event not found")
+
+// EventSource is where dining events are read from before they are indexed into the vector store
+type EventSource interface {
+	// ListByUser returns every event of a user
+	ListByUser(ctx context.Context, userID string) ([]models.Event, error)
+	// ListSince returns every dated event after cursor, ordered by creation time then ID, the zero cursor lists them all
+	ListSince(ctx context.Context, cursor SyncCursor) ([]models.Event, error)
+	// GetByID returns one event, ErrEventNotFound if it does not exist
+	GetByID(ctx context.Context, id int) (*models.Event, error)
+	// DetectDeletions returns the subset of ids that no longer exist in the source
+	DetectDeletions(ctx context.Context, ids []int) ([]int, error)
+	// Check reports whether the source is reachable
+	Check(ctx context.Context) error
+	// Close releases the source connection
+	Close() error
+}
+
+// SyncCursor is a position in the event source, where events are ordered by creation time then ID
+type SyncCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+}
+
+// Precedes reports whether the event created at createdAt with id comes after the cursor
+func (c SyncCursor) Precedes(createdAt time.Time, id int) bool {
+	return createdAt.After(c.CreatedAt) || (createdAt.Equal(c.CreatedAt) && id > c.ID)
+}
+
+// cursorAfter returns the position of the last of events, cursor itself when no event comes after it
+func cursorAfter(cursor SyncCursor, events []models.Event) SyncCursor {
+	for _, e := range events {
+		createdAt, err := time.Parse(time.RFC3339, e.CreatedAt)
+		if err == nil && cursor.Precedes(createdAt, e.ID) {
+			cursor = SyncCursor{CreatedAt: createdAt.UTC(), ID: e.ID}
+		}
+	}
+	return cursor
+}
+
+// EventWriter is implemented by event sources that can also store events, e.g. to load fixtures
+type EventWriter interface {
+	// InsertEvents stores events, replacing existing events with the same ID
+	InsertEvents(ctx context.Context, events []models.Event) error
+}
+
+// tableNamePattern guards table names that are interpolated into queries
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+func validateTableName(table string) error {
+	if !tableNamePattern.MatchString(table) {
+		return fmt.Errorf("invalid event table name %q", table)
+	}
+	return nil
+}
+
+// startSourceSpan opens a span for one call to an event source
+func startSourceSpan(ctx context.Context, system, operation, table string) (context.Context, trace.Span) {
+	return telemetry.StartSpan(ctx, system+"."+operation,
+		attribute.String("db.system", system),
+		attribute.String("db.collection.name", table),
+		attribute.String("db.operation.name", operation),
+	)
+}
+
+// missingIDs returns the ids that are not in found, preserving order
+func missingIDs(ids []int, found map[int]bool) []int {
+	missing := make([]int, 0)
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
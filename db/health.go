@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"mealmate-agent/telemetry"
+)
+
+// embedderCheckTTL bounds how often readiness probes hit the embedding API
+const embedderCheckTTL = 30 * time.Second
+
+// syncStaleIntervals is how many sync intervals may pass without a successful run before the sync is reported as stale
+const syncStaleIntervals = 3
+
+type healthState struct {
+	mu               sync.RWMutex
+	lastSyncAt       time.Time
+	lastEmbedCheckAt time.Time
+	lastEmbedErr     error
+}
+
+func (s *healthState) markSynced() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSyncAt = time.Now()
+}
+
+/**
+* @description: Get the time of the last successful automatic sync
+* @return zero time if no sync has succeeded yet
+ */
+func (db *MilvusDatabase) LastSyncAt() time.Time {
+	db.health.mu.RLock()
+	defer db.health.mu.RUnlock()
+	return db.health.lastSyncAt
+}
+
+/**
+* @description: Check that the vector store is reachable and ready to serve searches
+* @param ctx context.Context
+* @return nil if ready, error if not
+ */
+func (db *MilvusDatabase) CheckVectorStore(ctx context.Context) (err error) {
+	if db.Store == nil {
+		return fmt.Errorf("vector store not initialized")
+	}
+	ctx, span := telemetry.StartSpan(ctx, "vectorstore.check")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	return db.Store.Check(ctx)
+}
+
+/**
+* @description: Check that the event source is reachable
+* @param ctx context.Context
+* @return nil if reachable, error if not
+ */
+func (db *MilvusDatabase) CheckEventSource(ctx context.Context) error {
+	if db.Source == nil {
+		return fmt.Errorf("event source not initialized")
+	}
+	return db.Source.Check(ctx)
+}
+
+/**
+* @description: Check that the embedder can produce vectors, the result is cached for embedderCheckTTL
+* @param ctx context.Context
+* @return nil if available, error if not
+ */
+func (db *MilvusDatabase) CheckEmbedder(ctx context.Context) error {
+	if db.Embedder == nil {
+		return fmt.Errorf("embedder not initialized")
+	}
+	db.health.mu.RLock()
+	checkedAt, lastErr := db.health.lastEmbedCheckAt, db.health.lastEmbedErr
+	db.health.mu.RUnlock()
+	if !checkedAt.IsZero() && time.Since(checkedAt) < embedderCheckTTL {
+		return lastErr
+	}
+
+	vectors, err := db.Embedder.EmbedStrings(ctx, []string{"ping"})
+	if err == nil && (len(vectors) != 1 || len(vectors[0]) == 0) {
+		err = fmt.Errorf("embedder returned no vector")
+	}
+
+	db.health.mu.Lock()
+	db.health.lastEmbedCheckAt = time.Now()
+	db.health.lastEmbedErr = err
+	db.health.mu.Unlock()
+	return err
+}
+
+/**
+* @description: Check that the automatic sync has succeeded recently
+* @return nil if fresh, error if stale or never run
+ */
+func (db *MilvusDatabase) CheckSyncFreshness() error {
+	last := db.LastSyncAt()
+	if last.IsZero() {
+		return fmt.Errorf("automatic sync has not completed yet")
+	}
+	if age := time.Since(last); age > syncStaleIntervals*db.syncInterval() {
+		return fmt.Errorf("last successful sync was %s ago", age.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// countingEmbedder counts its calls and fails with err
+type countingEmbedder struct {
+	calls int
+	err   error
+}
+
+func (e *countingEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	return [][]float64{{1, 0}}, nil
+}
+
+func TestCheckSyncFreshness(t *testing.T) {
+	db := &MilvusDatabase{}
+	if err := db.CheckSyncFreshness(); err == nil {
+		t.Error("CheckSyncFreshness() before any sync succeeded")
+	}
+	db.health.markSynced()
+	if err := db.CheckSyncFreshness(); err != nil {
+		t.Errorf("CheckSyncFreshness() after a sync = %v", err)
+	}
+	db.health.lastSyncAt = time.Now().Add(-(syncStaleIntervals + 1) * db.syncInterval())
+	if err := db.CheckSyncFreshness(); err == nil {
+		t.Error("CheckSyncFreshness() of an old sync succeeded")
+	}
+}
+
+func TestCheckEmbedderCachesResult(t *testing.T) {
+	emb := &countingEmbedder{err: errors.New("quota exceeded")}
+	db := &MilvusDatabase{Embedder: emb}
+	for range 3 {
+		if err := db.CheckEmbedder(context.Background()); err == nil {
+			t.Error("CheckEmbedder() of a failing embedder succeeded")
+		}
+	}
+	if emb.calls != 1 {
+		t.Errorf("embedder called %d times, want 1 within embedderCheckTTL", emb.calls)
+	}
+
+	db.health.lastEmbedCheckAt = time.Now().Add(-embedderCheckTTL)
+	emb.err = nil
+	if err := db.CheckEmbedder(context.Background()); err != nil || emb.calls != 2 {
+		t.Errorf("CheckEmbedder() after the TTL = %v with %d calls, want a new successful check", err, emb.calls)
+	}
+}
+
+func TestChecksWithoutComponents(t *testing.T) {
+	db := &MilvusDatabase{}
+	ctx := context.Background()
+	for name, check := range map[string]func(context.Context) error{
+		"vector store": db.CheckVectorStore,
+		"event source": db.CheckEventSource,
+		"embedder":     db.CheckEmbedder,
+	} {
+		if err := check(ctx); err == nil {
+			t.Errorf("check of the %s succeeded without one", name)
+		}
+	}
+}
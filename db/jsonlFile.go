@@ -0,0 +1,91 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// appendJSONL appends one line per value, a missing file is created
+func appendJSONL[T any](path string, values []T) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for _, value := range values {
+		line, err := json.Marshal(value)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readJSONL calls fn with every line decoded, a missing file has no lines
+func readJSONL[T any](path string, fn func(T)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var value T
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		fn(value)
+	}
+	return scanner.Err()
+}
+
+// rewriteJSONL replaces the file through a rename so a crash never leaves it half written
+func rewriteJSONL[T any](path string, values []T) error {
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := appendJSONL(tmp, values); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// replaceJSONL rewrites the file without the values matching drop and with add appended, it reports whether a value was dropped
+func replaceJSONL[T any](path string, drop func(T) bool, add ...T) (bool, error) {
+	kept := make([]T, 0)
+	dropped := false
+	err := readJSONL(path, func(value T) {
+		if drop(value) {
+			dropped = true
+			return
+		}
+		kept = append(kept, value)
+	})
+	if err != nil {
+		return false, err
+	}
+	if !dropped && len(add) == 0 {
+		return false, nil
+	}
+	return dropped, rewriteJSONL(path, append(kept, add...))
+}
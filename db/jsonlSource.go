@@ -0,0 +1,134 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"slices"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+// JSONLEventSource reads events from a file with one models.Event JSON object per line.
+// The file is re-read on every call so edits are picked up without a restart.
+type JSONLEventSource struct {
+	path string
+}
+
+/**
+* @description: Create an event source on a JSONL file
+* @param path file path, the file must exist
+* @return event source and error
+ */
+func NewJSONLEventSource(path string) (*JSONLEventSource, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return &JSONLEventSource{path: path}, nil
+}
+
+func (s *JSONLEventSource) ListByUser(ctx context.Context, userID string) ([]models.Event, error) {
+	return s.filter(func(e models.Event) bool { return e.UserID == userID })
+}
+
+func (s *JSONLEventSource) ListSince(ctx context.Context, cursor SyncCursor) ([]models.Event, error) {
+	events, err := s.filter(func(e models.Event) bool {
+		createdAt, err := time.Parse(time.RFC3339, e.CreatedAt)
+		return err == nil && cursor.Precedes(createdAt, e.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Ordered like the other sources, every kept event has a creation time that parses
+	slices.SortStableFunc(events, func(a, b models.Event) int {
+		createdA, _ := time.Parse(time.RFC3339, a.CreatedAt)
+		createdB, _ := time.Parse(time.RFC3339, b.CreatedAt)
+		return cmp.Or(createdA.Compare(createdB), cmp.Compare(a.ID, b.ID))
+	})
+	return events, nil
+}
+
+func (s *JSONLEventSource) GetByID(ctx context.Context, id int) (*models.Event, error) {
+	events, err := s.filter(func(e models.Event) bool { return e.ID == id })
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrEventNotFound
+	}
+	return &events[0], nil
+}
+
+func (s *JSONLEventSource) DetectDeletions(ctx context.Context, ids []int) ([]int, error) {
+	events, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int]bool, len(events))
+	for _, e := range events {
+		found[e.ID] = true
+	}
+	return missingIDs(ids, found), nil
+}
+
+func (s *JSONLEventSource) Check(ctx context.Context) error {
+	_, err := os.Stat(s.path)
+	return err
+}
+
+// InsertEvents rewrites the file with events merged in, events with an existing ID replace the old line
+func (s *JSONLEventSource) InsertEvents(ctx context.Context, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	existing, err := s.read()
+	if err != nil {
+		return err
+	}
+	replaced := make(map[int]models.Event, len(events))
+	for _, event := range events {
+		replaced[event.ID] = event
+	}
+	merged := make([]models.Event, 0, len(existing)+len(events))
+	for _, event := range existing {
+		if replacement, ok := replaced[event.ID]; ok {
+			event = replacement
+			delete(replaced, event.ID)
+		}
+		merged = append(merged, event)
+	}
+	for _, event := range events {
+		if replacement, ok := replaced[event.ID]; ok {
+			merged = append(merged, replacement)
+			delete(replaced, event.ID)
+		}
+	}
+	return rewriteJSONL(s.path, merged)
+}
+
+func (s *JSONLEventSource) Close() error {
+	return nil
+}
+
+func (s *JSONLEventSource) filter(keep func(models.Event) bool) ([]models.Event, error) {
+	events, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]models.Event, 0)
+	for _, e := range events {
+		if keep(e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept, nil
+}
+
+func (s *JSONLEventSource) read() ([]models.Event, error) {
+	events := make([]models.Event, 0)
+	err := readJSONL(s.path, func(event models.Event) {
+		events = append(events, event)
+	})
+	return events, err
+}
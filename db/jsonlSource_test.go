@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+// writeEvents writes events to a JSONL file in a temporary directory and returns its path
+func writeEvents(t *testing.T, events ...models.Event) string {
+	t.Helper()
+	var data []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testEvents() []models.Event {
+	return []models.Event{
+		{ID: 1, UserID: "u1", RestaurantName: "Sakura", CreatedAt: "2025-03-01T12:00:00Z"},
+		{ID: 2, UserID: "u2", RestaurantName: "La Cantina", CreatedAt: "2025-03-02T12:00:00Z"},
+		{ID: 3, UserID: "u1", RestaurantName: "Le Bistrot", CreatedAt: "2025-03-03T12:00:00Z"},
+		{ID: 4, UserID: "u1", RestaurantName: "No Date"},
+	}
+}
+
+func eventIDs(events []models.Event) []int {
+	ids := make([]int, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestJSONLEventSource(t *testing.T) {
+	ctx := context.Background()
+	source, err := NewJSONLEventSource(writeEvents(t, testEvents()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		list func() ([]models.Event, error)
+		want []int
+	}{
+		{"by user", func() ([]models.Event, error) { return source.ListByUser(ctx, "u1") }, []int{1, 3, 4}},
+		{"unknown user", func() ([]models.Event, error) { return source.ListByUser(ctx, "u3") }, []int{}},
+		{"since", func() ([]models.Event, error) {
+			return source.ListSince(ctx, SyncCursor{CreatedAt: time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)})
+		}, []int{2, 3}},
+		{"after an event", func() ([]models.Event, error) {
+			return source.ListSince(ctx, SyncCursor{CreatedAt: time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC), ID: 2})
+		}, []int{3}},
+		{"zero cursor skips undated", func() ([]models.Event, error) { return source.ListSince(ctx, SyncCursor{}) }, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := tt.list()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventIDs(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if event, err := source.GetByID(ctx, 2); err != nil || event.RestaurantName != "La Cantina" {
+		t.Errorf("GetByID(2) = %+v, %v", event, err)
+	}
+	if _, err := source.GetByID(ctx, 9); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("GetByID(9) error = %v, want ErrEventNotFound", err)
+	}
+	if missing, err := source.DetectDeletions(ctx, []int{3, 9, 1, 7}); err != nil || !reflect.DeepEqual(missing, []int{9, 7}) {
+		t.Errorf("DetectDeletions() = %v, %v, want [9 7]", missing, err)
+	}
+}
+
+func TestJSONLEventSourceInsertEvents(t *testing.T) {
+	ctx := context.Background()
+	path := writeEvents(t, testEvents()[:2]...)
+	source, err := NewJSONLEventSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = source.InsertEvents(ctx, []models.Event{
+		{ID: 5, UserID: "u1", RestaurantName: "Pizza Roma"},
+		{ID: 1, UserID: "u1", RestaurantName: "Sakura Sushi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := source.ListByUser(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replaced events keep their place, new ones are appended
+	if got := eventIDs(events); !reflect.DeepEqual(got, []int{1, 5}) || events[0].RestaurantName != "Sakura Sushi" {
+		t.Errorf("events after insert = %+v", events)
+	}
+}
+
+func TestNewJSONLEventSourceMissingFile(t *testing.T) {
+	if _, err := NewJSONLEventSource(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("NewJSONLEventSource() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/telemetry"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// syncBatchSize bounds how many events are embedded and stored in one call
+const syncBatchSize = 100
+
+// IndexStats compares the vector store with the event source
+type IndexStats struct {
+	// Indexed is the number of documents in the vector store
+	Indexed int `json:"indexed"`
+	// SourceEvents is the number of events in the event source
+	SourceEvents int `json:"source_events"`
+	// MissingFromIndex counts source events that are not indexed
+	MissingFromIndex int `json:"missing_from_index"`
+	// DeadLetters is the number of events waiting in the dead letter store
+	DeadLetters int `json:"dead_letters"`
+}
+
+/**
+* @description: Index every event created at or after since, all events when since is zero
+* @param ctx context.Context
+* @param since lower bound of the event creation time
+* @return number of indexed events, error if any batch failed
+ */
+func (db *MilvusDatabase) SyncSince(ctx context.Context, since time.Time) (count int, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "sync.since", attribute.String("since", since.Format(time.RFC3339)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	events, err := db.Source.ListSince(ctx, SyncCursor{CreatedAt: since})
+	if err != nil {
+		return 0, err
+	}
+	return db.indexBatches(ctx, events)
+}
+
+/**
+* @description: Index events that do not come from the event source, e.g. generated fixtures
+* @param ctx context.Context
+* @param events events to index
+* @return number of indexed events, error if any batch failed
+ */
+func (db *MilvusDatabase) IndexEvents(ctx context.Context, events []models.Event) (count int, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "sync.index_events", attribute.Int("events", len(events)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	return db.indexBatches(ctx, events)
+}
+
+/**
+* @description: Rebuild the index from the event source, e.g. after the embedder changed
+* @param ctx context.Context
+* @param userID only rebuild this user's documents, empty for every user
+* @return number of removed documents that are no longer in the source, number of indexed events, error if failed
+ */
+func (db *MilvusDatabase) Reindex(ctx context.Context, userID string) (removed int, indexed int, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "sync.reindex", attribute.String("user_id", userID))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	var events []models.Event
+	if userID != "" {
+		events, err = db.Source.ListByUser(ctx, userID)
+	} else {
+		events, err = db.Source.ListSince(ctx, SyncCursor{})
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	// Index before removing anything, a failure partway through leaves the previous documents searchable
+	if indexed, err = db.indexBatches(ctx, events); err != nil {
+		return 0, indexed, err
+	}
+	ids, err := db.Store.IDs(ctx, vectorstore.Filter{UserID: userID})
+	if err != nil {
+		return 0, indexed, err
+	}
+	current := make(map[string]bool, len(events))
+	for _, event := range events {
+		current[strconv.Itoa(event.ID)] = true
+	}
+	stale := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !current[id] {
+			stale = append(stale, id)
+		}
+	}
+	if err := db.Store.Delete(ctx, stale); err != nil {
+		return 0, indexed, err
+	}
+	return len(stale), indexed, nil
+}
+
+/**
+* @description: Index dead lettered events again, using their current version from the event source
+* @param ctx context.Context
+* @param eventIDs events to retry, empty for every dead letter
+* @return number of events indexed, error if any retry failed
+ */
+func (db *MilvusDatabase) RetryDeadLetters(ctx context.Context, eventIDs []int) (int, error) {
+	if db.DeadLetters == nil {
+		return 0, fmt.Errorf("dead letter store not configured")
+	}
+	letters, err := db.DeadLetters.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	wanted := make(map[int]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		wanted[id] = true
+	}
+
+	var done []int
+	var failed []DeadLetter
+	var errs []error
+	for _, letter := range letters {
+		if len(wanted) > 0 && !wanted[letter.Event.ID] {
+			continue
+		}
+		event, err := db.Source.GetByID(ctx, letter.Event.ID)
+		if errors.Is(err, ErrEventNotFound) {
+			// Deleted from the source since it failed, nothing left to index
+			done = append(done, letter.Event.ID)
+			continue
+		}
+		if err == nil {
+			err = db.SyncEventToMilvus(ctx, &[]models.Event{*event})
+		}
+		if err != nil {
+			failed = append(failed, DeadLetter{Event: letter.Event, Error: err.Error(), FailedAt: time.Now().UTC()})
+			errs = append(errs, fmt.Errorf("event %d: %w", letter.Event.ID, err))
+			continue
+		}
+		done = append(done, letter.Event.ID)
+	}
+
+	if err := db.DeadLetters.Remove(ctx, done); err != nil {
+		return 0, err
+	}
+	if len(failed) > 0 {
+		if err := db.DeadLetters.Add(ctx, failed); err != nil {
+			return 0, err
+		}
+	}
+	return len(done), errors.Join(errs...)
+}
+
+/**
+* @description: Count indexed documents, source events and dead letters
+* @param ctx context.Context
+* @param userID only count this user's events, empty for every user
+* @return the statistics, error if failed
+ */
+func (db *MilvusDatabase) Stats(ctx context.Context, userID string) (IndexStats, error) {
+	var stats IndexStats
+	ids, err := db.Store.IDs(ctx, vectorstore.Filter{UserID: userID})
+	if err != nil {
+		return stats, err
+	}
+	stats.Indexed = len(ids)
+
+	var events []models.Event
+	if userID != "" {
+		events, err = db.Source.ListByUser(ctx, userID)
+	} else {
+		events, err = db.Source.ListSince(ctx, SyncCursor{})
+	}
+	if err != nil {
+		return stats, err
+	}
+	stats.SourceEvents = len(events)
+	indexed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		indexed[id] = true
+	}
+	for _, event := range events {
+		if !indexed[strconv.Itoa(event.ID)] {
+			stats.MissingFromIndex++
+		}
+	}
+
+	if db.DeadLetters != nil {
+		letters, err := db.DeadLetters.List(ctx)
+		if err != nil {
+			return stats, err
+		}
+		for _, letter := range letters {
+			if userID == "" || letter.Event.UserID == userID {
+				stats.DeadLetters++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// indexBatches indexes events in batches of syncBatchSize, dead lettering the events of every failed batch
+func (db *MilvusDatabase) indexBatches(ctx context.Context, events []models.Event) (int, error) {
+	indexed := 0
+	var errs []error
+	for start := 0; start < len(events); start += syncBatchSize {
+		batch := events[start:min(start+syncBatchSize, len(events))]
+		if err := db.SyncEventToMilvus(ctx, &batch); err != nil {
+			errs = append(errs, err)
+			db.deadLetter(ctx, batch, err)
+			continue
+		}
+		indexed += len(batch)
+	}
+	if len(errs) > 0 {
+		return indexed, fmt.Errorf("%d of %d events failed to index: %w", len(events)-indexed, len(events), errors.Join(errs...))
+	}
+	return indexed, nil
+}
+
+// deadLetter records events that failed to index, a failure to record is only logged
+func (db *MilvusDatabase) deadLetter(ctx context.Context, events []models.Event, cause error) {
+	if db.DeadLetters == nil {
+		return
+	}
+	now := time.Now().UTC()
+	letters := make([]DeadLetter, 0, len(events))
+	for _, event := range events {
+		letters = append(letters, DeadLetter{Event: event, Error: cause.Error(), FailedAt: now})
+	}
+	if err := db.DeadLetters.Add(ctx, letters); err != nil {
+		hlog.SystemLogger().Errorf("Failed to dead letter %d events: %v", len(letters), err)
+		return
+	}
+	hlog.SystemLogger().Warnf("Dead lettered %d events", len(letters))
+}
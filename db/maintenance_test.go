@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/schema"
+)
+
+// backend names the store embedded by failingStore, whose Store method would otherwise hide it
+type backend = vectorstore.Store
+
+// failingStore fails to store the documents of failUser
+type failingStore struct {
+	backend
+	failUser string
+}
+
+func (s *failingStore) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) ([]string, error) {
+	for _, doc := range docs {
+		if doc.MetaData["user_id"] == s.failUser {
+			return nil, errors.New("store unavailable")
+		}
+	}
+	return s.backend.Store(ctx, docs, opts...)
+}
+
+func TestReindex(t *testing.T) {
+	ctx := context.Background()
+	db, source := newTestDatabase(t, testEvents()...)
+	if _, err := db.SyncSince(ctx, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rewriteJSONL(source.path, append(testEvents()[1:], models.Event{ID: 5, UserID: "u1", RestaurantName: "Pizza Roma"})); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, indexed, err := db.Reindex(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || indexed != 3 {
+		t.Errorf("Reindex() removed %d, indexed %d, want 1 and 3", removed, indexed)
+	}
+	if got := indexedIDs(t, db, ""); !reflect.DeepEqual(got, []string{"2", "3", "4", "5"}) {
+		t.Errorf("indexed %v after reindex", got)
+	}
+}
+
+func TestDeadLettersAndRetry(t *testing.T) {
+	ctx := context.Background()
+	db, _ := newTestDatabase(t, testEvents()...)
+	db.DeadLetters = NewJSONLDeadLetterStore(filepath.Join(t.TempDir(), "dead_letters.jsonl"))
+	healthy := db.Store
+	db.Store = &failingStore{backend: healthy, failUser: "u2"}
+
+	// The failed batch is dead lettered, the others are indexed
+	if err := db.SyncEventToMilvus(ctx, &[]models.Event{testEvents()[0]}); err != nil {
+		t.Fatal(err)
+	}
+	count, err := db.indexBatches(ctx, testEvents()[1:2])
+	if err == nil || count != 0 {
+		t.Fatalf("indexBatches() = %d, %v, want a failure", count, err)
+	}
+	stats, err := db.Stats(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (IndexStats{Indexed: 1, SourceEvents: 3, MissingFromIndex: 2, DeadLetters: 1}); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	// Retrying while the store still fails keeps the letter
+	if _, err := db.RetryDeadLetters(ctx, nil); err == nil {
+		t.Error("RetryDeadLetters() succeeded while the store fails")
+	}
+	db.Store = healthy
+	if retried, err := db.RetryDeadLetters(ctx, nil); err != nil || retried != 1 {
+		t.Errorf("RetryDeadLetters() = %d, %v, want 1", retried, err)
+	}
+	letters, err := db.DeadLetters.List(ctx)
+	if err != nil || len(letters) != 0 {
+		t.Errorf("dead letters after retry = %+v, %v, want none", letters, err)
+	}
+	if got := indexedIDs(t, db, ""); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("indexed %v after retry, want [1 2]", got)
+	}
+}
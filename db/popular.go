@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// popularCacheTTL bounds how often the whole event source is scanned to rank restaurants
+	popularCacheTTL = 10 * time.Minute
+	// popularRadiusKm is how far from the requested location a restaurant counts as nearby
+	popularRadiusKm = 10.0
+)
+
+type popularCache struct {
+	mu          sync.Mutex
+	loadedAt    time.Time
+	restaurants []models.PopularRestaurant
+}
+
+/**
+* @description: Rank restaurants by how many users dined there, used to recommend something to users without history
+* @param ctx context.Context
+* @param near only keep restaurants within popularRadiusKm of this location, nil for every restaurant
+* @param limit maximum number of restaurants, 0 for no limit
+* @return restaurants with the most diners first, every restaurant if none is near the location
+ */
+func (db *MilvusDatabase) PopularRestaurants(ctx context.Context, near *models.Coordinates, limit int) (restaurants []models.PopularRestaurant, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "restaurants.popular", attribute.Bool("near", near != nil))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	ranked, err := db.rankedRestaurants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restaurants = ranked
+	if near != nil {
+		nearby := make([]models.PopularRestaurant, 0, len(ranked))
+		for _, r := range ranked {
+			if d := near.DistanceKm(r.Coordinates); d <= popularRadiusKm {
+				r.DistanceKm = math.Round(d*10) / 10
+				nearby = append(nearby, r)
+			}
+		}
+		// A location without known restaurants around still gets the overall favorites
+		if len(nearby) > 0 {
+			restaurants = nearby
+		}
+	}
+	if limit > 0 && len(restaurants) > limit {
+		restaurants = restaurants[:limit]
+	}
+	// The ranking is cached, callers get their own copy
+	return slices.Clone(restaurants), nil
+}
+
+// rankedRestaurants returns every restaurant ranked by diners, rebuilt from the event source after popularCacheTTL
+func (db *MilvusDatabase) rankedRestaurants(ctx context.Context) ([]models.PopularRestaurant, error) {
+	if db.Source == nil {
+		return nil, fmt.Errorf("event source not initialized")
+	}
+	db.popular.mu.Lock()
+	defer db.popular.mu.Unlock()
+	if !db.popular.loadedAt.IsZero() && time.Since(db.popular.loadedAt) < popularCacheTTL {
+		return db.popular.restaurants, nil
+	}
+
+	events, err := db.Source.ListSince(ctx, SyncCursor{})
+	if err != nil {
+		return nil, err
+	}
+	db.popular.restaurants = rankRestaurants(events)
+	db.popular.loadedAt = time.Now()
+	return db.popular.restaurants, nil
+}
+
+// rankRestaurants groups events by restaurant, places with the same name in different areas are kept apart
+func rankRestaurants(events []models.Event) []models.PopularRestaurant {
+	type aggregate struct {
+		name     string
+		visits   int
+		diners   map[string]bool
+		lat, lon float64
+	}
+	groups := make(map[string]*aggregate)
+	for _, event := range events {
+		name := strings.TrimSpace(event.RestaurantName)
+		if name == "" {
+			continue
+		}
+		c := event.RestaurantCoordinates
+		key := fmt.Sprintf("%s|%.2f|%.2f", strings.ToLower(name), c.Latitude, c.Longitude)
+		group, ok := groups[key]
+		if !ok {
+			group = &aggregate{name: name, diners: make(map[string]bool)}
+			groups[key] = group
+		}
+		group.visits++
+		group.diners[event.UserID] = true
+		group.lat += c.Latitude
+		group.lon += c.Longitude
+	}
+
+	restaurants := make([]models.PopularRestaurant, 0, len(groups))
+	for _, group := range groups {
+		restaurants = append(restaurants, models.PopularRestaurant{
+			RestaurantName: group.name,
+			Visits:         group.visits,
+			Diners:         len(group.diners),
+			Coordinates: models.Coordinates{
+				Latitude:  group.lat / float64(group.visits),
+				Longitude: group.lon / float64(group.visits),
+			},
+		})
+	}
+	sort.Slice(restaurants, func(i, j int) bool {
+		a, b := restaurants[i], restaurants[j]
+		if a.Diners != b.Diners {
+			return a.Diners > b.Diners
+		}
+		if a.Visits != b.Visits {
+			return a.Visits > b.Visits
+		}
+		return a.RestaurantName < b.RestaurantName
+	})
+	return restaurants
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"mealmate-agent/models"
+)
+
+func TestPopularRestaurants(t *testing.T) {
+	paris := models.Coordinates{Latitude: 48.8566, Longitude: 2.3522}
+	lyon := models.Coordinates{Latitude: 45.7640, Longitude: 4.8357}
+	event := func(id int, user, name string, at models.Coordinates) models.Event {
+		return models.Event{ID: id, UserID: user, RestaurantName: name, RestaurantCoordinates: at, CreatedAt: "2025-03-01T12:00:00Z"}
+	}
+	db, _ := newTestDatabase(t,
+		event(1, "u1", "Sakura", paris),
+		event(2, "u2", "sakura ", paris),
+		event(3, "u3", "Sakura", paris),
+		event(4, "u1", "Le Bistrot", paris),
+		event(5, "u1", "Le Bistrot", paris),
+		event(6, "u2", "Le Bistrot", paris),
+		event(7, "u1", "La Cantina", paris),
+		event(8, "u2", "La Cantina", paris),
+		// Same name in another city is another restaurant
+		event(9, "u4", "Sakura", lyon),
+		event(10, "u4", "  ", paris),
+	)
+	names := func(restaurants []models.PopularRestaurant) []string {
+		got := make([]string, 0, len(restaurants))
+		for _, r := range restaurants {
+			got = append(got, r.RestaurantName)
+		}
+		return got
+	}
+	tests := []struct {
+		name  string
+		near  *models.Coordinates
+		limit int
+		want  []string
+	}{
+		{"by diners then visits", nil, 0, []string{"Sakura", "Le Bistrot", "La Cantina", "Sakura"}},
+		{"limit", nil, 2, []string{"Sakura", "Le Bistrot"}},
+		{"near", &lyon, 0, []string{"Sakura"}},
+		{"nothing near", &models.Coordinates{Latitude: 40.7128, Longitude: -74.0060}, 1, []string{"Sakura"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restaurants, err := db.PopularRestaurants(context.Background(), tt.near, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(restaurants); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PopularRestaurants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	top, err := db.PopularRestaurants(context.Background(), nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top[0].Diners != 3 || top[0].Visits != 3 {
+		t.Errorf("Sakura has %d diners and %d visits, want 3 and 3", top[0].Diners, top[0].Visits)
+	}
+	// Callers get a copy of the cached ranking
+	top[0].RestaurantName = "Changed"
+	if again, _ := db.PopularRestaurants(context.Background(), nil, 1); again[0].RestaurantName != "Sakura" {
+		t.Errorf("cached ranking changed to %q by a caller", again[0].RestaurantName)
+	}
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"mealmate-agent/models"
+)
+
+// PreferenceStore keeps the explicit preferences of users, one set per user
+type PreferenceStore interface {
+	// GetPreferences returns the preferences of a user, nil if they have none
+	GetPreferences(ctx context.Context, userID string) (*models.UserPreferences, error)
+	// SavePreferences creates or replaces the preferences of preferences.UserID
+	SavePreferences(ctx context.Context, preferences models.UserPreferences) error
+	// DeletePreferences removes the preferences of a user, it reports whether they had any
+	DeletePreferences(ctx context.Context, userID string) (bool, error)
+}
+
+// JSONLPreferenceStore keeps the preferences in a file with one JSON object per user.
+// The file is read once, on first use, into an index by user that later writes keep up to date,
+// so it must not be written by another process while the store is in use.
+type JSONLPreferenceStore struct {
+	path        string
+	mu          sync.Mutex
+	loaded      bool
+	preferences map[string]models.UserPreferences
+}
+
+/**
+* @description: Create a preference store on a JSONL file, the file is created on the first write
+* @param path file of the preferences
+* @return preference store
+ */
+func NewJSONLPreferenceStore(path string) *JSONLPreferenceStore {
+	return &JSONLPreferenceStore{path: path}
+}
+
+// load reads the file into the index the first time it is called, s.mu must be held
+func (s *JSONLPreferenceStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	preferences := make(map[string]models.UserPreferences)
+	err := readJSONL(s.path, func(p models.UserPreferences) {
+		preferences[p.UserID] = p
+	})
+	if err != nil {
+		return err
+	}
+	s.preferences, s.loaded = preferences, true
+	return nil
+}
+
+func (s *JSONLPreferenceStore) GetPreferences(ctx context.Context, userID string) (*models.UserPreferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	preferences, ok := s.preferences[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &preferences, nil
+}
+
+func (s *JSONLPreferenceStore) SavePreferences(ctx context.Context, preferences models.UserPreferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, err := replaceJSONL(s.path, func(p models.UserPreferences) bool { return p.UserID == preferences.UserID }, preferences); err != nil {
+		return err
+	}
+	s.preferences[preferences.UserID] = preferences
+	return nil
+}
+
+func (s *JSONLPreferenceStore) DeletePreferences(ctx context.Context, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return false, err
+	}
+	deleted, err := replaceJSONL(s.path, func(p models.UserPreferences) bool { return p.UserID == userID })
+	if err != nil {
+		return false, err
+	}
+	delete(s.preferences, userID)
+	return deleted, nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mealmate-agent/models"
+)
+
+func TestJSONLPreferenceStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "preferences.jsonl")
+	store := NewJSONLPreferenceStore(path)
+
+	if found, err := store.GetPreferences(ctx, "u1"); err != nil || found != nil {
+		t.Errorf("GetPreferences() of a new store = %+v, %v, want nil", found, err)
+	}
+	for _, preferences := range []models.UserPreferences{
+		{UserID: "u1", Allergens: []string{"peanut"}},
+		{UserID: "u2", MaxPriceLevel: 2},
+		{UserID: "u1", DietaryRestrictions: []string{"vegetarian"}},
+	} {
+		if err := store.SavePreferences(ctx, preferences); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Saving replaces the previous preferences, also for a store reading the file again
+	for name, s := range map[string]*JSONLPreferenceStore{"same store": store, "reopened": NewJSONLPreferenceStore(path)} {
+		found, err := s.GetPreferences(ctx, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (models.UserPreferences{UserID: "u1", DietaryRestrictions: []string{"vegetarian"}}); found == nil || !reflect.DeepEqual(*found, want) {
+			t.Errorf("%s: GetPreferences() = %+v, want %+v", name, found, want)
+		}
+	}
+
+	if deleted, err := store.DeletePreferences(ctx, "u1"); err != nil || !deleted {
+		t.Errorf("DeletePreferences() = %v, %v, want deleted", deleted, err)
+	}
+	if deleted, err := store.DeletePreferences(ctx, "u1"); err != nil || deleted {
+		t.Errorf("DeletePreferences() again = %v, %v, want nothing deleted", deleted, err)
+	}
+	if found, err := store.GetPreferences(ctx, "u1"); err != nil || found != nil {
+		t.Errorf("GetPreferences() after delete = %+v, %v, want nil", found, err)
+	}
+	if found, err := NewJSONLPreferenceStore(path).GetPreferences(ctx, "u2"); err != nil || found == nil || found.MaxPriceLevel != 2 {
+		t.Errorf("GetPreferences() of another user = %+v, %v", found, err)
+	}
+
+	// Reads are served from the index loaded on first use, the file is not read again
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := store.GetPreferences(ctx, "u2"); err != nil || found == nil || found.MaxPriceLevel != 2 {
+		t.Errorf("GetPreferences() from the index = %+v, %v", found, err)
+	}
+}
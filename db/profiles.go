@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"mealmate-agent/models"
+)
+
+// ProfileStore keeps the taste profile of every user
+type ProfileStore interface {
+	// GetProfile returns the taste profile of a user, nil if none was built yet
+	GetProfile(ctx context.Context, userID string) (*models.TasteProfile, error)
+	// SaveProfile creates or replaces the taste profile of profile.UserID
+	SaveProfile(ctx context.Context, profile models.TasteProfile) error
+}
+
+// JSONLProfileStore keeps the profiles in a file with one JSON object per user.
+// The file is read once, on first use, into an index by user that later writes keep up to date,
+// so it must not be written by another process while the store is in use.
+type JSONLProfileStore struct {
+	path     string
+	mu       sync.Mutex
+	loaded   bool
+	profiles map[string]models.TasteProfile
+}
+
+/**
+* @description: Create a profile store on a JSONL file, the file is created on the first write
+* @param path file of the profiles
+* @return profile store
+ */
+func NewJSONLProfileStore(path string) *JSONLProfileStore {
+	return &JSONLProfileStore{path: path}
+}
+
+// load reads the file into the index the first time it is called, s.mu must be held
+func (s *JSONLProfileStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	profiles := make(map[string]models.TasteProfile)
+	err := readJSONL(s.path, func(profile models.TasteProfile) {
+		profiles[profile.UserID] = profile
+	})
+	if err != nil {
+		return err
+	}
+	s.profiles, s.loaded = profiles, true
+	return nil
+}
+
+func (s *JSONLProfileStore) GetProfile(ctx context.Context, userID string) (*models.TasteProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	profile, ok := s.profiles[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &profile, nil
+}
+
+func (s *JSONLProfileStore) SaveProfile(ctx context.Context, profile models.TasteProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, err := replaceJSONL(s.path, func(p models.TasteProfile) bool { return p.UserID == profile.UserID }, profile); err != nil {
+		return err
+	}
+	s.profiles[profile.UserID] = profile
+	return nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mealmate-agent/models"
+)
+
+func TestJSONLProfileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "profiles.jsonl")
+	store := NewJSONLProfileStore(path)
+
+	if found, err := store.GetProfile(ctx, "u1"); err != nil || found != nil {
+		t.Errorf("GetProfile() of a new store = %+v, %v, want nil", found, err)
+	}
+	for _, profile := range []models.TasteProfile{
+		{UserID: "u1", Summary: "Loves sushi.", EventCount: 3},
+		{UserID: "u2", Summary: "Burgers on Fridays.", EventCount: 1},
+		{UserID: "u1", Summary: "Loves sushi and ramen.", EventCount: 5},
+	} {
+		if err := store.SaveProfile(ctx, profile); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, s := range map[string]*JSONLProfileStore{"same store": store, "reopened": NewJSONLProfileStore(path)} {
+		found, err := s.GetProfile(ctx, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.Summary != "Loves sushi and ramen." || found.EventCount != 5 {
+			t.Errorf("%s: GetProfile() = %+v, want the latest profile", name, found)
+		}
+		if other, err := s.GetProfile(ctx, "u2"); err != nil || other == nil || other.EventCount != 1 {
+			t.Errorf("%s: GetProfile() of another user = %+v, %v", name, other, err)
+		}
+	}
+
+	// Reads are served from the index loaded on first use, the file is not read again
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := store.GetProfile(ctx, "u2"); err != nil || found == nil || found.EventCount != 1 {
+		t.Errorf("GetProfile() from the index = %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"mealmate-agent/models"
+)
+
+// ErrRecommendationNotFound is returned by RecommendationStore.GetRecommendation when no recommendation has the ID
+var ErrRecommendationNotFound = errors.New("recommendation not found")
+
+// RecommendationStore keeps the recommendations returned to users and their feedback on them
+type RecommendationStore interface {
+	// SaveRecommendations stores recommendations, their IDs must be set, see NewRecommendationID
+	SaveRecommendations(ctx context.Context, records []models.RecommendationRecord) error
+	// GetRecommendation returns one recommendation, ErrRecommendationNotFound if it does not exist
+	GetRecommendation(ctx context.Context, id string) (*models.RecommendationRecord, error)
+	// AddFeedback stores feedback, a recommendation may receive feedback several times
+	AddFeedback(ctx context.Context, feedback models.Feedback) error
+	// FeedbackByUser returns the feedback of a user, oldest first
+	FeedbackByUser(ctx context.Context, userID string) ([]models.Feedback, error)
+}
+
+// NewRecommendationID returns a random recommendation ID
+func NewRecommendationID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "rec-" + hex.EncodeToString(b)
+}
+
+// JSONLRecommendationStore appends recommendations and feedback to two files with one JSON object per line.
+// The files are read once, on first use, into an index by ID and by user that later writes keep up to date,
+// so they must not be written by another process while the store is in use.
+type JSONLRecommendationStore struct {
+	recommendationsPath string
+	feedbackPath        string
+	mu                  sync.Mutex
+	loaded              bool
+	recommendations     map[string]models.RecommendationRecord
+	feedback            map[string][]models.Feedback
+}
+
+/**
+* @description: Create a recommendation store on JSONL files, the files are created on the first write
+* @param recommendationsPath file of the recommendations
+* @param feedbackPath file of the feedback
+* @return recommendation store
+ */
+func NewJSONLRecommendationStore(recommendationsPath, feedbackPath string) *JSONLRecommendationStore {
+	return &JSONLRecommendationStore{recommendationsPath: recommendationsPath, feedbackPath: feedbackPath}
+}
+
+// load reads both files into the index the first time it is called, s.mu must be held
+func (s *JSONLRecommendationStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	recommendations := make(map[string]models.RecommendationRecord)
+	err := readJSONL(s.recommendationsPath, func(record models.RecommendationRecord) {
+		recommendations[record.ID] = record
+	})
+	if err != nil {
+		return err
+	}
+	feedback := make(map[string][]models.Feedback)
+	err = readJSONL(s.feedbackPath, func(f models.Feedback) {
+		feedback[f.UserID] = append(feedback[f.UserID], f)
+	})
+	if err != nil {
+		return err
+	}
+	s.recommendations, s.feedback, s.loaded = recommendations, feedback, true
+	return nil
+}
+
+func (s *JSONLRecommendationStore) SaveRecommendations(ctx context.Context, records []models.RecommendationRecord) error {
+	for _, record := range records {
+		if record.ID == "" {
+			return fmt.Errorf("recommendation of %q has no id", record.Recommendation.RestaurantName)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := appendJSONL(s.recommendationsPath, records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		s.recommendations[record.ID] = record
+	}
+	return nil
+}
+
+func (s *JSONLRecommendationStore) GetRecommendation(ctx context.Context, id string) (*models.RecommendationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	record, ok := s.recommendations[id]
+	if !ok {
+		return nil, ErrRecommendationNotFound
+	}
+	return &record, nil
+}
+
+func (s *JSONLRecommendationStore) AddFeedback(ctx context.Context, feedback models.Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := appendJSONL(s.feedbackPath, []models.Feedback{feedback}); err != nil {
+		return err
+	}
+	s.feedback[feedback.UserID] = append(s.feedback[feedback.UserID], feedback)
+	return nil
+}
+
+func (s *JSONLRecommendationStore) FeedbackByUser(ctx context.Context, userID string) ([]models.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	// A copy, so the caller can keep it while feedback is added
+	return append(make([]models.Feedback, 0, len(s.feedback[userID])), s.feedback[userID]...), nil
+}
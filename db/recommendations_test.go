@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+func TestJSONLRecommendationStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	recommendationsPath, feedbackPath := filepath.Join(dir, "recommendations.jsonl"), filepath.Join(dir, "feedback.jsonl")
+	store := NewJSONLRecommendationStore(recommendationsPath, feedbackPath)
+
+	record := models.RecommendationRecord{
+		ID:             NewRecommendationID(),
+		UserID:         "u1",
+		Recommendation: models.RestaurantRecommendation{RestaurantName: "Sakura"},
+		CreatedAt:      time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if err := store.SaveRecommendations(ctx, []models.RecommendationRecord{record}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveRecommendations(ctx, []models.RecommendationRecord{{UserID: "u1"}}); err == nil {
+		t.Error("SaveRecommendations() of a record without ID succeeded")
+	}
+	if _, err := store.GetRecommendation(ctx, "rec-unknown"); !errors.Is(err, ErrRecommendationNotFound) {
+		t.Errorf("GetRecommendation() of an unknown ID error = %v, want ErrRecommendationNotFound", err)
+	}
+
+	for _, verdict := range []models.FeedbackVerdict{models.FeedbackAccepted, models.FeedbackVisited} {
+		err := store.AddFeedback(ctx, models.Feedback{RecommendationID: record.ID, UserID: "u1", RestaurantName: "Sakura", Verdict: verdict})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.AddFeedback(ctx, models.Feedback{UserID: "u2", Verdict: models.FeedbackRejected}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store reads what the first one wrote
+	reopened := NewJSONLRecommendationStore(recommendationsPath, feedbackPath)
+	got, err := reopened.GetRecommendation(ctx, record.ID)
+	if err != nil || got.UserID != "u1" || got.Recommendation.RestaurantName != "Sakura" || !got.CreatedAt.Equal(record.CreatedAt) {
+		t.Errorf("GetRecommendation() = %+v, %v, want the saved record", got, err)
+	}
+	feedback, err := reopened.FeedbackByUser(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feedback) != 2 || feedback[0].Verdict != models.FeedbackAccepted || feedback[1].Verdict != models.FeedbackVisited {
+		t.Errorf("FeedbackByUser() = %+v, want accepted then visited", feedback)
+	}
+	if none, err := reopened.FeedbackByUser(ctx, "u3"); err != nil || len(none) != 0 {
+		t.Errorf("FeedbackByUser() of a user without feedback = %+v, %v", none, err)
+	}
+}
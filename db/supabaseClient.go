@@ -1,13 +1,170 @@
 package db
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/telemetry"
+
+	"github.com/supabase-community/postgrest-go"
 	"github.com/supabase-community/supabase-go"
 )
 
-func NewSupabaseClient(SUPABASE_API_URL, SUPABASE_API_KEY string) *supabase.Client {
-	client, err := supabase.NewClient(SUPABASE_API_URL, SUPABASE_API_KEY, &supabase.ClientOptions{})
+const (
+	// supabasePageSize is the number of rows fetched per request, PostgREST caps responses at 1000 rows by default
+	supabasePageSize = 1000
+	// supabaseIDChunk is the number of IDs checked per request, they are sent in the URL so a long list would exceed its limit
+	supabaseIDChunk = 200
+)
+
+/**
+* @description: Create a supabase client
+* @param apiURL project URL
+* @param apiKey project API key
+* @return client and error
+ */
+func NewSupabaseClient(apiURL, apiKey string) (*supabase.Client, error) {
+	return supabase.NewClient(apiURL, apiKey, &supabase.ClientOptions{})
+}
+
+// SupabaseEventSource reads events from a Supabase table through PostgREST
+type SupabaseEventSource struct {
+	client *supabase.Client
+	table  string
+}
+
+/**
+* @description: Create an event source on a Supabase table
+* @param client supabase client
+* @param table event table name
+* @return event source and error
+ */
+func NewSupabaseEventSource(client *supabase.Client, table string) (*SupabaseEventSource, error) {
+	if err := validateTableName(table); err != nil {
+		return nil, err
+	}
+	return &SupabaseEventSource{client: client, table: table}, nil
+}
+
+func (s *SupabaseEventSource) ListByUser(ctx context.Context, userID string) ([]models.Event, error) {
+	return s.selectEvents(ctx, "list_by_user", func(q *postgrest.FilterBuilder) *postgrest.FilterBuilder {
+		return q.Eq("user_id", userID)
+	}, "id")
+}
+
+func (s *SupabaseEventSource) ListSince(ctx context.Context, cursor SyncCursor) ([]models.Event, error) {
+	// The timestamp is quoted, its colons and dots are reserved inside a logical filter
+	createdAt := strconv.Quote(cursor.CreatedAt.UTC().Format(time.RFC3339Nano))
+	after := fmt.Sprintf("created_at.gt.%s,and(created_at.eq.%s,id.gt.%d)", createdAt, createdAt, cursor.ID)
+	return s.selectEvents(ctx, "list_since", func(q *postgrest.FilterBuilder) *postgrest.FilterBuilder {
+		return q.Or(after, "")
+	}, "created_at", "id")
+}
+
+func (s *SupabaseEventSource) GetByID(ctx context.Context, id int) (*models.Event, error) {
+	events, err := s.selectEvents(ctx, "get_by_id", func(q *postgrest.FilterBuilder) *postgrest.FilterBuilder {
+		return q.Eq("id", strconv.Itoa(id))
+	}, "id")
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrEventNotFound
+	}
+	return &events[0], nil
+}
+
+func (s *SupabaseEventSource) DetectDeletions(ctx context.Context, ids []int) (missing []int, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	_, span := startSourceSpan(ctx, "supabase", "detect_deletions", s.table)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	found := make(map[int]bool, len(ids))
+	for chunk := range slices.Chunk(ids, supabaseIDChunk) {
+		values := make([]string, 0, len(chunk))
+		for _, id := range chunk {
+			values = append(values, strconv.Itoa(id))
+		}
+		data, _, err := s.client.From(s.table).Select("id", "", false).In("id", values).Execute()
+		if err != nil {
+			return nil, err
+		}
+		var rows []struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			found[row.ID] = true
+		}
+	}
+	return missingIDs(ids, found), nil
+}
+
+func (s *SupabaseEventSource) Check(ctx context.Context) (err error) {
+	_, span := startSourceSpan(ctx, "supabase", "check", s.table)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	// postgrest does not accept a context, so run the query in the background and honour ctx here
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := s.client.From(s.table).Select("id", "", false).Limit(1, "").Execute()
+		errCh <- err
+	}()
+	select {
+	case err = <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *SupabaseEventSource) InsertEvents(ctx context.Context, events []models.Event) (err error) {
+	if len(events) == 0 {
+		return nil
+	}
+	_, span := startSourceSpan(ctx, "supabase", "insert", s.table)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	_, _, err = s.client.From(s.table).Upsert(events, "id", "minimal", "").Execute()
+	return err
+}
+
+// Close is a no-op, the supabase client only holds pooled HTTP connections
+func (s *SupabaseEventSource) Close() error {
+	return nil
+}
+
+// selectEvents fetches every row of the event table kept by filter, traced as one supabase call.
+// Rows are sorted by orderBy and read a page at a time until a short page comes back.
+func (s *SupabaseEventSource) selectEvents(ctx context.Context, operation string, filter func(*postgrest.FilterBuilder) *postgrest.FilterBuilder, orderBy ...string) (events []models.Event, err error) {
+	_, span := startSourceSpan(ctx, "supabase", operation, s.table)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	for offset := 0; ; offset += supabasePageSize {
+		query := filter(s.client.From(s.table).Select("*", "", false))
+		for _, order := range orderBy {
+			query = query.Order(order, &postgrest.OrderOpts{Ascending: true})
+		}
+		data, _, err := query.Range(offset, offset+supabasePageSize-1, "").Execute()
+		if err != nil {
+			return nil, err
+		}
+		var page []models.Event
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+		if len(page) < supabasePageSize {
+			return events, nil
+		}
 	}
-	return client
 }
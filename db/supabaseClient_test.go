@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+// fakePostgREST serves the rows of one event table the way PostgREST does, paging with offset and limit
+type fakePostgREST struct {
+	events []models.Event
+
+	mu      sync.Mutex
+	queries []url.Values
+}
+
+func (f *fakePostgREST) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f.mu.Lock()
+	f.queries = append(f.queries, query)
+	f.mu.Unlock()
+	if in, ok := strings.CutPrefix(query.Get("id"), "in.("); ok {
+		f.serveIDs(w, strings.Split(strings.TrimSuffix(in, ")"), ","))
+		return
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		limit = len(f.events)
+	}
+	rows := f.events[min(offset, len(f.events)):min(offset+limit, len(f.events))]
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rows)
+}
+
+// serveIDs answers an id=in.(...) filter with the IDs that exist
+func (f *fakePostgREST) serveIDs(w http.ResponseWriter, ids []string) {
+	type row struct {
+		ID int `json:"id"`
+	}
+	rows := make([]row, 0, len(ids))
+	for _, value := range ids {
+		id, _ := strconv.Atoi(value)
+		if id >= 1 && id <= len(f.events) {
+			rows = append(rows, row{ID: id})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rows)
+}
+
+// newFakeSupabase serves n events from a fake PostgREST and returns a source reading them
+func newFakeSupabase(t *testing.T, n int) (*SupabaseEventSource, *fakePostgREST) {
+	t.Helper()
+	fake := &fakePostgREST{}
+	created := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		fake.events = append(fake.events, models.Event{ID: i, UserID: "u1", CreatedAt: created.Format(time.RFC3339)})
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client, err := NewSupabaseClient(server.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewSupabaseEventSource(client, "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source, fake
+}
+
+func TestSupabaseListPages(t *testing.T) {
+	tests := []struct {
+		name         string
+		events       int
+		wantRequests int
+	}{
+		{"empty", 0, 1},
+		{"one short page", 10, 1},
+		{"exactly one page", supabasePageSize, 2},
+		{"several pages", 2*supabasePageSize + 500, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, fake := newFakeSupabase(t, tt.events)
+			events, err := source.ListSince(context.Background(), SyncCursor{CreatedAt: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), ID: 7})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != tt.events || len(fake.queries) != tt.wantRequests {
+				t.Fatalf("ListSince() = %d events in %d requests, want %d in %d", len(events), len(fake.queries), tt.events, tt.wantRequests)
+			}
+			for i, e := range events {
+				if e.ID != i+1 {
+					t.Fatalf("event %d has ID %d, pages overlap or are missing", i, e.ID)
+				}
+			}
+			// Pages are only stable over a total order
+			if order := fake.queries[0].Get("order"); order != "created_at.asc.nullslast,id.asc.nullslast" {
+				t.Errorf("order = %q", order)
+			}
+			const after = `(created_at.gt."2025-01-01T00:00:00Z",and(created_at.eq."2025-01-01T00:00:00Z",id.gt.7))`
+			if filter := fake.queries[0].Get("or"); filter != after {
+				t.Errorf("or = %q, want %q", filter, after)
+			}
+		})
+	}
+}
+
+func TestSupabaseDetectDeletionsInChunks(t *testing.T) {
+	source, fake := newFakeSupabase(t, 400)
+	ids := make([]int, 0, 450)
+	for id := 1; id <= 450; id++ {
+		ids = append(ids, id)
+	}
+	missing, err := source.DetectDeletions(context.Background(), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 50 || missing[0] != 401 || missing[49] != 450 {
+		t.Errorf("DetectDeletions() = %v, want 401 to 450", missing)
+	}
+	if len(fake.queries) != 3 {
+		t.Fatalf("DetectDeletions() sent %d requests, want 3", len(fake.queries))
+	}
+	for _, query := range fake.queries {
+		if n := len(strings.Split(query.Get("id"), ",")); n > supabaseIDChunk {
+			t.Errorf("a request checked %d IDs, want at most %d", n, supabaseIDChunk)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// SyncCursorStore keeps the position of the automatic sync so a restart resumes where the last sync stopped
+type SyncCursorStore interface {
+	// LoadCursor returns the saved cursor, false when none was saved yet
+	LoadCursor(ctx context.Context) (SyncCursor, bool, error)
+	// SaveCursor replaces the saved cursor
+	SaveCursor(ctx context.Context, cursor SyncCursor) error
+}
+
+// JSONLSyncCursorStore keeps the cursor as the single line of a JSONL file
+type JSONLSyncCursorStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+/**
+* @description: Create a sync cursor store on a JSONL file, the file is created on the first save
+* @param path file path
+* @return sync cursor store
+ */
+func NewJSONLSyncCursorStore(path string) *JSONLSyncCursorStore {
+	return &JSONLSyncCursorStore{path: path}
+}
+
+func (s *JSONLSyncCursorStore) LoadCursor(ctx context.Context) (cursor SyncCursor, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = readJSONL(s.path, func(saved SyncCursor) {
+		cursor, ok = saved, true
+	})
+	return cursor, ok, err
+}
+
+func (s *JSONLSyncCursorStore) SaveCursor(ctx context.Context, cursor SyncCursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return rewriteJSONL(s.path, []SyncCursor{cursor})
+}
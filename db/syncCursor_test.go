@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"mealmate-agent/models"
+)
+
+func TestJSONLSyncCursorStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewJSONLSyncCursorStore(filepath.Join(t.TempDir(), "sync_cursor.jsonl"))
+	if _, ok, err := store.LoadCursor(ctx); err != nil || ok {
+		t.Fatalf("LoadCursor() of a new store = %v, %v, want none", ok, err)
+	}
+	for _, cursor := range []SyncCursor{
+		{CreatedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), ID: 1},
+		{CreatedAt: time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC), ID: 7},
+	} {
+		if err := store.SaveCursor(ctx, cursor); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok, err := store.LoadCursor(ctx); err != nil || !ok || got != cursor {
+			t.Errorf("LoadCursor() = %+v, %v, %v, want %+v", got, ok, err, cursor)
+		}
+	}
+}
+
+func TestAutomaticSyncCursor(t *testing.T) {
+	ctx := context.Background()
+	cursors := NewJSONLSyncCursorStore(filepath.Join(t.TempDir(), "sync_cursor.jsonl"))
+	// Start from the beginning instead of the last interval
+	if err := cursors.SaveCursor(ctx, SyncCursor{}); err != nil {
+		t.Fatal(err)
+	}
+	db, source := newTestDatabase(t, testEvents()...)
+	db.SyncCursors = cursors
+	savedCursor := func() SyncCursor {
+		t.Helper()
+		cursor, _, err := cursors.LoadCursor(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cursor
+	}
+
+	if err := db.AutomaticSyncDatabase(ctx); err != nil {
+		t.Fatal(err)
+	}
+	third := SyncCursor{CreatedAt: time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC), ID: 3}
+	if got := savedCursor(); got != third {
+		t.Fatalf("cursor = %+v after the first sync, want %+v", got, third)
+	}
+
+	// A failed batch leaves the cursor where it was so the next run fetches it again
+	late := models.Event{ID: 5, UserID: "u2", RestaurantName: "Pho 24", CreatedAt: "2025-03-04T12:00:00Z"}
+	if err := source.InsertEvents(ctx, []models.Event{late}); err != nil {
+		t.Fatal(err)
+	}
+	healthy := db.Store
+	db.Store = &failingStore{backend: healthy, failUser: "u2"}
+	if err := db.AutomaticSyncDatabase(ctx); err == nil {
+		t.Fatal("AutomaticSyncDatabase() succeeded while the store fails")
+	}
+	if got := savedCursor(); got != third {
+		t.Errorf("cursor = %+v after a failed sync, want %+v", got, third)
+	}
+	db.Store = healthy
+	if err := db.AutomaticSyncDatabase(ctx); err != nil {
+		t.Fatal(err)
+	}
+	fifth := SyncCursor{CreatedAt: time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC), ID: 5}
+	if got := savedCursor(); got != fifth {
+		t.Errorf("cursor = %+v after the retry, want %+v", got, fifth)
+	}
+	if got := indexedIDs(t, db, ""); !reflect.DeepEqual(got, []string{"1", "2", "3", "5"}) {
+		t.Errorf("indexed %v, want every dated event", got)
+	}
+
+	// A restart resumes from the saved cursor
+	restarted, _ := newTestDatabase(t, append(testEvents(), late)...)
+	restarted.SyncCursors = cursors
+	if err := restarted.AutomaticSyncDatabase(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := indexedIDs(t, restarted, ""); len(got) != 0 {
+		t.Errorf("restarted sync indexed %v again, want nothing", got)
+	}
+}
@@ -0,0 +1,108 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+const (
+	// wordWeight and gramWeight balance whole-word matches against character trigram overlap
+	wordWeight = 1.0
+	gramWeight = 0.5
+)
+
+// HashEmbedder projects hashed words and character trigrams into a fixed dimension vector.
+// It needs no network and returns the same vector for the same text on every run,
+// which makes it suitable for development and offline tests but not for semantic quality.
+type HashEmbedder struct {
+	dim int
+}
+
+/**
+* @description: Create a hashed n-gram embedder
+* @param dim vector dimension, must match the vector store collection
+* @return embedder instance and error
+ */
+func NewHashEmbedder(dim int) (*HashEmbedder, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("embedding dimension must be positive, got %d", dim)
+	}
+	return &HashEmbedder{dim: dim}, nil
+}
+
+// EmbedStrings returns one L2-normalised vector per text
+func (e *HashEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) (vectors [][]float64, err error) {
+	ctx = callbacks.EnsureRunInfo(ctx, e.GetType(), components.ComponentOfEmbedding)
+	ctx = callbacks.OnStart(ctx, &embedding.CallbackInput{Texts: texts})
+
+	vectors = make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+
+	callbacks.OnEnd(ctx, &embedding.CallbackOutput{Embeddings: vectors})
+	return vectors, nil
+}
+
+func (e *HashEmbedder) embed(text string) []float64 {
+	vector := make([]float64, e.dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		e.add(vector, "w:"+word, wordWeight)
+		// Boundary markers let short words still produce trigrams
+		runes := []rune("#" + word + "#")
+		for j := 0; j+3 <= len(runes); j++ {
+			e.add(vector, "g:"+string(runes[j:j+3]), gramWeight)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// add hashes feature to a bucket and a sign, the sign keeps collisions from always adding up
+func (e *HashEmbedder) add(vector []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(feature))
+	sum := h.Sum64()
+	index := int(sum % uint64(e.dim))
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[index] += weight
+}
+
+// Dimension returns the vector dimension
+func (e *HashEmbedder) Dimension() int {
+	return e.dim
+}
+
+// GetType reports the component type for eino callbacks
+func (e *HashEmbedder) GetType() string {
+	return "Hash"
+}
+
+// IsCallbacksEnabled is true because EmbedStrings reports callbacks itself
+func (e *HashEmbedder) IsCallbacksEnabled() bool {
+	return true
+}
@@ -0,0 +1,74 @@
+package embedder
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	e, err := NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{"Sushi dinner at Sakura", "sushi, DINNER at sakura!", "Pizza night at Luigi", ""}
+	vectors, err := e.EmbedStrings(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := e.EmbedStrings(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vectors, again) {
+		t.Error("EmbedStrings() is not deterministic")
+	}
+
+	tests := []struct {
+		name string
+		vec  []float64
+		norm float64
+	}{
+		{"text", vectors[0], 1},
+		{"punctuation and case", vectors[1], 1},
+		{"empty", vectors[3], 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.vec) != 64 {
+				t.Fatalf("vector has %d dimensions, want 64", len(tt.vec))
+			}
+			if got := norm(tt.vec); math.Abs(got-tt.norm) > 1e-9 {
+				t.Errorf("norm = %f, want %f", got, tt.norm)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Error("case and punctuation change the vector")
+	}
+	if dot(vectors[0], vectors[1]) <= dot(vectors[0], vectors[2]) {
+		t.Error("the same words are not closer than different ones")
+	}
+}
+
+func TestNewHashEmbedderRejectsDimension(t *testing.T) {
+	for _, dim := range []int{0, -1} {
+		if _, err := NewHashEmbedder(dim); err == nil {
+			t.Errorf("NewHashEmbedder(%d) succeeded, want an error", dim)
+		}
+	}
+}
+
+func norm(v []float64) float64 {
+	return math.Sqrt(dot(v, v))
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
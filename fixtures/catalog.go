@@ -0,0 +1,108 @@
+package fixtures
+
+// City is a center around which restaurants are placed
+type City struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	// Timezone is the IANA zone meal times are planned in
+	Timezone string
+}
+
+// Cities are the built-in cities, keyed by the name used on the command line
+var Cities = map[string]City{
+	"new_york":      {"New York", 40.7359, -73.9911, "America/New_York"},
+	"san_francisco": {"San Francisco", 37.7793, -122.4192, "America/Los_Angeles"},
+	"london":        {"London", 51.5145, -0.1270, "Europe/London"},
+	"paris":         {"Paris", 48.8566, 2.3522, "Europe/Paris"},
+	"tokyo":         {"Tokyo", 35.6812, 139.7671, "Asia/Tokyo"},
+	"singapore":     {"Singapore", 1.2834, 103.8607, "Asia/Singapore"},
+}
+
+// cuisine lists the material restaurants of one kind are generated from
+type cuisine struct {
+	name   string
+	places []string
+	dishes []string
+	// meals are the meals this kind of place is usually visited for
+	meals []meal
+}
+
+type meal int
+
+const (
+	breakfast meal = iota
+	brunch
+	lunch
+	dinner
+	lateNight
+)
+
+// mealHours is the local hour range, end exclusive, a meal is scheduled in
+var mealHours = map[meal][2]int{
+	breakfast: {7, 10},
+	brunch:    {10, 13},
+	lunch:     {11, 14},
+	dinner:    {18, 22},
+	lateNight: {22, 24},
+}
+
+var cuisines = []cuisine{
+	{
+		name:   "japanese",
+		places: []string{"Sushi Zen", "Sakura House", "Ramen Ichi", "Izakaya Tora", "Omakase Kaito", "Udon Mugi"},
+		dishes: []string{"salmon nigiri", "omakase", "tonkotsu ramen", "chicken karaage", "tempura udon", "unagi don"},
+		meals:  []meal{lunch, dinner, lateNight},
+	},
+	{
+		name:   "italian",
+		places: []string{"Trattoria Roma", "Napoli Pizza", "Osteria Luca", "Pasta Fresca", "Il Forno"},
+		dishes: []string{"margherita pizza", "cacio e pepe", "lasagna", "truffle risotto", "tiramisu"},
+		meals:  []meal{lunch, dinner},
+	},
+	{
+		name:   "mexican",
+		places: []string{"Taqueria El Sol", "Casa Mole", "Burrito Loco", "La Cantina"},
+		dishes: []string{"al pastor tacos", "chicken mole", "carnitas burrito", "guacamole and chips", "churros"},
+		meals:  []meal{lunch, dinner, lateNight},
+	},
+	{
+		name:   "indian",
+		places: []string{"Green Leaf", "Masala Corner", "Tandoor House", "Chai and Dosa"},
+		dishes: []string{"vegetarian thali", "butter chicken", "masala dosa", "lamb biryani", "paneer tikka"},
+		meals:  []meal{lunch, dinner},
+	},
+	{
+		name:   "american",
+		places: []string{"Burger Hub", "Smokehouse BBQ", "Liberty Diner", "Prime Steak"},
+		dishes: []string{"double cheeseburger", "pulled pork sandwich", "brisket platter", "ribeye steak", "buttermilk pancakes"},
+		meals:  []meal{breakfast, lunch, dinner},
+	},
+	{
+		name:   "thai",
+		places: []string{"Pad Thai Express", "Bangkok Street", "Curry House", "Lemongrass"},
+		dishes: []string{"pad thai", "green curry", "tom yum soup", "mango sticky rice", "papaya salad"},
+		meals:  []meal{lunch, dinner},
+	},
+	{
+		name:   "cafe",
+		places: []string{"Café Marais", "Boulangerie Saint-Paul", "Morning Grind", "The Avocado Club"},
+		dishes: []string{"eggs benedict", "croissants and coffee", "avocado toast", "granola bowl", "flat white and a muffin"},
+		meals:  []meal{breakfast, brunch},
+	},
+	{
+		name:   "vegan",
+		places: []string{"Falafel Corner", "Poke Bowl Co", "Plant Kitchen", "Sprout Salad Bar"},
+		dishes: []string{"falafel wrap", "tuna poke", "jackfruit tacos", "quinoa salad", "hummus plate"},
+		meals:  []meal{lunch, dinner},
+	},
+}
+
+// Message parts, a message reads "<dish> <company>, <opinion>"
+var (
+	companions = []string{"with friends", "with the team", "with my partner", "alone", "with family", "for a birthday", "with a client", "after the gym"}
+	opinions   = []string{
+		"amazing as always", "great value", "a bit slow but tasty", "would go back", "too salty this time",
+		"perfect portion size", "cozy place", "loud but fun", "best in town", "not worth the wait",
+	}
+)
@@ -0,0 +1,226 @@
+// Package fixtures generates reproducible synthetic users and dining events and reads and writes them as JSONL
+package fixtures
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+	// Embedded zone database so city time zones resolve on hosts without one
+	_ "time/tzdata"
+
+	"mealmate-agent/models"
+)
+
+// earthRadiusKm converts the placement radius into degrees
+const earthRadiusKm = 6371.0
+
+// GeneratorConfig controls the size and shape of a generated dataset, the same config always yields the same events
+type GeneratorConfig struct {
+	Seed          uint64
+	Users         int
+	EventsPerUser int
+	// Cities are keys of Cities, each user lives in one of them
+	Cities []string
+	// Start and Days bound the creation time of the events
+	Start time.Time
+	Days  int
+	// FirstID is the ID of the first event, IDs are consecutive
+	FirstID int
+	// RadiusKm is how far from the city center restaurants are placed
+	RadiusKm float64
+	// RestaurantsPerCuisine is how many places of each cuisine exist per city
+	RestaurantsPerCuisine int
+}
+
+// DefaultGeneratorConfig is a small dataset spread over three cities
+func DefaultGeneratorConfig() GeneratorConfig {
+	return GeneratorConfig{
+		Seed:                  42,
+		Users:                 10,
+		EventsPerUser:         8,
+		Cities:                []string{"new_york", "london", "paris"},
+		Start:                 time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Days:                  90,
+		FirstID:               100000,
+		RadiusKm:              5,
+		RestaurantsPerCuisine: 3,
+	}
+}
+
+func (c GeneratorConfig) validate() error {
+	if c.Users <= 0 || c.EventsPerUser <= 0 {
+		return fmt.Errorf("users and events per user must be positive")
+	}
+	if c.Days <= 0 {
+		return fmt.Errorf("days must be positive")
+	}
+	if c.RadiusKm <= 0 {
+		return fmt.Errorf("radius must be positive")
+	}
+	if c.RestaurantsPerCuisine <= 0 {
+		return fmt.Errorf("restaurants per cuisine must be positive")
+	}
+	if len(c.Cities) == 0 {
+		return fmt.Errorf("at least one city is required")
+	}
+	for _, key := range c.Cities {
+		if _, ok := Cities[key]; !ok {
+			return fmt.Errorf("unknown city %q, known cities are %v", key, CityKeys())
+		}
+	}
+	return nil
+}
+
+// CityKeys returns the keys of Cities, sorted
+func CityKeys() []string {
+	keys := make([]string, 0, len(Cities))
+	for key := range Cities {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type restaurant struct {
+	name     string
+	cuisine  *cuisine
+	coords   models.Coordinates
+	location *time.Location
+}
+
+/**
+* @description: Generate users and their dining events
+* @param config dataset size and shape
+* @return events ordered by ID, error if the config is invalid
+ */
+func Generate(config GeneratorConfig) ([]models.Event, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewPCG(config.Seed, config.Seed^0x9e3779b97f4a7c15))
+
+	// Restaurants are fixed per city so users of the same city share places and repeat visits
+	restaurants := make(map[string][]restaurant, len(config.Cities))
+	for _, key := range config.Cities {
+		places, err := cityRestaurants(rng, Cities[key], config)
+		if err != nil {
+			return nil, err
+		}
+		restaurants[key] = places
+	}
+
+	events := make([]models.Event, 0, config.Users*config.EventsPerUser)
+	id := config.FirstID
+	for u := 0; u < config.Users; u++ {
+		userID := uuid(rng)
+		places := restaurants[config.Cities[rng.IntN(len(config.Cities))]]
+		favorites := favoriteCuisines(rng)
+		for e := 0; e < config.EventsPerUser; e++ {
+			place := pickRestaurant(rng, places, favorites)
+			createdAt := config.Start.Add(time.Duration(rng.Int64N(int64(config.Days) * int64(24*time.Hour)))).In(place.location)
+			events = append(events, models.Event{
+				ID:                    id,
+				UserID:                userID,
+				RestaurantName:        place.name,
+				Message:               message(rng, place.cuisine),
+				ScheduleTime:          scheduleTime(rng, createdAt, place.cuisine).Format(time.RFC3339),
+				CreatedAt:             createdAt.Format(time.RFC3339),
+				RestaurantCoordinates: place.coords,
+			})
+			id++
+		}
+	}
+	return events, nil
+}
+
+func cityRestaurants(rng *rand.Rand, city City, config GeneratorConfig) ([]restaurant, error) {
+	location, err := time.LoadLocation(city.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	places := make([]restaurant, 0, len(cuisines)*config.RestaurantsPerCuisine)
+	for i := range cuisines {
+		c := &cuisines[i]
+		names := append([]string(nil), c.places...)
+		rng.Shuffle(len(names), func(a, b int) { names[a], names[b] = names[b], names[a] })
+		for _, name := range names[:min(config.RestaurantsPerCuisine, len(names))] {
+			places = append(places, restaurant{name: name, cuisine: c, coords: around(rng, city, config.RadiusKm), location: location})
+		}
+	}
+	return places, nil
+}
+
+// around returns a point uniformly distributed within radiusKm of the city center
+func around(rng *rand.Rand, city City, radiusKm float64) models.Coordinates {
+	distance := radiusKm * math.Sqrt(rng.Float64())
+	bearing := 2 * math.Pi * rng.Float64()
+	dLat := distance * math.Cos(bearing) / earthRadiusKm
+	dLon := distance * math.Sin(bearing) / (earthRadiusKm * math.Cos(city.Latitude*math.Pi/180))
+	return models.Coordinates{
+		Latitude:  round(city.Latitude+dLat*180/math.Pi, 6),
+		Longitude: round(city.Longitude+dLon*180/math.Pi, 6),
+	}
+}
+
+// favoriteCuisines gives each user two or three cuisines they pick most of the time, so histories have a taste
+func favoriteCuisines(rng *rand.Rand) map[string]bool {
+	favorites := make(map[string]bool)
+	for _, i := range rng.Perm(len(cuisines))[:2+rng.IntN(2)] {
+		favorites[cuisines[i].name] = true
+	}
+	return favorites
+}
+
+// pickRestaurant picks a favorite cuisine 80% of the time
+func pickRestaurant(rng *rand.Rand, places []restaurant, favorites map[string]bool) restaurant {
+	wantFavorite := rng.Float64() < 0.8
+	candidates := make([]restaurant, 0, len(places))
+	for _, place := range places {
+		if favorites[place.cuisine.name] == wantFavorite {
+			candidates = append(candidates, place)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = places
+	}
+	return candidates[rng.IntN(len(candidates))]
+}
+
+func message(rng *rand.Rand, c *cuisine) string {
+	dish := c.dishes[rng.IntN(len(c.dishes))]
+	return fmt.Sprintf("%s %s, %s", capitalize(dish), companions[rng.IntN(len(companions))], opinions[rng.IntN(len(opinions))])
+}
+
+// scheduleTime plans the meal up to two weeks after the event was created, at a fitting local hour
+func scheduleTime(rng *rand.Rand, createdAt time.Time, c *cuisine) time.Time {
+	hours := mealHours[c.meals[rng.IntN(len(c.meals))]]
+	day := createdAt.AddDate(0, 0, 1+rng.IntN(14))
+	hour := hours[0] + rng.IntN(hours[1]-hours[0])
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, 15*rng.IntN(4), 0, 0, createdAt.Location())
+}
+
+// uuid returns a random version 4 UUID drawn from rng
+func uuid(rng *rand.Rand) string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(rng.UintN(256))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,92 @@
+package fixtures
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	config := DefaultGeneratorConfig()
+	first, err := Generate(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Generate(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Generate() with the same seed returned different events")
+	}
+	if want := config.Users * config.EventsPerUser; len(first) != want {
+		t.Errorf("Generate() returned %d events, want %d", len(first), want)
+	}
+	for i, event := range first {
+		if event.ID != config.FirstID+i {
+			t.Fatalf("event %d has ID %d, want %d", i, event.ID, config.FirstID+i)
+		}
+	}
+
+	config.Seed++
+	other, err := Generate(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(first, other) {
+		t.Error("Generate() with another seed returned the same events")
+	}
+}
+
+// TestGenerateMatchesFixtures keeps testdata/fixtures/events.jsonl in sync with the generator,
+// regenerate it with go run ./cmd/mealmate generate -out testdata/fixtures/events.jsonl
+func TestGenerateMatchesFixtures(t *testing.T) {
+	events, err := Generate(DefaultGeneratorConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := WriteJSONL(&b, events); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../testdata/fixtures/events.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Error("the default dataset differs from testdata/fixtures/events.jsonl")
+	}
+
+	read, err := ReadJSONL(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, events) {
+		t.Error("ReadJSONL() does not return the events written by WriteJSONL()")
+	}
+}
+
+func TestGenerateRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *GeneratorConfig)
+	}{
+		{"no users", func(c *GeneratorConfig) { c.Users = 0 }},
+		{"no events", func(c *GeneratorConfig) { c.EventsPerUser = 0 }},
+		{"no days", func(c *GeneratorConfig) { c.Days = 0 }},
+		{"no radius", func(c *GeneratorConfig) { c.RadiusKm = 0 }},
+		{"no restaurants", func(c *GeneratorConfig) { c.RestaurantsPerCuisine = 0 }},
+		{"no cities", func(c *GeneratorConfig) { c.Cities = nil }},
+		{"unknown city", func(c *GeneratorConfig) { c.Cities = []string{"atlantis"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultGeneratorConfig()
+			tt.modify(&config)
+			if _, err := Generate(config); err == nil {
+				t.Error("Generate() succeeded, want an error")
+			}
+		})
+	}
+}
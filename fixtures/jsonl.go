@@ -0,0 +1,42 @@
+package fixtures
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"mealmate-agent/models"
+)
+
+// WriteJSONL writes one event per line, the format read by the jsonl event source
+func WriteJSONL(w io.Writer, events []models.Event) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadJSONL reads events written by WriteJSONL, blank lines are skipped
+func ReadJSONL(r io.Reader) ([]models.Event, error) {
+	events := make([]models.Event, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var event models.Event
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
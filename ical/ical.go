@@ -0,0 +1,133 @@
+// Package ical writes RFC 5545 iCalendar files that calendar apps can import
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mealmate-agent/models"
+)
+
+const (
+	// lineLimit is the maximal length of a content line in octets, without the CRLF
+	lineLimit = 75
+	// utcLayout is a UTC DATE-TIME, floatingLayout a local one bound to no zone
+	utcLayout      = "20060102T150405Z"
+	floatingLayout = "20060102T150405"
+)
+
+// Event is one VEVENT
+type Event struct {
+	// UID must stay the same when the event is exported again, so calendars update it instead of adding a copy
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Geo         *models.Coordinates
+	Start       time.Time
+	End         time.Time
+	// Floating writes Start and End as clock times in whatever zone the calendar is, else they are written in UTC
+	Floating bool
+}
+
+// Calendar is a VCALENDAR of events
+type Calendar struct {
+	// ProdID identifies the product that wrote the calendar, e.g. "-//MealMate//Meal Plan//EN"
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+/**
+* @description: Write the calendar as an iCalendar file
+* @param w where the file is written
+* @param stamp when the calendar was created, the DTSTAMP of every event
+* @return error if writing failed
+ */
+func (c Calendar) Write(w io.Writer, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + escape(c.ProdID),
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	if c.Name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+escape(c.Name))
+	}
+	for _, e := range c.Events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escape(e.UID),
+			"DTSTAMP:"+stamp.UTC().Format(utcLayout),
+			"DTSTART:"+formatTime(e.Start, e.Floating),
+			"DTEND:"+formatTime(e.End, e.Floating),
+			"SUMMARY:"+escape(e.Summary),
+		)
+		if e.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.Location != "" {
+			lines = append(lines, "LOCATION:"+escape(e.Location))
+		}
+		if e.Geo != nil {
+			lines = append(lines, fmt.Sprintf("GEO:%.6f;%.6f", e.Geo.Latitude, e.Geo.Longitude))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := bw.WriteString(fold(line)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func formatTime(t time.Time, floating bool) string {
+	if floating {
+		return t.Format(floatingLayout)
+	}
+	return t.UTC().Format(utcLayout)
+}
+
+// escaper quotes the characters that are special in a TEXT value, newlines become \n
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// fold splits a content line into lines of at most 75 octets ended by CRLF, continuations start with a space,
+// a UTF-8 character is never split
+func fold(line string) string {
+	var b strings.Builder
+	limit := lineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation counts toward its length
+		limit = lineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
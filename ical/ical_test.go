@@ -0,0 +1,125 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"mealmate-agent/models"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Sushi Zen", "Sushi Zen"},
+		{"Ramen, gyoza; beer", `Ramen\, gyoza\; beer`},
+		{`C:\menu`, `C:\\menu`},
+		{"line\nbreak", `line\nbreak`},
+		{"windows\r\nbreak", `windows\nbreak`},
+		{"old mac\rbreak", `old mac\nbreak`},
+		{"END:VEVENT\nBEGIN:VEVENT", `END:VEVENT\nBEGIN:VEVENT`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := escape(tt.in); got != tt.want {
+				t.Errorf("escape() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Lunch"},
+		{"exactly the limit", "SUMMARY:" + strings.Repeat("a", lineLimit-len("SUMMARY:"))},
+		{"one over the limit", "SUMMARY:" + strings.Repeat("a", lineLimit-len("SUMMARY:")+1)},
+		{"several lines", "DESCRIPTION:" + strings.Repeat("abcdefghij", 30)},
+		{"multibyte", "DESCRIPTION:" + strings.Repeat("寿司と味噌汁", 20)},
+		{"emoji", "SUMMARY:" + strings.Repeat("🍣", 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := fold(tt.line)
+			if !strings.HasSuffix(folded, "\r\n") {
+				t.Fatalf("fold() = %q, not ended by CRLF", folded)
+			}
+			lines := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+			var unfolded strings.Builder
+			for i, line := range lines {
+				if len(line) > lineLimit {
+					t.Errorf("line %d has %d octets, want at most %d", i, len(line), lineLimit)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a UTF-8 character: %q", i, line)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(line, " ") {
+						t.Errorf("continuation line %d does not start with a space", i)
+					}
+					line = line[1:]
+				}
+				unfolded.WriteString(line)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolding gives %q, want %q", unfolded.String(), tt.line)
+			}
+		})
+	}
+}
+
+func TestCalendarWrite(t *testing.T) {
+	paris := time.FixedZone("CET", 3600)
+	calendar := Calendar{
+		ProdID: "-//MealMate//Meal Plan//EN",
+		Name:   "Meals, this week",
+		Events: []Event{
+			{
+				UID:         "plan-1@mealmate",
+				Summary:     "Lunch at Sushi Zen",
+				Description: "Salmon nigiri\nYou liked it last week",
+				Location:    "Sushi Zen",
+				Geo:         &models.Coordinates{Latitude: 48.8566, Longitude: 2.3522},
+				Start:       time.Date(2025, 3, 11, 12, 30, 0, 0, paris),
+				End:         time.Date(2025, 3, 11, 13, 30, 0, 0, paris),
+			},
+			{
+				UID:      "plan-2@mealmate",
+				Summary:  "Dinner",
+				Start:    time.Date(2025, 3, 11, 19, 30, 0, 0, paris),
+				End:      time.Date(2025, 3, 11, 21, 0, 0, 0, paris),
+				Floating: true,
+			},
+		},
+	}
+	var b bytes.Buffer
+	if err := calendar.Write(&b, time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Meals\\, this week\r\n",
+		"DTSTAMP:20250310T090000Z\r\n",
+		"DTSTART:20250311T113000Z\r\n",
+		"DESCRIPTION:Salmon nigiri\\nYou liked it last week\r\n",
+		"GEO:48.856600;2.352200\r\n",
+		"DTSTART:20250311T193000\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar has no line %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("calendar has %d events, want 2", n)
+	}
+	if strings.Contains(strings.SplitN(got, "plan-2", 2)[1], "LOCATION:") {
+		t.Error("an event without location has a LOCATION line")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Lifecycle owns the root context, cancelled on SIGINT/SIGTERM, and the ordered shutdown hooks
+type Lifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	hooks  []shutdownHook
+	// shutdownTimeout bounds the whole shutdown sequence after the HTTP server has drained
+	shutdownTimeout time.Duration
+}
+
+/**
+* @description: Create a lifecycle whose root context is cancelled on SIGINT or SIGTERM
+* @param shutdownTimeout bound of the shutdown sequence
+* @return the lifecycle
+ */
+func NewLifecycle(shutdownTimeout time.Duration) *Lifecycle {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	return &Lifecycle{ctx: ctx, cancel: cancel, shutdownTimeout: shutdownTimeout}
+}
+
+// Context returns the root context, done once shutdown has been requested
+func (l *Lifecycle) Context() context.Context {
+	return l.ctx
+}
+
+// OnShutdown registers a hook, hooks run in reverse registration order like defers
+func (l *Lifecycle) OnShutdown(name string, fn func(ctx context.Context) error) {
+	l.hooks = append(l.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// SignalWaiter lets hertz start its graceful shutdown when the root context is cancelled
+func (l *Lifecycle) SignalWaiter(errCh chan error) error {
+	select {
+	case <-l.ctx.Done():
+		hlog.SystemLogger().Info("Shutdown requested, draining HTTP requests...")
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+/**
+* @description: Cancel the root context and run every shutdown hook within shutdownTimeout
+ */
+func (l *Lifecycle) Shutdown() {
+	l.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.shutdownTimeout)
+	defer cancel()
+
+	for i := len(l.hooks) - 1; i >= 0; i-- {
+		hook := l.hooks[i]
+		start := time.Now()
+		if err := hook.fn(ctx); err != nil {
+			hlog.SystemLogger().Errorf("Shutdown step %s failed: %v", hook.name, err)
+			continue
+		}
+		hlog.SystemLogger().Infof("Shutdown step %s completed in %s", hook.name, time.Since(start).Round(time.Millisecond))
+	}
+	hlog.SystemLogger().Info("Shutdown complete")
+}
@@ -1,50 +1,70 @@
 package main
 
 import (
-	"context"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"mealmate-agent/app"
 	"mealmate-agent/biz/router"
+	"mealmate-agent/config"
 	"mealmate-agent/db"
-	"mealmate-agent/pipeline"
+	"mealmate-agent/telemetry"
 
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/cloudwego/hertz/pkg/common/hlog"
-
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	err := godotenv.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		panic(err)
 	}
-	ctx := context.Background()
+	for _, line := range strings.Split(strings.TrimSpace(cfg.Dump()), "\n") {
+		hlog.SystemLogger().Info("Config ", line)
+	}
+	lifecycle := NewLifecycle(cfg.Server.ShutdownTimeout)
+	defer lifecycle.Shutdown()
+	ctx := lifecycle.Context()
 
-	// Initialize Milvus client and embedder
-	milvusClient := InitMilvusClient(ctx)
-	hlog.SystemLogger().Info("Milvus client initialized")
-	embedder, err := InitEmbedder(ctx)
-	hlog.SystemLogger().Info("Embedder initialized")
+	// Initialize tracing before any client so their calls are traced
+	shutdownTracing, err := telemetry.InitTracing(ctx, cfg.Telemetry.TracesExporter)
 	if err != nil {
 		panic(err)
 	}
-	// Initialize MilvusDatabase
-	milvusDB := db.NewMilvusDatabase(ctx, &milvusClient, embedder)
-	hlog.SystemLogger().Info("MilvusDatabase initialized")
-
-	// Start automatic sync task
-	milvusDB.StartAutoSync(ctx)
-	hlog.SystemLogger().Info("Automatic sync task started")
+	lifecycle.OnShutdown("flush traces", shutdownTracing)
 
-	// Init pipeline
-	runnable, err := pipeline.BuildMealMateAgent(ctx, embedder, &milvusClient)
+	// Build the embedder, vector store, event source and agent, the container owns their clients
+	application, err := app.New(ctx, cfg)
 	if err != nil {
 		panic(err)
 	}
+	hlog.SystemLogger().Info("Application initialized")
+	lifecycle.OnShutdown("close database clients", application.Close)
+
+	// Start automatic sync task, it stops when the root context is cancelled
+	application.Database.StartAutoSync(ctx)
+	hlog.SystemLogger().Info("Automatic sync task started")
+	lifecycle.OnShutdown("wait for running sync", application.Database.WaitForSync)
+
+	// Refresh taste profiles as the sync indexes events, a running refresh is awaited on shutdown
+	if application.Profiler != nil {
+		application.Profiler.Start(ctx)
+		lifecycle.OnShutdown("wait for taste profile refresh", application.Profiler.Wait)
+	}
+
+	// Prune the audit log in the background, it stops with the root context
+	db.StartAuditRetention(ctx, application.Audit, cfg.Audit.Retention)
 
-	// Start Hertz server
-	h := server.Default(server.WithHostPorts("127.0.0.1:8080"))
+	// Start Hertz server, Spin returns once in-flight requests are drained
+	h := server.Default(server.WithHostPorts(cfg.Server.Address), server.WithExitWaitTime(cfg.Server.DrainTimeout))
+	h.SetCustomSignalWaiter(lifecycle.SignalWaiter)
 
-	router.RegisterRoutes(h, milvusDB, &runnable)
+	router.RegisterRoutes(h, application)
 
 	h.Spin()
 }
@@ -0,0 +1,38 @@
+package models
+
+// AgentOptions tunes a single agent call
+type AgentOptions struct {
+	// MaxResults caps the number of recommendations, 0 means the default of 5
+	MaxResults int `json:"max_results" vd:"$>=0 && $<=5"`
+}
+
+// Location is a position sent with a request, its range is validated unlike the coordinates of stored events
+type Location struct {
+	Latitude  float64 `json:"latitude" vd:"$>=-90 && $<=90"`
+	Longitude float64 `json:"longitude" vd:"$>=-180 && $<=180"`
+}
+
+// Coordinates converts the location for the agent
+func (l *Location) Coordinates() *Coordinates {
+	return (*Coordinates)(l)
+}
+
+// Participant is another member of a group meal, the user sending the request is always a member
+type Participant struct {
+	UserID   string `json:"user_id" vd:"len($)>0 && len($)<=256"`
+	Username string `json:"username" vd:"len($)>0 && len($)<=128"`
+}
+
+// AgentRequest is the typed body of POST /v1/events/ai
+type AgentRequest struct {
+	UserID   string    `json:"user_id" vd:"len($)>0 && len($)<=256"`
+	Username string    `json:"username" vd:"len($)>0 && len($)<=128"`
+	Prompt   string    `json:"prompt" vd:"len($)>0 && len($)<=2000"`
+	Locale   string    `json:"locale" vd:"len($)<=35"`
+	Location *Location `json:"location"`
+	// Timezone is the IANA zone of the user, e.g. Europe/Paris
+	Timezone string       `json:"timezone" vd:"len($)<=64"`
+	Options  AgentOptions `json:"options"`
+	// Participants makes it a group request, recommendations must suit them and the user alike
+	Participants []Participant `json:"participants" vd:"len($)<=9"`
+}
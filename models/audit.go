@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// AgentTrace records what the agent saw and answered for one POST /v1/events/ai call
+type AgentTrace struct {
+	// RequestID is generated by the server, ClientRequestID is the X-Request-ID header sent by the client if any
+	RequestID       string       `json:"request_id"`
+	ClientRequestID string       `json:"client_request_id,omitempty"`
+	UserID          string       `json:"user_id"`
+	Request         AgentRequest `json:"request"`
+	// Documents are the history and catalog documents given to the prompt, in prompt order
+	Documents []TracedDocument `json:"documents"`
+	// Prompt is the conversation rendered by the chat template
+	Prompt []PromptMessage `json:"prompt"`
+	Model  string          `json:"model"`
+	Usage  TokenUsage      `json:"usage"`
+	// RawOutputs has one entry per chat model call, the hallucination guard may ask again
+	RawOutputs []string            `json:"raw_outputs"`
+	Output     *EventAgentResponse `json:"output,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Status     int                 `json:"status"`
+	LatencyMs  int64               `json:"latency_ms"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// TracedDocument is a retrieved document, Source is history or catalog
+type TracedDocument struct {
+	ID     string  `json:"id"`
+	Source string  `json:"source"`
+	Score  float64 `json:"score"`
+}
+
+type PromptMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TokenUsage sums the tokens of every chat model call of a trace
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
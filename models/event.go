@@ -1,10 +1,24 @@
 package models
 
+import "math"
+
+// earthRadiusKm is used by the haversine distance
+const earthRadiusKm = 6371.0
+
 type Coordinates struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
 
+// DistanceKm is the great circle distance to other
+func (c Coordinates) DistanceKm(other Coordinates) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(other.Latitude - c.Latitude)
+	dLon := toRad(other.Longitude - c.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(c.Latitude))*math.Cos(toRad(other.Latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
 type Event struct {
 	ID                    int         `json:"id"`
 	UserID                string      `json:"user_id"`
@@ -20,12 +34,37 @@ type SyncConfig struct {
 }
 
 type RestaurantRecommendation struct {
+	// ID is set on recommendations returned by the API, feedback is posted to it
+	ID                   string  `json:"id,omitempty"`
 	RestaurantName       string  `json:"restaurant_name"`
 	RecommendationRating float64 `json:"recommendation_rating"`
 	MainDishes           string  `json:"main_dishes"`
 	ShortReason          string  `json:"short_reason"`
+	// RestaurantID and Coordinates are attached when the name resolves to a known restaurant
+	RestaurantID string       `json:"restaurant_id,omitempty"`
+	Coordinates  *Coordinates `json:"coordinates,omitempty"`
+	// MemberFit explains, per member name, why the place suits them, on group requests only
+	MemberFit map[string]string `json:"member_fit,omitempty"`
+	// Unverified marks a restaurant that matches no known place, when unknown ones are flagged instead of dropped
+	Unverified bool `json:"unverified,omitempty"`
 }
 
 type EventAgentResponse struct {
+	// RequestID identifies the call in the audit log, it is also returned in the X-Request-ID header
+	RequestID       string                     `json:"request_id,omitempty"`
 	Recommendations []RestaurantRecommendation `json:"recommendations"`
+	// ColdStart is true when the user had no history and popular restaurants were recommended instead
+	ColdStart bool `json:"cold_start,omitempty"`
+	// OnboardingQuestions are asked on a cold start so the user can describe their taste
+	OnboardingQuestions []string `json:"onboarding_questions,omitempty"`
+}
+
+// PopularRestaurant aggregates the events of every user at one restaurant
+type PopularRestaurant struct {
+	RestaurantName string      `json:"restaurant_name"`
+	Visits         int         `json:"visits"`
+	Diners         int         `json:"diners"`
+	Coordinates    Coordinates `json:"coordinates"`
+	// DistanceKm is the distance from the requested location, zero when no location was given
+	DistanceKm float64 `json:"distance_km,omitempty"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// FeedbackVerdict is what a user tells about a recommendation
+type FeedbackVerdict string
+
+const (
+	FeedbackAccepted FeedbackVerdict = "accepted"
+	FeedbackRejected FeedbackVerdict = "rejected"
+	FeedbackVisited  FeedbackVerdict = "visited"
+)
+
+// RecommendationRecord is a recommendation returned by POST /v1/events/ai, kept so feedback can refer to it
+type RecommendationRecord struct {
+	ID             string                   `json:"id"`
+	UserID         string                   `json:"user_id"`
+	Prompt         string                   `json:"prompt"`
+	Recommendation RestaurantRecommendation `json:"recommendation"`
+	CreatedAt      time.Time                `json:"created_at"`
+}
+
+// FeedbackRequest is the typed body of POST /v1/recommendations/:id/feedback
+type FeedbackRequest struct {
+	// UserID must be the user the recommendation was made for
+	UserID  string          `json:"user_id" vd:"len($)>0 && len($)<=256"`
+	Verdict FeedbackVerdict `json:"verdict" vd:"$=='accepted' || $=='rejected' || $=='visited'"`
+	// Rating from 1 to 5, 0 when the user did not rate
+	Rating  int    `json:"rating" vd:"$>=0 && $<=5"`
+	Comment string `json:"comment" vd:"len($)<=1000"`
+}
+
+// Feedback is a stored FeedbackRequest with the restaurant it is about
+type Feedback struct {
+	RecommendationID string          `json:"recommendation_id"`
+	UserID           string          `json:"user_id"`
+	RestaurantName   string          `json:"restaurant_name"`
+	RestaurantID     string          `json:"restaurant_id,omitempty"`
+	Verdict          FeedbackVerdict `json:"verdict"`
+	Rating           int             `json:"rating,omitempty"`
+	Comment          string          `json:"comment,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+// MealPlanRequest is the typed body of POST /v1/plans
+type MealPlanRequest struct {
+	UserID   string `json:"user_id" vd:"len($)>0 && len($)<=256"`
+	Username string `json:"username" vd:"len($)>0 && len($)<=128"`
+	// Prompt holds the constraints of the plan, e.g. "vegetarian, under $$"
+	Prompt string `json:"prompt" vd:"len($)<=2000"`
+	// Start is the first day as YYYY-MM-DD, tomorrow when empty
+	Start string `json:"start" vd:"len($)<=10"`
+	// Days is the length of the plan, 0 means a week
+	Days int `json:"days" vd:"$>=0 && $<=14"`
+	// Slots are the meals to plan each day, lunch and dinner when empty
+	Slots    []string  `json:"slots"`
+	Timezone string    `json:"timezone" vd:"len($)<=64"`
+	Locale   string    `json:"locale" vd:"len($)<=35"`
+	Location *Location `json:"location"`
+}
+
+// MealPlanEntry is one planned meal
+type MealPlanEntry struct {
+	// Date is YYYY-MM-DD and Time HH:MM, on the clock of the user
+	Date           string       `json:"date"`
+	Slot           string       `json:"slot"`
+	Time           string       `json:"time"`
+	RestaurantName string       `json:"restaurant_name"`
+	RestaurantID   string       `json:"restaurant_id,omitempty"`
+	Coordinates    *Coordinates `json:"coordinates,omitempty"`
+	MainDishes     string       `json:"main_dishes"`
+	Reason         string       `json:"reason"`
+	// Unverified marks a restaurant that matches no known place, when the guard flags instead of dropping
+	Unverified bool `json:"unverified,omitempty"`
+}
+
+// MealPlan is the response of POST /v1/plans
+type MealPlan struct {
+	UserID string `json:"user_id"`
+	Start  string `json:"start"`
+	Days   int    `json:"days"`
+	// Timezone is empty when the times are floating, on whatever clock the user is
+	Timezone string          `json:"timezone,omitempty"`
+	Entries  []MealPlanEntry `json:"entries"`
+	// Unplanned are the meals left without a verified restaurant, even after the model was asked again
+	Unplanned []UnplannedMeal `json:"unplanned,omitempty"`
+}
+
+// UnplannedMeal is a meal of the plan without an entry
+type UnplannedMeal struct {
+	Date string `json:"date"`
+	Slot string `json:"slot"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// UserPreferences are what a user told us about their taste. Allergens, diets, disliked cuisines and restaurants,
+// the budget and the distance are hard constraints, recommendations breaking them are removed.
+type UserPreferences struct {
+	UserID string `json:"user_id"`
+	// DietaryRestrictions are diets like vegetarian, vegan or halal
+	DietaryRestrictions []string `json:"dietary_restrictions"`
+	Allergens           []string `json:"allergens"`
+	DislikedCuisines    []string `json:"disliked_cuisines"`
+	DislikedRestaurants []string `json:"disliked_restaurants"`
+	// MaxPriceLevel is the budget on the catalog scale from 1 (cheap) to 4 (expensive), 0 for no budget
+	MaxPriceLevel int `json:"max_price_level"`
+	// MaxDistanceKm is how far from the requested location a restaurant may be, 0 for no limit
+	MaxDistanceKm float64 `json:"max_distance_km"`
+	// Language is the locale of the answers when a request sets none
+	Language string `json:"language"`
+	// ShareWith are the users who may invite this user to their group requests, which reads these preferences and
+	// the history of the user
+	ShareWith []string  `json:"share_with"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PreferencesRequest is the typed body of PUT /v1/users/:user_id/preferences, it replaces every preference
+type PreferencesRequest struct {
+	DietaryRestrictions []string `json:"dietary_restrictions" vd:"len($)<=20"`
+	Allergens           []string `json:"allergens" vd:"len($)<=20"`
+	DislikedCuisines    []string `json:"disliked_cuisines" vd:"len($)<=50"`
+	DislikedRestaurants []string `json:"disliked_restaurants" vd:"len($)<=100"`
+	MaxPriceLevel       int      `json:"max_price_level" vd:"$>=0 && $<=4"`
+	MaxDistanceKm       float64  `json:"max_distance_km" vd:"$>=0 && $<=500"`
+	Language            string   `json:"language" vd:"len($)<=35"`
+	ShareWith           []string `json:"share_with" vd:"len($)<=100"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TasteProfile is a compact summary of the whole dining history of a user, written by the chat model and
+// updated as their new events sync
+type TasteProfile struct {
+	UserID            string   `json:"user_id"`
+	FavouriteCuisines []string `json:"favourite_cuisines"`
+	// PriceBand is how much the user usually spends, e.g. "mostly $$, $$$ on weekends"
+	PriceBand string `json:"price_band"`
+	// UsualAreas are the neighbourhoods or cities the user eats in
+	UsualAreas []string `json:"usual_areas"`
+	// TimePatterns describe when the user eats out, e.g. "late dinners on Fridays"
+	TimePatterns []string `json:"time_patterns"`
+	Summary      string   `json:"summary"`
+	// EventCount is how many events the profile summarises
+	EventCount int `json:"event_count"`
+	// LastEventAt and LastEventID are the newest event summarised, later events update the profile
+	LastEventAt time.Time `json:"last_event_at"`
+	LastEventID int       `json:"last_event_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
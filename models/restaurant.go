@@ -0,0 +1,23 @@
+package models
+
+// Restaurant is an entry of the restaurant catalog the agent recommends from
+type Restaurant struct {
+	// ID is derived from the name and coordinates when empty
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Cuisine string   `json:"cuisine"`
+	Dishes  []string `json:"dishes"`
+	// PriceLevel goes from 1 (cheap) to 4 (expensive), 0 when unknown
+	PriceLevel   int             `json:"price_level"`
+	Coordinates  Coordinates     `json:"coordinates"`
+	OpeningHours []OpeningPeriod `json:"opening_hours,omitempty"`
+}
+
+// OpeningPeriod is one opening slot of a day, a restaurant may have several per day
+type OpeningPeriod struct {
+	// Day is mon, tue, wed, thu, fri, sat or sun
+	Day string `json:"day"`
+	// Open and Close are HH:MM local times, Close is earlier than Open when the slot ends after midnight
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
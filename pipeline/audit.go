@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Names of the nodes whose callbacks feed the audit trace
+const (
+	userProfileGen    = "UserProfileGen"
+	catalogGen        = "CatalogGen"
+	eventChatTemplate = "EventChatTemplate"
+)
+
+/**
+* @description: Create an Invoke option recording the documents, prompt and model calls of the run
+* @return the option to pass to Invoke, and a function returning the partial trace once Invoke has returned
+ */
+func RecordTrace() (compose.Option, func() models.AgentTrace) {
+	var mu sync.Mutex
+	trace := models.AgentTrace{
+		Documents:  make([]models.TracedDocument, 0),
+		Prompt:     make([]models.PromptMessage, 0),
+		RawOutputs: make([]string, 0),
+	}
+	handler := callbacks.NewHandlerBuilder().
+		// The lambdas after the retrievers see the documents as given to the prompt, after reranking
+		OnStartFn(func(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+			source := map[string]string{userProfileGen: "history", catalogGen: "catalog"}[info.Name]
+			docs, ok := input.([]*schema.Document)
+			if source == "" || !ok {
+				return ctx
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, doc := range docs {
+				trace.Documents = append(trace.Documents, models.TracedDocument{ID: doc.ID, Source: source, Score: doc.Score()})
+			}
+			return ctx
+		}).
+		OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+			mu.Lock()
+			defer mu.Unlock()
+			if out := prompt.ConvCallbackOutput(output); info.Name == eventChatTemplate && out != nil {
+				for _, message := range out.Result {
+					trace.Prompt = append(trace.Prompt, models.PromptMessage{Role: string(message.Role), Content: message.Content})
+				}
+			}
+			if out := model.ConvCallbackOutput(output); info.Component == components.ComponentOfChatModel && out != nil {
+				if out.Config != nil && out.Config.Model != "" {
+					trace.Model = out.Config.Model
+				}
+				if out.Message != nil {
+					trace.RawOutputs = append(trace.RawOutputs, out.Message.Content)
+				}
+				usage := out.TokenUsage
+				if usage == nil && out.Message != nil && out.Message.ResponseMeta != nil && out.Message.ResponseMeta.Usage != nil {
+					usage = &model.TokenUsage{
+						PromptTokens:     out.Message.ResponseMeta.Usage.PromptTokens,
+						CompletionTokens: out.Message.ResponseMeta.Usage.CompletionTokens,
+						TotalTokens:      out.Message.ResponseMeta.Usage.TotalTokens,
+					}
+				}
+				if usage != nil {
+					trace.Usage.PromptTokens += usage.PromptTokens
+					trace.Usage.CompletionTokens += usage.CompletionTokens
+					trace.Usage.TotalTokens += usage.TotalTokens
+				}
+			}
+			return ctx
+		}).
+		Build()
+	return compose.WithCallbacks(handler), func() models.AgentTrace {
+		mu.Lock()
+		defer mu.Unlock()
+		return trace
+	}
+}
@@ -0,0 +1,39 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// callbackRunnable attaches a fixed set of callback handlers to every run of the wrapped graph
+type callbackRunnable[I, O any] struct {
+	runnable compose.Runnable[I, O]
+	handlers []callbacks.Handler
+}
+
+func withCallbacks[I, O any](r compose.Runnable[I, O], handlers ...callbacks.Handler) compose.Runnable[I, O] {
+	return &callbackRunnable[I, O]{runnable: r, handlers: handlers}
+}
+
+func (r *callbackRunnable[I, O]) opts(opts []compose.Option) []compose.Option {
+	return append([]compose.Option{compose.WithCallbacks(r.handlers...)}, opts...)
+}
+
+func (r *callbackRunnable[I, O]) Invoke(ctx context.Context, input I, opts ...compose.Option) (O, error) {
+	return r.runnable.Invoke(ctx, input, r.opts(opts)...)
+}
+
+func (r *callbackRunnable[I, O]) Stream(ctx context.Context, input I, opts ...compose.Option) (*schema.StreamReader[O], error) {
+	return r.runnable.Stream(ctx, input, r.opts(opts)...)
+}
+
+func (r *callbackRunnable[I, O]) Collect(ctx context.Context, input *schema.StreamReader[I], opts ...compose.Option) (O, error) {
+	return r.runnable.Collect(ctx, input, r.opts(opts)...)
+}
+
+func (r *callbackRunnable[I, O]) Transform(ctx context.Context, input *schema.StreamReader[I], opts ...compose.Option) (*schema.StreamReader[O], error) {
+	return r.runnable.Transform(ctx, input, r.opts(opts)...)
+}
@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mealmate-agent/catalog"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// WithCatalog grounds recommendations in the restaurant catalog, topK restaurants are offered per request.
+// Without it the model only knows the user's history.
+func WithCatalog(c *catalog.Catalog, topK int) AgentOption {
+	return func(o *agentOptions) {
+		o.catalog = c
+		o.catalogTopK = topK
+	}
+}
+
+// NearbyCatalogRetriever searches catalog restaurants matching the prompt, near the user when a location is given
+type NearbyCatalogRetriever struct {
+	catalog *catalog.Catalog
+	topK    int
+}
+
+// IsCallbacksEnabled lets the catalog store report callbacks itself, like DynamicFilterRetriever
+func (r *NearbyCatalogRetriever) IsCallbacksEnabled() bool {
+	return true
+}
+
+// Retrieve reads the same JSON input as DynamicFilterRetriever, which validates it
+func (r *NearbyCatalogRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	var input RetrieverInput
+	if err := json.Unmarshal([]byte(query), &input); err != nil {
+		return nil, inputError(ctx, query, fmt.Errorf("%w: input is not a valid json", ErrInvalidInput))
+	}
+	if input.UserPrompt == "" {
+		return nil, nil
+	}
+	return r.catalog.Search(ctx, input.UserPrompt, input.Location, r.topK)
+}
+
+// genCatalog component initialization function of node 'CatalogGen' in graph 'MealMateAgent'
+func genCatalog(ctx context.Context, input []*schema.Document) (output map[string]any, err error) {
+	var b strings.Builder
+	known := make([]knownRestaurant, 0, len(input))
+	for _, doc := range input {
+		restaurant, err := catalog.FromDocument(doc)
+		if err != nil {
+			hlog.SystemLogger().Warnf("Skipping catalog document: %v", err)
+			continue
+		}
+		known = append(known, catalogRestaurant(restaurant))
+		b.WriteString("- " + restaurant.Name)
+		details := make([]string, 0, 4)
+		if restaurant.Cuisine != "" {
+			details = append(details, restaurant.Cuisine)
+		}
+		if restaurant.PriceLevel > 0 {
+			details = append(details, "price "+strings.Repeat("$", restaurant.PriceLevel))
+		}
+		if len(restaurant.Dishes) > 0 {
+			details = append(details, "dishes: "+strings.Join(restaurant.Dishes, ", "))
+		}
+		if hours := catalog.FormatOpeningHours(restaurant.OpeningHours); hours != "" {
+			details = append(details, "open "+hours)
+		}
+		if len(details) > 0 {
+			b.WriteString(" (" + strings.Join(details, "; ") + ")")
+		}
+		b.WriteString("\n")
+	}
+	rememberRestaurants(ctx, known...)
+	return map[string]any{"catalog": b.String()}, nil
+}
@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// ColdStartGen is the node answering users without indexed history
+const ColdStartGen = "ColdStartGen"
+
+// coldStartCandidates is how many popular restaurants the cold start prompt offers the model
+const coldStartCandidates = 10
+
+// OnboardingQuestions are returned with cold start recommendations so the client can learn the user's taste
+var OnboardingQuestions = []string{
+	"Which cuisines do you enjoy the most?",
+	"Do you have any dietary restrictions or allergies?",
+	"What price range do you usually go for?",
+	"Do you prefer quick bites or sit-down meals?",
+}
+
+// PopularRestaurants ranks restaurants across every user, implemented by db.MilvusDatabase
+type PopularRestaurants interface {
+	PopularRestaurants(ctx context.Context, near *models.Coordinates, limit int) ([]models.PopularRestaurant, error)
+}
+
+// WithPopularRestaurants sets where the cold start path finds popular restaurants, without it the model gets no candidates
+func WithPopularRestaurants(popular PopularRestaurants) AgentOption {
+	return func(o *agentOptions) {
+		o.popular = popular
+	}
+}
+
+// newColdStartGen component initialization function of node 'ColdStartGen' in graph 'MealMateAgent'
+func newColdStartGen(popular PopularRestaurants) func(ctx context.Context, input []*schema.Document) (map[string]any, error) {
+	return func(ctx context.Context, input []*schema.Document) (output map[string]any, err error) {
+		var location *models.Coordinates
+		_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+			location, _ = state.History["location"].(*models.Coordinates)
+			return nil
+		})
+
+		var restaurants []models.PopularRestaurant
+		if popular != nil {
+			// A failed lookup still answers, the prompt then admits there is nothing to go on
+			if restaurants, err = popular.PopularRestaurants(ctx, location, coldStartCandidates); err != nil {
+				hlog.SystemLogger().Errorf("Failed to load popular restaurants: %v", err)
+			}
+		}
+		known := make([]knownRestaurant, 0, len(restaurants))
+		for _, r := range restaurants {
+			known = append(known, newKnownRestaurant(r.RestaurantName, r.Coordinates, ""))
+		}
+		rememberRestaurants(ctx, known...)
+		return map[string]any{
+			"history":    "",
+			"cold_start": true,
+			"popular":    formatPopular(restaurants),
+		}, nil
+	}
+}
+
+func formatPopular(restaurants []models.PopularRestaurant) string {
+	if len(restaurants) == 0 {
+		return "No popularity data is available yet."
+	}
+	var b strings.Builder
+	for _, r := range restaurants {
+		fmt.Fprintf(&b, "- %s: %d visits by %d diners", r.RestaurantName, r.Visits, r.Diners)
+		if r.DistanceKm > 0 {
+			fmt.Fprintf(&b, ", %.1f km away", r.DistanceKm)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+/**
+* @description: Create an Invoke option reporting whether the run took the cold start path
+* @return the option to pass to Invoke, and a function returning true once the cold start node has run
+ */
+func DetectColdStart() (compose.Option, func() bool) {
+	var coldStart atomic.Bool
+	handler := callbacks.NewHandlerBuilder().
+		OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+			coldStart.Store(true)
+			return ctx
+		}).
+		Build()
+	return compose.WithCallbacks(handler).DesignateNode(ColdStartGen), coldStart.Load
+}
@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// feedbackPromptLimit is how many restaurants with feedback are listed in the prompt, the most recent first
+const feedbackPromptLimit = 10
+
+// FeedbackSource returns what a user said about earlier recommendations, implemented by db.RecommendationStore
+type FeedbackSource interface {
+	FeedbackByUser(ctx context.Context, userID string) ([]models.Feedback, error)
+}
+
+// WithFeedback boosts history of restaurants the user accepted and down-weights the ones they rejected
+func WithFeedback(source FeedbackSource) AgentOption {
+	return func(o *agentOptions) {
+		o.feedback = source
+	}
+}
+
+// restaurantFeedback is the latest feedback of a user on one restaurant
+type restaurantFeedback struct {
+	models.Feedback
+	// weight is positive for a restaurant the user liked and negative for one they did not
+	weight int
+}
+
+// latestFeedback keeps the most recent feedback per restaurant, most recent first
+func latestFeedback(feedback []models.Feedback) []restaurantFeedback {
+	seen := make(map[string]bool, len(feedback))
+	latest := make([]restaurantFeedback, 0, len(feedback))
+	for i := len(feedback) - 1; i >= 0; i-- {
+		f := feedback[i]
+		key := normalizeRestaurantName(f.RestaurantName)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		latest = append(latest, restaurantFeedback{Feedback: f, weight: feedbackWeight(f)})
+	}
+	return latest
+}
+
+// feedbackWeight is +1 per positive signal and -1 per negative one, a rating of 3 is neutral
+func feedbackWeight(f models.Feedback) int {
+	weight := 0
+	switch f.Verdict {
+	case models.FeedbackAccepted, models.FeedbackVisited:
+		weight++
+	case models.FeedbackRejected:
+		weight--
+	}
+	switch {
+	case f.Rating >= 4:
+		weight++
+	case f.Rating > 0 && f.Rating <= 2:
+		weight--
+	}
+	return weight
+}
+
+// feedbackTopK is how many documents to fetch so that topK are left after the disliked restaurants are ranked last,
+// one more per disliked restaurant up to twice topK
+func feedbackTopK(topK int, feedback []restaurantFeedback) int {
+	fetch := topK
+	for _, f := range feedback {
+		if f.weight < 0 && fetch < 2*topK {
+			fetch++
+		}
+	}
+	return fetch
+}
+
+// rerankByFeedback moves history of liked restaurants first and of disliked ones last, keeping the store order otherwise
+func rerankByFeedback(docs []*schema.Document, feedback []restaurantFeedback) []*schema.Document {
+	if len(feedback) == 0 {
+		return docs
+	}
+	weights := make([]int, len(docs))
+	for i, doc := range docs {
+		weights[i] = docWeight(doc, feedback)
+	}
+	order := make([]int, len(docs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return weights[order[a]] > weights[order[b]]
+	})
+	reranked := make([]*schema.Document, 0, len(docs))
+	for _, i := range order {
+		reranked = append(reranked, docs[i])
+	}
+	return reranked
+}
+
+// docWeight is the weight of the restaurant of a history document, 0 without feedback on it
+func docWeight(doc *schema.Document, feedback []restaurantFeedback) int {
+	name, _ := doc.MetaData["restaurant_name"].(string)
+	name = normalizeRestaurantName(name)
+	content := normalizeRestaurantName(doc.Content)
+	for _, f := range feedback {
+		key := normalizeRestaurantName(f.RestaurantName)
+		// Documents indexed before the name was stored start with it
+		if key == name || (name == "" && (content == key || strings.HasPrefix(content, key+" "))) {
+			return f.weight
+		}
+	}
+	return 0
+}
+
+// formatFeedback lists the latest feedback for the prompt
+func formatFeedback(feedback []restaurantFeedback) string {
+	var b strings.Builder
+	for _, f := range feedback[:min(len(feedback), feedbackPromptLimit)] {
+		fmt.Fprintf(&b, "- %s: %s", f.RestaurantName, f.Verdict)
+		if f.Rating > 0 {
+			fmt.Fprintf(&b, ", rated %d/5", f.Rating)
+		}
+		if f.Comment != "" {
+			fmt.Fprintf(&b, ", said %q", f.Comment)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
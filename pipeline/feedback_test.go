@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestRerankByFeedback(t *testing.T) {
+	docs := []*schema.Document{
+		{ID: "1", MetaData: map[string]any{"restaurant_name": "Sushi Zen"}},
+		{ID: "2", MetaData: map[string]any{"restaurant_name": "La Cantina"}},
+		{ID: "3", MetaData: map[string]any{"restaurant_name": "Golden Dragon"}},
+		{ID: "4", Content: "Pizza Roma: margherita on the terrace"},
+		{ID: "5", MetaData: map[string]any{"restaurant_name": "The Golden Dragon"}},
+	}
+	feedback := func(name string, verdict models.FeedbackVerdict, rating int) models.Feedback {
+		return models.Feedback{RestaurantName: name, Verdict: verdict, Rating: rating}
+	}
+	tests := []struct {
+		name     string
+		feedback []models.Feedback
+		want     []string
+	}{
+		{"no feedback", nil, []string{"1", "2", "3", "4", "5"}},
+		{"liked first", []models.Feedback{feedback("Golden Dragon", models.FeedbackAccepted, 0)}, []string{"3", "5", "1", "2", "4"}},
+		{"rejected last", []models.Feedback{feedback("sushi zen", models.FeedbackRejected, 0)}, []string{"2", "3", "4", "5", "1"}},
+		{"by content prefix", []models.Feedback{feedback("Pizza Roma", models.FeedbackVisited, 5)}, []string{"4", "1", "2", "3", "5"}},
+		{"low rating cancels visit", []models.Feedback{feedback("La Cantina", models.FeedbackVisited, 1)}, []string{"1", "2", "3", "4", "5"}},
+		{"low rating alone", []models.Feedback{feedback("La Cantina", "", 2)}, []string{"1", "3", "4", "5", "2"}},
+		{"neutral rating", []models.Feedback{feedback("La Cantina", "", 3)}, []string{"1", "2", "3", "4", "5"}},
+		{"latest feedback wins", []models.Feedback{
+			feedback("Sushi Zen", models.FeedbackAccepted, 5),
+			feedback("Sushi Zen", models.FeedbackRejected, 0),
+		}, []string{"2", "3", "4", "5", "1"}},
+		{"liked and rejected", []models.Feedback{
+			feedback("Sushi Zen", models.FeedbackRejected, 0),
+			feedback("Pizza Roma", models.FeedbackAccepted, 4),
+		}, []string{"4", "2", "3", "5", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reranked := rerankByFeedback(docs, latestFeedback(tt.feedback))
+			got := make([]string, 0, len(reranked))
+			for _, doc := range reranked {
+				got = append(got, doc.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rerankByFeedback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedbackTopK(t *testing.T) {
+	rejected := func(n int) []restaurantFeedback {
+		feedback := make([]restaurantFeedback, n)
+		for i := range feedback {
+			feedback[i].weight = -1
+		}
+		return feedback
+	}
+	tests := []struct {
+		name     string
+		topK     int
+		feedback []restaurantFeedback
+		want     int
+	}{
+		{"no feedback", 3, nil, 3},
+		{"liked only", 3, []restaurantFeedback{{weight: 1}, {weight: 2}}, 3},
+		{"one per rejection", 3, rejected(2), 5},
+		{"capped at twice topK", 3, rejected(50), 6},
+		{"zero topK", 0, rejected(5), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feedbackTopK(tt.topK, tt.feedback); got != tt.want {
+				t.Errorf("feedbackTopK() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"mealmate-agent/models"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	// maxGroupSize bounds the members of a group request, the user included, each costs a few lookups
+	maxGroupSize = 10
+	// minMemberTopK is how many history events every member gets at least, however large the group
+	minMemberTopK = 2
+	// meetingPointIterations bounds the search of the meeting point, it ends within a few percent of the radius of the group
+	meetingPointIterations = 1000
+)
+
+// groupMember is one diner of a group request with what is known about them
+type groupMember struct {
+	models.Participant
+	// preferences, profile and feedback are nil when the member has none or the source is not configured
+	preferences *models.UserPreferences
+	profile     *models.TasteProfile
+	feedback    []restaurantFeedback
+	// usualArea is the restaurant the member eats closest to all their others, nil without located history
+	usualArea *models.Coordinates
+	// events is how many history events of the member the prompt shows
+	events int
+}
+
+// diningGroup is kept in the state by 'GroupResolver' for the retriever, the filter and the prompt
+type diningGroup struct {
+	// members starts with the user sending the request
+	members []*groupMember
+	// constraints are enforced by 'PreferenceFilter', nil when no member set preferences
+	constraints *models.UserPreferences
+	// softDislikes are cuisines too few members dislike to rule them out
+	softDislikes []string
+	// favourites are the cuisines of the taste profiles, the most shared first
+	favourites []string
+	// meetingPoint is the location of the request, else the fairest point between the usual areas
+	meetingPoint     *models.Coordinates
+	requestedMeeting bool
+}
+
+// resolveGroup component initialization function of node 'GroupResolver' in graph 'MealMateAgent'.
+// It passes single user requests through, and sets the meeting point of a group as the location of the request so
+// every retriever searches around it.
+func (r *DynamicFilterRetriever) resolveGroup(ctx context.Context, query string) (output string, err error) {
+	var input RetrieverInput
+	if json.Unmarshal([]byte(query), &input) != nil || len(input.Participants) == 0 {
+		// An invalid input is reported by 'UserProfileRetriever'
+		return query, nil
+	}
+	// Nothing is looked up for a request the retriever would reject
+	if err := input.validate(); err != nil {
+		return "", err
+	}
+	members, err := groupMembers(input)
+	if err != nil {
+		return "", err
+	}
+	if err := r.authorizeGroup(ctx, members); err != nil {
+		return "", err
+	}
+
+	group := &diningGroup{members: members}
+	areas := make([]models.Coordinates, 0, len(members))
+	for _, m := range members {
+		r.loadMember(ctx, input.UserPrompt, m)
+		if m.usualArea != nil {
+			areas = append(areas, *m.usualArea)
+		}
+	}
+	group.constraints, group.softDislikes = mergePreferences(members)
+	group.favourites = groupFavourites(members, group.constraints)
+	if input.Location != nil {
+		group.meetingPoint, group.requestedMeeting = input.Location, true
+	} else if group.meetingPoint = fairMeetingPoint(areas); group.meetingPoint != nil {
+		input.Location = group.meetingPoint
+	}
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		state.History["group"] = group
+		return nil
+	})
+
+	content, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// groupMembers validates the participants, names must be unique since the model explains the fit per name
+func groupMembers(input RetrieverInput) ([]*groupMember, error) {
+	if len(input.Participants)+1 > maxGroupSize {
+		return nil, fmt.Errorf("%w: a group has at most %d members", ErrInvalidInput, maxGroupSize)
+	}
+	members := make([]*groupMember, 0, len(input.Participants)+1)
+	ids := make(map[string]bool, cap(members))
+	names := make(map[string]bool, cap(members))
+	for _, p := range append([]models.Participant{{UserID: input.UserID, Username: input.Username}}, input.Participants...) {
+		p.Username = strings.TrimSpace(p.Username)
+		if p.UserID == "" || p.Username == "" {
+			return nil, fmt.Errorf("%w: every participant needs a user id and a username", ErrInvalidInput)
+		}
+		if ids[p.UserID] {
+			return nil, fmt.Errorf("%w: user %s takes part twice", ErrInvalidInput, p.UserID)
+		}
+		if names[strings.ToLower(p.Username)] {
+			return nil, fmt.Errorf("%w: two participants are named %q", ErrInvalidInput, p.Username)
+		}
+		ids[p.UserID], names[strings.ToLower(p.Username)] = true, true
+		members = append(members, &groupMember{Participant: p})
+	}
+	return members, nil
+}
+
+// authorizeGroup checks that every participant listed the user asking in the users they share with, the history and
+// preferences of a participant are only read once they did. Their preferences are kept for the prompt.
+func (r *DynamicFilterRetriever) authorizeGroup(ctx context.Context, members []*groupMember) error {
+	if r.preferences == nil {
+		return fmt.Errorf("%w: group requests need the preference store", ErrNotShared)
+	}
+	requester := members[0].UserID
+	for _, m := range members[1:] {
+		preferences, err := r.preferences.GetPreferences(ctx, m.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to load preferences of user %s: %w", m.UserID, err)
+		}
+		if preferences == nil || !slices.Contains(preferences.ShareWith, requester) {
+			return fmt.Errorf("%w: user %s does not share with user %s", ErrNotShared, m.UserID, requester)
+		}
+		m.preferences = preferences
+	}
+	return nil
+}
+
+// loadMember reads what every source knows about a member, a failed lookup leaves it out like for a single user
+func (r *DynamicFilterRetriever) loadMember(ctx context.Context, prompt string, m *groupMember) {
+	var err error
+	// Participants were loaded by authorizeGroup
+	if r.preferences != nil && m.preferences == nil {
+		if m.preferences, err = r.preferences.GetPreferences(ctx, m.UserID); err != nil {
+			hlog.SystemLogger().Errorf("Failed to load preferences of user %s: %v", m.UserID, err)
+		}
+	}
+	if r.profiles != nil {
+		if m.profile, err = r.profiles.GetProfile(ctx, m.UserID); err != nil {
+			hlog.SystemLogger().Errorf("Failed to load the taste profile of user %s: %v", m.UserID, err)
+		}
+	}
+	m.feedback = r.loadFeedback(ctx, m.UserID)
+
+	docs, err := r.baseRetriever.Retrieve(ctx, prompt, retriever.WithTopK(habitLookback), vectorstore.WithFilter(vectorstore.Filter{UserID: m.UserID}))
+	if err != nil {
+		hlog.SystemLogger().Errorf("Failed to load the usual area of user %s: %v", m.UserID, err)
+		return
+	}
+	places := make([]models.Coordinates, 0, len(docs))
+	for _, doc := range docs {
+		if c := historyRestaurant(doc).Coordinates; c != (models.Coordinates{}) {
+			places = append(places, c)
+		}
+	}
+	m.usualArea = medoid(places)
+}
+
+// medoid is the place with the smallest total distance to the others, a place the user really goes to unlike an average
+// of two cities
+func medoid(places []models.Coordinates) *models.Coordinates {
+	best, bestTotal := -1, math.Inf(1)
+	for i, p := range places {
+		total := 0.0
+		for _, q := range places {
+			total += p.DistanceKm(q)
+		}
+		if total < bestTotal {
+			best, bestTotal = i, total
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	return &places[best]
+}
+
+// fairMeetingPoint is the centre of the smallest circle around the usual areas, so the member coming from the farthest
+// travels as little as possible and every member counts once however often they eat out. Nil without any area.
+func fairMeetingPoint(areas []models.Coordinates) *models.Coordinates {
+	if len(areas) == 0 {
+		return nil
+	}
+	// Degrees of longitude shrink away from the equator, distances are compared on a flat projection around the group
+	var x, y float64
+	for _, a := range areas {
+		y += a.Latitude
+	}
+	y /= float64(len(areas))
+	scale := math.Cos(y * math.Pi / 180)
+	for _, a := range areas {
+		x += a.Longitude * scale
+	}
+	x /= float64(len(areas))
+	// Badoiu-Clarkson: step towards the farthest area with a shrinking step, it converges to the centre of the circle
+	for i := 1; i <= meetingPointIterations; i++ {
+		far, farthest := areas[0], -1.0
+		for _, a := range areas {
+			dx, dy := a.Longitude*scale-x, a.Latitude-y
+			if d := dx*dx + dy*dy; d > farthest {
+				far, farthest = a, d
+			}
+		}
+		x += (far.Longitude*scale - x) / float64(i+1)
+		y += (far.Latitude - y) / float64(i+1)
+	}
+	return &models.Coordinates{Latitude: y, Longitude: x / scale}
+}
+
+// mergePreferences applies the union of the hard constraints of the members: diets, allergies and disliked restaurants
+// of anyone, the smallest budget and distance. A cuisine is ruled out when at least half of the group dislikes it,
+// the others are only told to the model.
+func mergePreferences(members []*groupMember) (*models.UserPreferences, []string) {
+	merged := &models.UserPreferences{UserID: members[0].UserID}
+	if members[0].preferences != nil {
+		merged.Language = members[0].preferences.Language
+	}
+	found := false
+	dislikedBy := make(map[string][]string)
+	var cuisines []string
+	for _, m := range members {
+		p := m.preferences
+		if p == nil {
+			continue
+		}
+		found = true
+		merged.DietaryRestrictions = appendUnique(merged.DietaryRestrictions, p.DietaryRestrictions...)
+		merged.Allergens = appendUnique(merged.Allergens, p.Allergens...)
+		merged.DislikedRestaurants = appendUnique(merged.DislikedRestaurants, p.DislikedRestaurants...)
+		if p.MaxPriceLevel > 0 && (merged.MaxPriceLevel == 0 || p.MaxPriceLevel < merged.MaxPriceLevel) {
+			merged.MaxPriceLevel = p.MaxPriceLevel
+		}
+		if p.MaxDistanceKm > 0 && (merged.MaxDistanceKm == 0 || p.MaxDistanceKm < merged.MaxDistanceKm) {
+			merged.MaxDistanceKm = p.MaxDistanceKm
+		}
+		for _, cuisine := range p.DislikedCuisines {
+			key := strings.ToLower(strings.TrimSpace(cuisine))
+			if _, ok := dislikedBy[key]; !ok {
+				cuisines = append(cuisines, cuisine)
+			}
+			dislikedBy[key] = append(dislikedBy[key], m.Username)
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	var soft []string
+	for _, cuisine := range cuisines {
+		by := dislikedBy[strings.ToLower(strings.TrimSpace(cuisine))]
+		if 2*len(by) >= len(members) {
+			merged.DislikedCuisines = append(merged.DislikedCuisines, cuisine)
+		} else {
+			soft = append(soft, cuisine+" ("+strings.Join(by, ", ")+")")
+		}
+	}
+	return merged, soft
+}
+
+// appendUnique appends the values list does not have yet, ignoring case
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !containsFold(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func containsFold(list []string, v string) bool {
+	return slices.ContainsFunc(list, func(l string) bool { return strings.EqualFold(strings.TrimSpace(l), strings.TrimSpace(v)) })
+}
+
+// groupFavourites weighs the favourite cuisines of the taste profiles: the most members first, then the best ranked.
+// Cuisines the group rules out are left out.
+func groupFavourites(members []*groupMember, constraints *models.UserPreferences) []string {
+	type favourite struct {
+		name    string
+		members int
+		rank    int
+	}
+	byKey := make(map[string]*favourite)
+	var favourites []*favourite
+	for _, m := range members {
+		if m.profile == nil {
+			continue
+		}
+		for rank, cuisine := range m.profile.FavouriteCuisines {
+			key := strings.ToLower(strings.TrimSpace(cuisine))
+			if key == "" || (constraints != nil && containsFold(constraints.DislikedCuisines, cuisine)) {
+				continue
+			}
+			f, ok := byKey[key]
+			if !ok {
+				f = &favourite{name: cuisine}
+				byKey[key] = f
+				favourites = append(favourites, f)
+			}
+			f.members++
+			f.rank += rank
+		}
+	}
+	sort.SliceStable(favourites, func(i, j int) bool {
+		if favourites[i].members != favourites[j].members {
+			return favourites[i].members > favourites[j].members
+		}
+		return favourites[i].rank < favourites[j].rank
+	})
+	lines := make([]string, 0, len(favourites))
+	for _, f := range favourites {
+		lines = append(lines, fmt.Sprintf("%s (%d of %d members)", f.name, f.members, len(members)))
+	}
+	return lines
+}
+
+// retrieveGroup retrieves the history of every member, labelled with their name, each reranked by their own feedback.
+// The topK of the agent is shared between the members.
+func (r *DynamicFilterRetriever) retrieveGroup(ctx context.Context, input RetrieverInput, opts []retriever.Option) ([]*schema.Document, error) {
+	var group *diningGroup
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		group, _ = state.History["group"].(*diningGroup)
+		if group != nil && group.constraints != nil {
+			state.History["preferences"] = group.constraints
+			if locale, _ := state.History["locale"].(string); locale == "" {
+				state.History["locale"] = group.constraints.Language
+			}
+		}
+		return nil
+	})
+	if group == nil {
+		return nil, fmt.Errorf("group of user %s was not resolved", input.UserID)
+	}
+
+	memberTopK := 0
+	if r.topK > 0 {
+		memberTopK = max(minMemberTopK, (r.topK+len(group.members)-1)/len(group.members))
+	}
+	var docs []*schema.Document
+	for _, m := range group.members {
+		memberOpts := append(append([]retriever.Option{}, opts...), vectorstore.WithFilter(vectorstore.Filter{UserID: m.UserID}))
+		if memberTopK > 0 {
+			memberOpts = append(memberOpts, retriever.WithTopK(feedbackTopK(memberTopK, m.feedback)))
+		}
+		found, err := r.baseRetriever.Retrieve(ctx, input.UserPrompt, memberOpts...)
+		if err != nil {
+			return nil, err
+		}
+		found = rerankByFeedback(found, m.feedback)
+		if memberTopK > 0 && len(found) > memberTopK {
+			found = found[:memberTopK]
+		}
+		for _, doc := range found {
+			// The store may return its own documents, they are copied before being labelled
+			labelled := *doc
+			labelled.MetaData = maps.Clone(doc.MetaData)
+			if labelled.MetaData == nil {
+				labelled.MetaData = make(map[string]any)
+			}
+			labelled.MetaData["member"] = m.Username
+			docs = append(docs, &labelled)
+		}
+		_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+			m.events = len(found)
+			return nil
+		})
+	}
+	return docs, nil
+}
+
+// formatGroup describes every member, then what the whole group needs
+func formatGroup(g *diningGroup) string {
+	var b strings.Builder
+	for i, m := range g.members {
+		b.WriteString("- " + m.Username)
+		if i == 0 {
+			b.WriteString(", who is asking")
+		}
+		if m.usualArea != nil && g.meetingPoint != nil {
+			fmt.Fprintf(&b, ", usually eats %.1f km from the meeting point", m.usualArea.DistanceKm(*g.meetingPoint))
+		}
+		if m.events == 0 {
+			b.WriteString(", no dining history with us yet")
+		}
+		b.WriteString("\n")
+		section := func(title, lines string) {
+			if lines != "" {
+				b.WriteString("  " + title + ":\n" + indent(lines, "  "))
+			}
+		}
+		if m.preferences != nil {
+			section("Preferences", formatPreferences(m.preferences))
+		}
+		if m.profile != nil {
+			section("Taste profile", formatTasteProfile(m.profile))
+		}
+		if len(m.feedback) > 0 {
+			section("Feedback on earlier recommendations", formatFeedback(m.feedback))
+		}
+	}
+	if g.constraints != nil {
+		if constraints := formatPreferences(g.constraints); constraints != "" {
+			b.WriteString("\n\tHard constraints of the whole group, never recommend anything against them:\n" + constraints)
+		}
+	}
+	if len(g.softDislikes) > 0 {
+		b.WriteString("\n\tCuisines only some members dislike, prefer other places:\n- " + strings.Join(g.softDislikes, "\n- ") + "\n")
+	}
+	if len(g.favourites) > 0 {
+		b.WriteString("\n\tCuisines the members enjoy, the most shared first:\n- " + strings.Join(g.favourites, "\n- ") + "\n")
+	}
+	if p := g.meetingPoint; p != nil {
+		if g.requestedMeeting {
+			fmt.Fprintf(&b, "\n\tThe group meets at latitude %f, longitude %f, prefer places nearby.\n", p.Latitude, p.Longitude)
+		} else {
+			fmt.Fprintf(&b, "\n\tThe fairest meeting point between where the members usually eat is latitude %f, longitude %f, prefer places nearby.\n", p.Latitude, p.Longitude)
+		}
+	}
+	return b.String()
+}
+
+// companions are the names of the members besides the user asking
+func companions(g *diningGroup) string {
+	names := make([]string, 0, len(g.members)-1)
+	for _, m := range g.members[1:] {
+		names = append(names, m.Username)
+	}
+	return strings.Join(names, ", ")
+}
+
+// indent prefixes every line of text
+func indent(text, prefix string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
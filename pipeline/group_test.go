@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"mealmate-agent/embedder"
+	"mealmate-agent/models"
+	"mealmate-agent/vectorstore"
+)
+
+func TestFairMeetingPoint(t *testing.T) {
+	paris := models.Coordinates{Latitude: 48.8566, Longitude: 2.3522}
+	tests := []struct {
+		name  string
+		areas []models.Coordinates
+		// radiusKm is the distance from the fairest point to the farthest area
+		radiusKm float64
+	}{
+		{"one", []models.Coordinates{paris}, 0},
+		{"two", []models.Coordinates{
+			{Latitude: 48.8566, Longitude: 2.30},
+			{Latitude: 48.8566, Longitude: 2.40},
+		}, 3.66},
+		{"clustered with one far away", []models.Coordinates{
+			{Latitude: 48.80, Longitude: 2.35},
+			{Latitude: 48.801, Longitude: 2.35},
+			{Latitude: 48.802, Longitude: 2.35},
+			{Latitude: 48.90, Longitude: 2.35},
+		}, 5.56},
+		{"triangle", []models.Coordinates{
+			{Latitude: 48.88, Longitude: 2.30},
+			{Latitude: 48.88, Longitude: 2.40},
+			{Latitude: 48.83, Longitude: 2.35},
+		}, 3.99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fairMeetingPoint(tt.areas)
+			if got == nil {
+				t.Fatal("fairMeetingPoint() = nil")
+			}
+			farthest := 0.0
+			for _, a := range tt.areas {
+				farthest = max(farthest, got.DistanceKm(a))
+			}
+			// The search ends within a few percent of the radius, and nothing is fairer than the radius
+			if farthest > tt.radiusKm*1.05+0.01 || farthest < tt.radiusKm*0.95 {
+				t.Errorf("farthest member is %.2f km away, want about %.2f km", farthest, tt.radiusKm)
+			}
+		})
+	}
+
+	if got := fairMeetingPoint(nil); got != nil {
+		t.Errorf("fairMeetingPoint(nil) = %v, want nil", got)
+	}
+}
+
+// preferenceMap serves preferences by user ID and counts the lookups
+type preferenceMap struct {
+	preferences map[string]*models.UserPreferences
+	lookups     int
+}
+
+func (p *preferenceMap) GetPreferences(ctx context.Context, userID string) (*models.UserPreferences, error) {
+	p.lookups++
+	return p.preferences[userID], nil
+}
+
+func TestResolveGroupAuthorization(t *testing.T) {
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := vectorstore.NewMemoryStore(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(prompt string, participants ...string) string {
+		input := RetrieverInput{UserPrompt: prompt, UserID: "u1", Username: "Alex"}
+		for _, id := range participants {
+			input.Participants = append(input.Participants, models.Participant{UserID: id, Username: "Member " + id})
+		}
+		content, _ := json.Marshal(input)
+		return string(content)
+	}
+	preferences := map[string]*models.UserPreferences{
+		"u2": {UserID: "u2", ShareWith: []string{"u1"}},
+		"u3": {UserID: "u3", ShareWith: []string{"u9"}},
+	}
+	tests := []struct {
+		name        string
+		query       string
+		noStore     bool
+		want        error
+		wantLookups int
+	}{
+		{"shared", request("sushi", "u2"), false, nil, 2},
+		// Invalid requests are rejected before any participant is looked up
+		{"no prompt", request("", "u2"), false, ErrInvalidInput, 0},
+		{"shares with someone else", request("sushi", "u2", "u3"), false, ErrNotShared, 2},
+		{"no preferences", request("sushi", "u4"), false, ErrNotShared, 1},
+		{"no preference store", request("sushi", "u2"), true, ErrNotShared, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &preferenceMap{preferences: preferences}
+			r := &DynamicFilterRetriever{baseRetriever: store, preferences: source}
+			if tt.noStore {
+				r.preferences = nil
+			}
+			if _, err := r.resolveGroup(context.Background(), tt.query); !errors.Is(err, tt.want) {
+				t.Errorf("resolveGroup() error = %v, want %v", err, tt.want)
+			}
+			if source.lookups != tt.wantLookups {
+				t.Errorf("resolveGroup() looked up %d preferences, want %d", source.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"mealmate-agent/catalog"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// GuardMode selects what the 'HallucinationGuard' node does with restaurants it cannot resolve
+type GuardMode string
+
+const (
+	// GuardDrop removes unknown restaurants from the recommendations
+	GuardDrop GuardMode = "drop"
+	// GuardFlag keeps unknown restaurants and marks them unverified
+	GuardFlag GuardMode = "flag"
+	// GuardOff passes the model output through untouched
+	GuardOff GuardMode = "off"
+)
+
+const (
+	// guardRetries is how many times the model is asked again when too few recommendations are valid
+	guardRetries = 1
+	// guardCatalogLookups is how many catalog restaurants are compared with a name missing from the candidates
+	guardCatalogLookups = 3
+	// nameSimilarity is the minimal similarity of two normalized names to be the same restaurant
+	nameSimilarity = 0.85
+)
+
+// WithGuard configures the 'HallucinationGuard' node, by default unknown restaurants are dropped and the
+// model is asked again when fewer than one recommendation is left
+func WithGuard(mode GuardMode, minValid int) AgentOption {
+	return func(o *agentOptions) {
+		o.guardMode = mode
+		o.guardMinValid = minValid
+	}
+}
+
+// knownRestaurant is a real place surfaced during the run, the guard accepts only these
+type knownRestaurant struct {
+	ID          string
+	Name        string
+	Coordinates models.Coordinates
+	// content is matched by prefix when the name is unknown, history documents indexed before the name was stored
+	content string
+	// fromCatalog wins ties, the catalog holds the canonical ID and coordinates
+	fromCatalog bool
+	// cuisine and priceLevel are only known for catalog restaurants, they are checked against the preferences
+	cuisine    string
+	priceLevel int
+}
+
+// rememberRestaurants adds restaurants to the graph state for the guard
+func rememberRestaurants(ctx context.Context, restaurants ...knownRestaurant) {
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		known, _ := state.History["known_restaurants"].([]knownRestaurant)
+		state.History["known_restaurants"] = append(known, restaurants...)
+		return nil
+	})
+}
+
+// historyRestaurant converts an event document, its ID is the one the catalog derives for the same place
+func historyRestaurant(doc *schema.Document) knownRestaurant {
+	name, _ := doc.MetaData["restaurant_name"].(string)
+	latitude, _ := doc.MetaData["latitude"].(float64)
+	longitude, _ := doc.MetaData["longitude"].(float64)
+	return newKnownRestaurant(name, models.Coordinates{Latitude: latitude, Longitude: longitude}, doc.Content)
+}
+
+// catalogRestaurant converts a catalog entry
+func catalogRestaurant(restaurant models.Restaurant) knownRestaurant {
+	return knownRestaurant{
+		ID:          restaurant.ID,
+		Name:        restaurant.Name,
+		Coordinates: restaurant.Coordinates,
+		fromCatalog: true,
+		cuisine:     restaurant.Cuisine,
+		priceLevel:  restaurant.PriceLevel,
+	}
+}
+
+func newKnownRestaurant(name string, coordinates models.Coordinates, content string) knownRestaurant {
+	known := knownRestaurant{Name: name, Coordinates: coordinates, content: content}
+	if name != "" {
+		known.ID = catalog.RestaurantID(models.Restaurant{Name: name, Coordinates: coordinates})
+	}
+	return known
+}
+
+type hallucinationGuard struct {
+	chatModel model.BaseChatModel
+	catalog   *catalog.Catalog
+	mode      GuardMode
+	minValid  int
+}
+
+// newHallucinationGuard component initialization function of node 'HallucinationGuard' in graph 'MealMateAgent'
+func newHallucinationGuard(cm model.BaseChatModel, c *catalog.Catalog, mode GuardMode, minValid int) *hallucinationGuard {
+	if mode == "" {
+		mode = GuardDrop
+	}
+	if minValid <= 0 {
+		minValid = 1
+	}
+	return &hallucinationGuard{chatModel: cm, catalog: c, mode: mode, minValid: minValid}
+}
+
+// Check resolves every recommended restaurant and asks the model again when too few are real
+func (g *hallucinationGuard) Check(ctx context.Context, input *schema.Message) (output *schema.Message, err error) {
+	if g.mode == GuardOff {
+		return input, nil
+	}
+	var known []knownRestaurant
+	var messages []*schema.Message
+	var location *models.Coordinates
+	var maxResults int
+	var plan *PlanInput
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		known, _ = state.History["known_restaurants"].([]knownRestaurant)
+		messages, _ = state.History["messages"].([]*schema.Message)
+		location, _ = state.History["location"].(*models.Coordinates)
+		maxResults, _ = state.History["max_results"].(int)
+		plan, _ = state.History["plan"].(*PlanInput)
+		return nil
+	})
+	if len(known) == 0 && g.catalog == nil {
+		hlog.SystemLogger().Warn("No known restaurants to verify the recommendations against")
+		return input, nil
+	}
+	if maxResults <= 0 {
+		maxResults = defaultMaxResults
+	}
+	minValid := guardMinValid(g.minValid, maxResults, plan)
+
+	var items []map[string]any
+	if err := json.Unmarshal([]byte(input.Content), &items); err != nil {
+		// Reported by the caller, the guard only judges well formed answers
+		return input, nil
+	}
+	unknown := g.resolve(ctx, items, known, location)
+
+	answer := input
+	for retry := 0; retry < guardRetries && len(items)-len(unknown) < minValid && messages != nil; retry++ {
+		hlog.SystemLogger().Warnf("Asking the model again, unknown restaurants: %s", strings.Join(unknown, ", "))
+		retryMessages := append(append([]*schema.Message{}, messages...), answer, schema.UserMessage(fmt.Sprintf(
+			"These restaurants do not exist in our catalog or in my history: %s. Recommend only restaurants listed above, "+
+				"and answer again with ONLY the JSON array.", strings.Join(unknown, ", "))))
+		// Report the call as a chat model, not as part of the guard lambda, so it is metered and traced
+		modelCtx := callbacks.ReuseHandlers(ctx, &callbacks.RunInfo{Name: "HallucinationGuardRetry", Component: components.ComponentOfChatModel})
+		retried, err := g.chatModel.Generate(modelCtx, retryMessages)
+		if err != nil {
+			return nil, err
+		}
+		var retriedItems []map[string]any
+		if err := json.Unmarshal([]byte(retried.Content), &retriedItems); err != nil {
+			hlog.SystemLogger().Warnf("Ignoring invalid answer to the guard prompt: %v", err)
+			continue
+		}
+		retriedUnknown := g.resolve(ctx, retriedItems, known, location)
+		if len(retriedItems)-len(retriedUnknown) > len(items)-len(unknown) {
+			answer, items, unknown = retried, retriedItems, retriedUnknown
+		}
+	}
+
+	kept := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		if unverified, _ := item["unverified"].(bool); unverified && g.mode == GuardDrop {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	// The meals left without a restaurant are reported as unplanned by ParseMealPlan
+	if plan != nil && len(kept) < len(items) {
+		hlog.SystemLogger().Warnf("Leaving %d meals unplanned, unknown restaurants: %s", len(items)-len(kept), strings.Join(unknown, ", "))
+	}
+	// encoding/json sorts the keys, so the same answer always renders the same
+	content, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	output = &schema.Message{Role: answer.Role, Content: string(content), ResponseMeta: answer.ResponseMeta}
+	return output, nil
+}
+
+// guardMinValid is how many verified items an answer needs before the model is asked again, a plan needs one per meal
+func guardMinValid(minValid, maxResults int, plan *PlanInput) int {
+	if plan != nil {
+		if meals, err := plan.meals(); err == nil {
+			return len(meals)
+		}
+	}
+	return min(minValid, maxResults)
+}
+
+// resolve attaches the canonical name, ID and coordinates to each item naming a restaurant, recommendations or
+// meal plan entries alike, unknown ones are marked unverified and their names returned
+func (g *hallucinationGuard) resolve(ctx context.Context, items []map[string]any, known []knownRestaurant, location *models.Coordinates) []string {
+	unknown := make([]string, 0)
+	for _, item := range items {
+		name, _ := item["restaurant_name"].(string)
+		match, ok := matchRestaurant(name, known)
+		if !ok {
+			// Found restaurants are remembered so the preference filter knows their cuisine and price
+			if match, ok = g.lookupCatalog(ctx, name, location); ok {
+				rememberRestaurants(ctx, match)
+			}
+		}
+		if !ok {
+			item["unverified"] = true
+			unknown = append(unknown, name)
+			continue
+		}
+		if match.Name != "" {
+			name = match.Name
+		}
+		if match.ID == "" {
+			match.ID = catalog.RestaurantID(models.Restaurant{Name: name, Coordinates: match.Coordinates})
+		}
+		item["restaurant_name"], item["restaurant_id"], item["coordinates"] = name, match.ID, match.Coordinates
+		delete(item, "unverified")
+	}
+	return unknown
+}
+
+// lookupCatalog searches the catalog for a name that was not among the candidates of the run
+func (g *hallucinationGuard) lookupCatalog(ctx context.Context, name string, location *models.Coordinates) (knownRestaurant, bool) {
+	if g.catalog == nil || strings.TrimSpace(name) == "" {
+		return knownRestaurant{}, false
+	}
+	docs, err := g.catalog.Search(ctx, name, location, guardCatalogLookups)
+	if err != nil {
+		hlog.SystemLogger().Warnf("Catalog lookup of %q failed: %v", name, err)
+		return knownRestaurant{}, false
+	}
+	candidates := make([]knownRestaurant, 0, len(docs))
+	for _, doc := range docs {
+		restaurant, err := catalog.FromDocument(doc)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, catalogRestaurant(restaurant))
+	}
+	return matchRestaurant(name, candidates)
+}
+
+// matchRestaurant returns the known restaurant whose name is most similar to name, if similar enough
+func matchRestaurant(name string, known []knownRestaurant) (knownRestaurant, bool) {
+	target := normalizeRestaurantName(name)
+	if target == "" {
+		return knownRestaurant{}, false
+	}
+	var best knownRestaurant
+	bestScore := 0.0
+	for _, k := range known {
+		score := 0.0
+		if k.Name != "" {
+			score = nameScore(target, normalizeRestaurantName(k.Name))
+		} else if content := normalizeRestaurantName(k.content); content == target || strings.HasPrefix(content, target+" ") {
+			score = 1
+			k.Name = strings.TrimSpace(name)
+		}
+		// Nodes run in parallel, so ties are broken by source rather than by order
+		if score > bestScore || (score == bestScore && score > 0 && k.fromCatalog && !best.fromCatalog) {
+			best, bestScore = k, score
+		}
+	}
+	return best, bestScore >= nameSimilarity
+}
+
+// nameScore is 1 for names equal or containing each other with at least two words, else their edit similarity
+func nameScore(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	shorter, longer := a, b
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	if len(strings.Fields(shorter)) >= 2 && strings.Contains(" "+longer+" ", " "+shorter+" ") {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	return 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+}
+
+// normalizeRestaurantName lowercases, strips accents and punctuation and drops a leading "the"
+func normalizeRestaurantName(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+	words := strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,67 @@
+package pipeline
+
+import "testing"
+
+func TestMatchRestaurant(t *testing.T) {
+	known := []knownRestaurant{
+		{Name: "Sushi Zen"},
+		{Name: "Café de Flore"},
+		{Name: "The Golden Dragon"},
+		{Name: "Luigi's Trattoria Roma"},
+		{ID: "cat-1", Name: "Sushi Zen", fromCatalog: true},
+		// History indexed before the name was stored
+		{content: "La Cantina: tacos al pastor with friends"},
+	}
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantID  string
+		matched bool
+	}{
+		{"exact", "Sushi Zen", "Sushi Zen", "cat-1", true},
+		{"case and punctuation", "sushi-zen!", "Sushi Zen", "cat-1", true},
+		{"accents", "Cafe de Flore", "Café de Flore", "", true},
+		{"leading the", "Golden Dragon", "The Golden Dragon", "", true},
+		{"typo", "Golden Dragn", "The Golden Dragon", "", true},
+		{"contained name", "Trattoria Roma", "Luigi's Trattoria Roma", "", true},
+		{"content prefix", "La Cantina", "La Cantina", "", true},
+		{"one contained word", "Roma", "", "", false},
+		{"unknown", "Burger Palace", "", "", false},
+		{"empty", "  ", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchRestaurant(tt.in, known)
+			if ok != tt.matched {
+				t.Fatalf("matchRestaurant(%q) matched = %v, want %v", tt.in, ok, tt.matched)
+			}
+			if ok && (got.Name != tt.want || got.ID != tt.wantID) {
+				t.Errorf("matchRestaurant(%q) = %q %q, want %q %q", tt.in, got.Name, got.ID, tt.want, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestGuardMinValid(t *testing.T) {
+	tests := []struct {
+		name       string
+		minValid   int
+		maxResults int
+		plan       *PlanInput
+		want       int
+	}{
+		{"recommendations", 1, 3, nil, 1},
+		{"capped by max results", 5, 3, nil, 3},
+		// A plan needs a restaurant for every meal, 2 days of lunch and dinner
+		{"plan", 1, 3, &PlanInput{Start: "2025-03-10", Days: 2, Slots: []string{"lunch", "dinner"}}, 4},
+		{"invalid plan", 1, 3, &PlanInput{Start: "2025-03-10"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guardMinValid(tt.minValid, tt.maxResults, tt.plan); got != tt.want {
+				t.Errorf("guardMinValid() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
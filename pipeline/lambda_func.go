@@ -9,14 +9,18 @@ import (
 
 // genUserProfile component initialization function of node 'UserProfileGen' in graph 'MealMateAgent'
 func genUserProfile(ctx context.Context, input []*schema.Document) (output map[string]any, err error) {
-	if len(input) == 0 {
-		return nil, nil
-	}
 	output = make(map[string]any)
 	var history string
+	known := make([]knownRestaurant, 0, len(input))
 	for _, doc := range input {
+		// Group requests label the history of every member
+		if member, _ := doc.MetaData["member"].(string); member != "" {
+			history += member + ": "
+		}
 		history += doc.Content + "\n"
+		known = append(known, historyRestaurant(doc))
 	}
+	rememberRestaurants(ctx, known...)
 	output["history"] = history
 	return output, nil
 }
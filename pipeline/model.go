@@ -2,17 +2,16 @@ package pipeline
 
 import (
 	"context"
-	"os"
 
 	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino/components/model"
 )
 
 // newChatModel component initialization function of node 'ChatModel' in graph 'MealMateAgent'
-func newChatModel(ctx context.Context) (cm model.ChatModel, err error) {
+func newChatModel(ctx context.Context, apiKey, modelName string) (cm model.ChatModel, err error) {
 	config := &ark.ChatModelConfig{
-		APIKey: os.Getenv("ARK_API_KEY"),
-		Model: os.Getenv("ARK_CHAT_MODEL"),
+		APIKey: apiKey,
+		Model: modelName,
 	}
 	cm, err = ark.NewChatModel(ctx, config)
 	if err != nil {
@@ -20,3 +19,8 @@ func newChatModel(ctx context.Context) (cm model.ChatModel, err error) {
 	}
 	return cm, nil
 }
+
+// NewChatModel creates the Ark chat model used by the 'ChatModel' node, pass it to BuildMealMateAgent with WithChatModel
+func NewChatModel(ctx context.Context, apiKey, modelName string) (model.ChatModel, error) {
+	return newChatModel(ctx, apiKey, modelName)
+}
@@ -2,29 +2,101 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"time"
 
-	"github.com/cloudwego/eino-ext/components/embedding/ark"
+	"mealmate-agent/catalog"
+	"mealmate-agent/telemetry"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
-	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/cloudwego/eino/schema"
 )
 
 type EventAgentState struct {
 	History map[string]any
 }
 
+type agentOptions struct {
+	chatModel     model.BaseChatModel
+	topK          int
+	systemPrompt  string
+	popular       PopularRestaurants
+	catalog       *catalog.Catalog
+	catalogTopK   int
+	guardMode     GuardMode
+	guardMinValid int
+	feedback      FeedbackSource
+	preferences   PreferenceSource
+	profiles      ProfileSource
+	clock         func() time.Time
+}
+
+// AgentOption customizes the components of the MealMateAgent
+type AgentOption func(*agentOptions)
+
+// WithChatModel sets the chat model of the 'ChatModel' node, it is required
+func WithChatModel(cm model.BaseChatModel) AgentOption {
+	return func(o *agentOptions) {
+		o.chatModel = cm
+	}
+}
+
+// WithTopK sets how many history documents the retriever returns, the store default is used when unset
+func WithTopK(k int) AgentOption {
+	return func(o *agentOptions) {
+		o.topK = k
+	}
+}
+
+// WithSystemPrompt replaces DefaultSystemPrompt, the template may use {history}, {max_results} and {max_reason_length}
+func WithSystemPrompt(prompt string) AgentOption {
+	return func(o *agentOptions) {
+		o.systemPrompt = prompt
+	}
+}
+
 /**
 * @description: Build the MealMateAgent
 * @param ctx context.Context
 * @return r compose.Runnable[string, string], err error
 * @return nil if success, error if failed
  */
-func BuildMealMateAgent(ctx context.Context, embedder *ark.Embedder, milvusClient *client.Client) (r compose.Runnable[string, string], err error) {
+func BuildMealMateAgent(ctx context.Context, store vectorstore.Store, opts ...AgentOption) (r compose.Runnable[string, string], err error) {
+	options := newAgentOptions(opts)
+	eventChatTemplateKeyOfChatTemplate, err := newChatTemplate(ctx, options.systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	return buildAgentGraph(ctx, store, options, "MealMateAgent", eventChatTemplateKeyOfChatTemplate)
+}
+
+func newAgentOptions(opts []AgentOption) *agentOptions {
+	options := &agentOptions{clock: time.Now}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// buildAgentGraph wires the retrievers, the model and the guard around the template of the 'EventChatTemplate' node,
+// the agents differ only by what they ask the model
+func buildAgentGraph(ctx context.Context, store vectorstore.Store, options *agentOptions, graphName string, template prompt.ChatTemplate) (r compose.Runnable[string, string], err error) {
 	const (
 		UserProfileRetriever = "UserProfileRetriever"
-		UserProfileGen       = "UserProfileGen"
-		EventChatTemplate    = "EventChatTemplate"
+		UserProfileGen       = userProfileGen
+		EventChatTemplate    = eventChatTemplate
 		ChatModel            = "ChatModel"
 		outputFormatHandler  = "outputFormatHandler"
+		CatalogRetriever     = "CatalogRetriever"
+		CatalogGen           = catalogGen
+		HallucinationGuard   = "HallucinationGuard"
+		PreferenceFilter     = "PreferenceFilter"
+		ScheduleRetriever    = "ScheduleRetriever"
+		ScheduleGen          = "ScheduleGen"
+		GroupResolver        = "GroupResolver"
 	)
 	g := compose.NewGraph[string, string](compose.WithGenLocalState(func(ctx context.Context) (state EventAgentState) {
 		return EventAgentState{
@@ -33,29 +105,65 @@ func BuildMealMateAgent(ctx context.Context, embedder *ark.Embedder, milvusClien
 	}))
 
 	// Create Event Retriever Node
-	dynamicRetriever := NewDynamicFilterRetriever(embedder, milvusClient)
-	_ = g.AddRetrieverNode(UserProfileRetriever, dynamicRetriever)
-	_ = g.AddLambdaNode(UserProfileGen, compose.InvokableLambda(genUserProfile))
-	eventChatTemplateKeyOfChatTemplate, err := newChatTemplate(ctx)
+	dynamicRetriever, err := NewDynamicFilterRetriever(store)
 	if err != nil {
 		return nil, err
 	}
-	_ = g.AddChatTemplateNode(EventChatTemplate, eventChatTemplateKeyOfChatTemplate, compose.WithStatePreHandler(chatTemplatePreHandler))
-	chatModelKeyOfChatModel, err := newChatModel(ctx)
-	if err != nil {
-		return nil, err
+	dynamicRetriever.topK = options.topK
+	dynamicRetriever.feedback = options.feedback
+	dynamicRetriever.preferences = options.preferences
+	dynamicRetriever.profiles = options.profiles
+	// Group requests are resolved first, so every retriever searches around the meeting point
+	_ = g.AddLambdaNode(GroupResolver, compose.InvokableLambda(dynamicRetriever.resolveGroup), compose.WithNodeName(GroupResolver))
+	_ = g.AddRetrieverNode(UserProfileRetriever, dynamicRetriever, compose.WithNodeName(UserProfileRetriever))
+	_ = g.AddLambdaNode(UserProfileGen, compose.InvokableLambda(genUserProfile), compose.WithNodeName(UserProfileGen))
+	_ = g.AddLambdaNode(ColdStartGen, compose.InvokableLambda(newColdStartGen(options.popular)), compose.WithNodeName(ColdStartGen))
+	_ = g.AddChatTemplateNode(EventChatTemplate, template, compose.WithStatePreHandler(chatTemplatePreHandler), compose.WithNodeName(EventChatTemplate))
+	chatModelKeyOfChatModel := options.chatModel
+	if chatModelKeyOfChatModel == nil {
+		return nil, fmt.Errorf("chat model not configured")
 	}
-	_ = g.AddChatModelNode(ChatModel, chatModelKeyOfChatModel)
-	_ = g.AddLambdaNode(outputFormatHandler, compose.InvokableLambda(chatOutputHandler))
-	_ = g.AddEdge(compose.START, UserProfileRetriever)
+	_ = g.AddChatModelNode(ChatModel, chatModelKeyOfChatModel, compose.WithStatePreHandler(chatModelPreHandler), compose.WithNodeName(ChatModel))
+	guard := newHallucinationGuard(chatModelKeyOfChatModel, options.catalog, options.guardMode, options.guardMinValid)
+	_ = g.AddLambdaNode(HallucinationGuard, compose.InvokableLambda(guard.Check), compose.WithNodeName(HallucinationGuard))
+	_ = g.AddLambdaNode(PreferenceFilter, compose.InvokableLambda(filterPreferences), compose.WithNodeName(PreferenceFilter))
+	_ = g.AddLambdaNode(outputFormatHandler, compose.InvokableLambda(chatOutputHandler), compose.WithNodeName(outputFormatHandler))
+	_ = g.AddEdge(compose.START, GroupResolver)
+	_ = g.AddEdge(GroupResolver, UserProfileRetriever)
 	_ = g.AddEdge(outputFormatHandler, compose.END)
-	_ = g.AddEdge(UserProfileRetriever, UserProfileGen)
+	// Users without any indexed event take the cold start path
+	_ = g.AddBranch(UserProfileRetriever, compose.NewGraphBranch(func(ctx context.Context, docs []*schema.Document) (string, error) {
+		if len(docs) == 0 {
+			return ColdStartGen, nil
+		}
+		return UserProfileGen, nil
+	}, map[string]bool{UserProfileGen: true, ColdStartGen: true}))
 	_ = g.AddEdge(UserProfileGen, EventChatTemplate)
+	_ = g.AddEdge(ColdStartGen, EventChatTemplate)
+	// Catalog candidates are retrieved in parallel with the history, the template waits for both
+	if options.catalog != nil {
+		catalogRetriever := &NearbyCatalogRetriever{catalog: options.catalog, topK: options.catalogTopK}
+		_ = g.AddRetrieverNode(CatalogRetriever, catalogRetriever, compose.WithNodeName(CatalogRetriever))
+		_ = g.AddLambdaNode(CatalogGen, compose.InvokableLambda(genCatalog), compose.WithNodeName(CatalogGen))
+		_ = g.AddEdge(GroupResolver, CatalogRetriever)
+		_ = g.AddEdge(CatalogRetriever, CatalogGen)
+		_ = g.AddEdge(CatalogGen, EventChatTemplate)
+	}
+	// Upcoming meals and meal times are read in parallel too
+	scheduleRetriever := &MealScheduleRetriever{store: store, clock: options.clock}
+	_ = g.AddRetrieverNode(ScheduleRetriever, scheduleRetriever, compose.WithNodeName(ScheduleRetriever))
+	_ = g.AddLambdaNode(ScheduleGen, compose.InvokableLambda(genSchedule), compose.WithNodeName(ScheduleGen))
+	_ = g.AddEdge(GroupResolver, ScheduleRetriever)
+	_ = g.AddEdge(ScheduleRetriever, ScheduleGen)
+	_ = g.AddEdge(ScheduleGen, EventChatTemplate)
 	_ = g.AddEdge(EventChatTemplate, ChatModel)
-	_ = g.AddEdge(ChatModel, outputFormatHandler)
-	r, err = g.Compile(ctx, compose.WithGraphName("MealMateAgent"), compose.WithNodeTriggerMode(compose.AnyPredecessor))
+	_ = g.AddEdge(ChatModel, HallucinationGuard)
+	_ = g.AddEdge(HallucinationGuard, PreferenceFilter)
+	_ = g.AddEdge(PreferenceFilter, outputFormatHandler)
+	r, err = g.Compile(ctx, compose.WithGraphName(graphName), compose.WithNodeTriggerMode(compose.AllPredecessor))
 	if err != nil {
 		return nil, err
 	}
-	return r, err
+	// Instrument every node with metrics and traces through eino callbacks
+	return withCallbacks(r, telemetry.CallbackHandlers()...), nil
 }
@@ -0,0 +1,240 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"mealmate-agent/models"
+	"mealmate-agent/schedule"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// PlanInput is the part of RetrieverInput only the meal plan agent reads
+type PlanInput struct {
+	// Start is the first day as YYYY-MM-DD
+	Start string   `json:"start"`
+	Days  int      `json:"days"`
+	Slots []string `json:"slots"`
+}
+
+// plannedMeal is one meal the plan must fill
+type plannedMeal struct {
+	date string
+	day  time.Weekday
+	slot schedule.Slot
+}
+
+// meals lists the meals of the plan by day, then in the order of the day
+func (p PlanInput) meals() ([]plannedMeal, error) {
+	start, err := time.Parse(time.DateOnly, p.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q, use YYYY-MM-DD", p.Start)
+	}
+	if p.Days <= 0 {
+		return nil, fmt.Errorf("a plan needs at least one day")
+	}
+	slots := make([]schedule.Slot, 0, len(p.Slots))
+	for _, s := range p.Slots {
+		slot, err := schedule.ParseSlot(s)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(slots, slot) {
+			slots = append(slots, slot)
+		}
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("a plan needs at least one meal slot")
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Order() < slots[j].Order() })
+
+	meals := make([]plannedMeal, 0, p.Days*len(slots))
+	for i := 0; i < p.Days; i++ {
+		day := start.AddDate(0, 0, i)
+		for _, slot := range slots {
+			meals = append(meals, plannedMeal{date: day.Format(time.DateOnly), day: day.Weekday(), slot: slot})
+		}
+	}
+	return meals, nil
+}
+
+// planScheduleRule keeps the meals the user already scheduled in the plan
+const planScheduleRule = "A meal the user already scheduled stays in the plan at the restaurant they scheduled, plan the others around it at their usual meal times."
+
+// DefaultMealPlanPrompt is the system message template of the MealPlanAgent, {meals}, {count}, {history} and {max_reason_length} are substituted on Format
+const DefaultMealPlanPrompt = `You are a cute waitress, and the advice you give needs to reflect your cuteness. Your task is to plan the meals of the user for the days below, based on their historical event records.
+
+	Meals to plan:
+	{meals}
+	{history}
+
+	PLANNING RULES:
+	1. Plan exactly one restaurant for every meal listed above, and no other meal
+	2. Vary the restaurants, do not plan the same restaurant twice in a row unless the user asks for it
+	3. Respect every constraint in the user's request
+
+	` + outputRules + `3. The JSON array must contain exactly {count} meal objects, one per meal listed above
+	4. Each object MUST have exactly these fields with the correct types:
+	- "date" (string): Day of the meal as YYYY-MM-DD, as listed above
+	- "slot" (string): Meal of the day, as listed above
+	- "time" (string): Time of the meal as HH:MM on the user's clock
+	- "restaurant_name" (string): Name of the restaurant
+	- "main_dishes" (string): Dishes to order
+	- "reason" (string): Brief explanation (max {max_reason_length} characters)
+
+	Example of correct output format:
+	[
+	{
+		"date": "2025-03-11",
+		"slot": "lunch",
+		"time": "12:30",
+		"restaurant_name": "Example Restaurant",
+		"main_dishes": "Signature Dish Name",
+		"reason": "A light lunch you enjoyed last week."
+	}
+	]
+
+	` + outputReminder
+
+// planColdStartHistory replaces the event history for users without any, the popular restaurants follow it
+const planColdStartHistory = `This user is new and has no dining history with us yet, never claim or imply that a meal is based on their previous visits.
+
+	Restaurants popular with other diners:
+	`
+
+type MealPlanTemplateImpl struct {
+	systemPrompt string
+}
+
+// newMealPlanTemplate component initialization function of node 'EventChatTemplate' in graph 'MealPlanAgent'
+func newMealPlanTemplate() prompt.ChatTemplate {
+	return &MealPlanTemplateImpl{systemPrompt: DefaultMealPlanPrompt}
+}
+
+func (impl *MealPlanTemplateImpl) Format(ctx context.Context, vs map[string]any, opts ...prompt.Option) ([]*schema.Message, error) {
+	plan, _ := vs["plan"].(*PlanInput)
+	if plan == nil {
+		return nil, fmt.Errorf("%w: the meal plan agent needs a plan", ErrInvalidInput)
+	}
+	meals, err := plan.meals()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	username, _ := vs["username"].(string)
+	userPrompt, _ := vs["user_prompt"].(string)
+	history, _ := vs["history"].(string)
+	history = "\n\tEvent history:\n\t" + history
+	if coldStart, _ := vs["cold_start"].(bool); coldStart {
+		popular, _ := vs["popular"].(string)
+		history = "\n\t" + planColdStartHistory + popular
+	}
+	systemPrompt := strings.NewReplacer(
+		"{meals}", formatPlannedMeals(meals),
+		"{count}", fmt.Sprint(len(meals)),
+		"{history}", history,
+		"{max_reason_length}", fmt.Sprint(MaxReasonLength),
+	).Replace(impl.systemPrompt)
+	systemPrompt = appendContext(systemPrompt, vs, planScheduleRule)
+
+	query := "I'm " + username + ", " + userPrompt
+	messages := []*schema.Message{
+		{
+			Role:    schema.System,
+			Content: systemPrompt,
+		},
+		{
+			Role:    schema.User,
+			Content: query,
+		},
+	}
+	return messages, nil
+}
+
+// formatPlannedMeals writes one line per day, e.g. "- 2025-03-11 (Tuesday): lunch, dinner"
+func formatPlannedMeals(meals []plannedMeal) string {
+	var b strings.Builder
+	for i := 0; i < len(meals); {
+		day := meals[i]
+		names := make([]string, 0)
+		for ; i < len(meals) && meals[i].date == day.date; i++ {
+			names = append(names, string(meals[i].slot))
+		}
+		fmt.Fprintf(&b, "- %s (%s): %s\n\t", day.date, day.day, strings.Join(names, ", "))
+	}
+	return strings.TrimSuffix(b.String(), "\n\t")
+}
+
+/**
+* @description: Build the MealPlanAgent, it runs the nodes of the MealMateAgent with a template asking for a plan
+* @param ctx context.Context
+* @param store the event store
+* @return r compose.Runnable[string, string], err error
+* @return nil if success, error if failed
+ */
+func BuildMealPlanAgent(ctx context.Context, store vectorstore.Store, opts ...AgentOption) (r compose.Runnable[string, string], err error) {
+	options := newAgentOptions(opts)
+	return buildAgentGraph(ctx, store, options, "MealPlanAgent", newMealPlanTemplate())
+}
+
+/**
+* @description: Parse the output of the MealPlanAgent
+* @param output the JSON array answered by the agent
+* @param plan the plan that was asked for
+* @return the entries for the meals of the plan by day and slot, the meals without one, error if output is not a JSON array
+ */
+func ParseMealPlan(output string, plan PlanInput) ([]models.MealPlanEntry, []models.UnplannedMeal, error) {
+	meals, err := plan.meals()
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []models.MealPlanEntry
+	if err := json.Unmarshal([]byte(output), &items); err != nil {
+		return nil, nil, err
+	}
+	wanted := make(map[plannedMeal]bool, len(meals))
+	for _, meal := range meals {
+		wanted[plannedMeal{date: meal.date, slot: meal.slot}] = true
+	}
+
+	entries := make([]models.MealPlanEntry, 0, len(items))
+	for _, item := range items {
+		slot, err := schedule.ParseSlot(item.Slot)
+		if err != nil || strings.TrimSpace(item.RestaurantName) == "" {
+			continue
+		}
+		// Models add meals nobody asked for and repeat some, the first answer for a meal wins
+		key := plannedMeal{date: strings.TrimSpace(item.Date), slot: slot}
+		if !wanted[key] {
+			continue
+		}
+		delete(wanted, key)
+		item.Date, item.Slot = key.date, string(slot)
+		if _, err := time.Parse("15:04", item.Time); err != nil {
+			item.Time = slot.TypicalTime()
+		}
+		entries = append(entries, item)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return schedule.Slot(entries[i].Slot).Order() < schedule.Slot(entries[j].Slot).Order()
+	})
+	// Meals the guard dropped or the model skipped are reported rather than silently missing
+	unplanned := make([]models.UnplannedMeal, 0)
+	for _, meal := range meals {
+		if wanted[plannedMeal{date: meal.date, slot: meal.slot}] {
+			unplanned = append(unplanned, models.UnplannedMeal{Date: meal.date, Slot: string(meal.slot)})
+		}
+	}
+	return entries, unplanned, nil
+}
@@ -0,0 +1,116 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+
+	"mealmate-agent/models"
+)
+
+func TestParseMealPlan(t *testing.T) {
+	plan := PlanInput{Start: "2025-03-10", Days: 2, Slots: []string{"dinner", "Lunch", "lunch"}}
+	tests := []struct {
+		name          string
+		output        string
+		want          []models.MealPlanEntry
+		wantUnplanned []models.UnplannedMeal
+	}{
+		{
+			name: "sorted by day and meal",
+			output: `[
+				{"date": "2025-03-11", "slot": "dinner", "time": "19:00", "restaurant_name": "Sakura"},
+				{"date": "2025-03-10", "slot": "dinner", "time": "20:00", "restaurant_name": "Le Bistrot"},
+				{"date": "2025-03-10", "slot": "lunch", "time": "12:00", "restaurant_name": "La Cantina"}
+			]`,
+			want: []models.MealPlanEntry{
+				{Date: "2025-03-10", Slot: "lunch", Time: "12:00", RestaurantName: "La Cantina"},
+				{Date: "2025-03-10", Slot: "dinner", Time: "20:00", RestaurantName: "Le Bistrot"},
+				{Date: "2025-03-11", Slot: "dinner", Time: "19:00", RestaurantName: "Sakura"},
+			},
+			wantUnplanned: []models.UnplannedMeal{
+				{Date: "2025-03-11", Slot: "lunch"},
+			},
+		},
+		{
+			name: "meals not asked for and repeated",
+			output: `[
+				{"date": "2025-03-10", "slot": "lunch", "time": "12:00", "restaurant_name": "La Cantina"},
+				{"date": "2025-03-10", "slot": "lunch", "time": "13:00", "restaurant_name": "Sakura"},
+				{"date": "2025-03-10", "slot": "breakfast", "time": "08:00", "restaurant_name": "Café de Flore"},
+				{"date": "2025-03-12", "slot": "lunch", "time": "12:00", "restaurant_name": "Le Bistrot"}
+			]`,
+			want: []models.MealPlanEntry{
+				{Date: "2025-03-10", Slot: "lunch", Time: "12:00", RestaurantName: "La Cantina"},
+			},
+			wantUnplanned: []models.UnplannedMeal{
+				{Date: "2025-03-10", Slot: "dinner"},
+				{Date: "2025-03-11", Slot: "lunch"},
+				{Date: "2025-03-11", Slot: "dinner"},
+			},
+		},
+		{
+			name: "normalized fields",
+			output: `[
+				{"date": " 2025-03-11 ", "slot": "Lunch", "time": "noon", "restaurant_name": "La Cantina"},
+				{"date": "2025-03-10", "slot": "tea", "time": "16:00", "restaurant_name": "Sakura"},
+				{"date": "2025-03-10", "slot": "dinner", "time": "19:00", "restaurant_name": " "}
+			]`,
+			want: []models.MealPlanEntry{
+				{Date: "2025-03-11", Slot: "lunch", Time: "12:30", RestaurantName: "La Cantina"},
+			},
+			wantUnplanned: []models.UnplannedMeal{
+				{Date: "2025-03-10", Slot: "lunch"},
+				{Date: "2025-03-10", Slot: "dinner"},
+				{Date: "2025-03-11", Slot: "dinner"},
+			},
+		},
+		{
+			name:   "empty",
+			output: `[]`,
+			want:   []models.MealPlanEntry{},
+			// The guard dropped every meal, none goes missing silently
+			wantUnplanned: []models.UnplannedMeal{
+				{Date: "2025-03-10", Slot: "lunch"},
+				{Date: "2025-03-10", Slot: "dinner"},
+				{Date: "2025-03-11", Slot: "lunch"},
+				{Date: "2025-03-11", Slot: "dinner"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, unplanned, err := ParseMealPlan(tt.output, plan)
+			if err != nil {
+				t.Fatalf("ParseMealPlan() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMealPlan() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(unplanned, tt.wantUnplanned) {
+				t.Errorf("ParseMealPlan() unplanned = %+v, want %+v", unplanned, tt.wantUnplanned)
+			}
+		})
+	}
+}
+
+func TestParseMealPlanRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		plan   PlanInput
+	}{
+		{"not json", "Here is your plan!", PlanInput{Start: "2025-03-10", Days: 1, Slots: []string{"lunch"}}},
+		{"not an array", `{"date": "2025-03-10"}`, PlanInput{Start: "2025-03-10", Days: 1, Slots: []string{"lunch"}}},
+		{"invalid start", `[]`, PlanInput{Start: "10/03/2025", Days: 1, Slots: []string{"lunch"}}},
+		{"no day", `[]`, PlanInput{Start: "2025-03-10", Slots: []string{"lunch"}}},
+		{"no slot", `[]`, PlanInput{Start: "2025-03-10", Days: 1}},
+		{"unknown slot", `[]`, PlanInput{Start: "2025-03-10", Days: 1, Slots: []string{"elevenses"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _, err := ParseMealPlan(tt.output, tt.plan); err == nil {
+				t.Errorf("ParseMealPlan() = %+v, want an error", got)
+			}
+		})
+	}
+}
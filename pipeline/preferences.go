@@ -0,0 +1,234 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// PreferenceSource returns the explicit preferences of a user, implemented by db.PreferenceStore
+type PreferenceSource interface {
+	GetPreferences(ctx context.Context, userID string) (*models.UserPreferences, error)
+}
+
+// WithPreferences tells the model the preferences of the user and removes recommendations breaking them
+func WithPreferences(source PreferenceSource) AgentOption {
+	return func(o *agentOptions) {
+		o.preferences = source
+	}
+}
+
+// allergenIngredients are dish words revealing an allergen besides its own name
+var allergenIngredients = map[string][]string{
+	"peanut":    {"satay"},
+	"nut":       {"almond", "cashew", "hazelnut", "pecan", "pistachio", "walnut", "praline"},
+	"gluten":    {"wheat", "bread", "pasta", "noodle", "ramen", "udon", "croissant", "pizza", "burger", "sandwich", "toast"},
+	"milk":      {"cheese", "cream", "butter", "yogurt", "dairy"},
+	"dairy":     {"cheese", "cream", "butter", "yogurt", "milk"},
+	"egg":       {"omelette", "mayonnaise"},
+	"fish":      {"salmon", "tuna", "cod", "anchovy", "sashimi", "nigiri"},
+	"shellfish": {"shrimp", "prawn", "crab", "lobster", "oyster", "mussel", "scallop"},
+	"soy":       {"tofu", "miso", "edamame", "tempeh"},
+	"sesame":    {"tahini"},
+}
+
+// meats are ruled out by every vegetarian diet
+var meats = []string{"meat", "beef", "pork", "chicken", "lamb", "duck", "veal", "ham", "bacon", "sausage", "steak", "burger", "karaage", "chorizo", "pastor", "carnitas"}
+
+// dietExclusions are the dish words each known diet rules out, other diets are only told to the model
+var dietExclusions = map[string][]string{
+	"vegetarian":  append(append([]string{}, meats...), allergenIngredients["fish"]...),
+	"vegan":       append(append(append([]string{"egg", "honey"}, meats...), allergenIngredients["fish"]...), allergenIngredients["dairy"]...),
+	"pescatarian": meats,
+	"halal":       {"pork", "ham", "bacon", "chorizo", "pastor", "carnitas"},
+}
+
+// recommendedPlace is the part of a recommendation or meal plan entry the constraints are checked on
+type recommendedPlace struct {
+	RestaurantName string              `json:"restaurant_name"`
+	RestaurantID   string              `json:"restaurant_id"`
+	MainDishes     string              `json:"main_dishes"`
+	Coordinates    *models.Coordinates `json:"coordinates"`
+}
+
+// userPreferences loads the preferences of the user into the state, their language is the default locale
+func (r *DynamicFilterRetriever) userPreferences(ctx context.Context, userID string) {
+	if r.preferences == nil {
+		return
+	}
+	preferences, err := r.preferences.GetPreferences(ctx, userID)
+	if err != nil {
+		// Without them the prompt cannot state the constraints, and the filter cannot enforce them
+		hlog.SystemLogger().Errorf("Failed to load preferences of user %s: %v", userID, err)
+		return
+	}
+	if preferences == nil {
+		return
+	}
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		state.History["preferences"] = preferences
+		if locale, _ := state.History["locale"].(string); locale == "" {
+			state.History["locale"] = preferences.Language
+		}
+		return nil
+	})
+}
+
+// formatPreferences writes one line per preference the user set
+func formatPreferences(p *models.UserPreferences) string {
+	var b strings.Builder
+	line := func(label string, values []string) {
+		if len(values) > 0 {
+			b.WriteString("- " + label + ": " + strings.Join(values, ", ") + "\n")
+		}
+	}
+	line("Dietary restrictions", p.DietaryRestrictions)
+	line("Allergic to", p.Allergens)
+	line("Dislikes the cuisines", p.DislikedCuisines)
+	line("Never wants to go to", p.DislikedRestaurants)
+	if p.MaxPriceLevel > 0 {
+		b.WriteString("- Budget: at most " + strings.Repeat("$", p.MaxPriceLevel) + " on a scale from $ to $$$$\n")
+	}
+	if p.MaxDistanceKm > 0 {
+		fmt.Fprintf(&b, "- At most %.1f km away\n", p.MaxDistanceKm)
+	}
+	return b.String()
+}
+
+// filterPreferences component initialization function of node 'PreferenceFilter' in graph 'MealMateAgent'.
+// The model is told the preferences, but the hard constraints are enforced here rather than trusted to it.
+func filterPreferences(ctx context.Context, input *schema.Message) (output *schema.Message, err error) {
+	var preferences *models.UserPreferences
+	var known []knownRestaurant
+	var location *models.Coordinates
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		preferences, _ = state.History["preferences"].(*models.UserPreferences)
+		known, _ = state.History["known_restaurants"].([]knownRestaurant)
+		location, _ = state.History["location"].(*models.Coordinates)
+		return nil
+	})
+	if preferences == nil {
+		return input, nil
+	}
+	var items []map[string]any
+	var places []recommendedPlace
+	if json.Unmarshal([]byte(input.Content), &items) != nil || json.Unmarshal([]byte(input.Content), &places) != nil {
+		// Reported by the caller, like in the guard
+		return input, nil
+	}
+
+	kept := make([]map[string]any, 0, len(items))
+	for i, place := range places {
+		if reason := violation(place, known, preferences, location); reason != "" {
+			hlog.SystemLogger().Infof("Removed %q from the answer to user %s: %s", place.RestaurantName, preferences.UserID, reason)
+			continue
+		}
+		kept = append(kept, items[i])
+	}
+	if len(kept) == len(items) {
+		return input, nil
+	}
+	content, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.Message{Role: input.Role, Content: string(content), ResponseMeta: input.ResponseMeta}, nil
+}
+
+// violation returns which hard constraint the place breaks, empty when it breaks none.
+// Cuisine and price are only known for catalog restaurants, a place is not removed for what is unknown.
+func violation(place recommendedPlace, known []knownRestaurant, p *models.UserPreferences, location *models.Coordinates) string {
+	disliked := make([]knownRestaurant, 0, len(p.DislikedRestaurants))
+	for _, name := range p.DislikedRestaurants {
+		disliked = append(disliked, knownRestaurant{Name: name})
+	}
+	if _, ok := matchRestaurant(place.RestaurantName, disliked); ok {
+		return "disliked restaurant"
+	}
+
+	// A restaurant serving other food is fine, only the dishes recommended are checked
+	dishes := place.MainDishes
+	for _, allergen := range p.Allergens {
+		for _, word := range append([]string{allergen}, lookupTerm(allergenIngredients, allergen)...) {
+			if mentions(dishes, word) {
+				return "contains " + word + ", the user is allergic to " + allergen
+			}
+		}
+	}
+	for _, diet := range p.DietaryRestrictions {
+		for _, word := range lookupTerm(dietExclusions, diet) {
+			if mentions(dishes, word) {
+				return "contains " + word + ", the user is " + diet
+			}
+		}
+	}
+	details, _ := catalogDetails(place, known)
+	for _, cuisine := range p.DislikedCuisines {
+		if details.cuisine != "" && mentions(details.cuisine, cuisine) {
+			return "disliked cuisine " + details.cuisine
+		}
+	}
+	if p.MaxPriceLevel > 0 && details.priceLevel > p.MaxPriceLevel {
+		return "price " + strings.Repeat("$", details.priceLevel) + " is over budget"
+	}
+
+	coordinates := place.Coordinates
+	if coordinates == nil && details.ID != "" {
+		coordinates = &details.Coordinates
+	}
+	if p.MaxDistanceKm > 0 && location != nil && coordinates != nil && *coordinates != (models.Coordinates{}) {
+		if d := location.DistanceKm(*coordinates); d > p.MaxDistanceKm {
+			return fmt.Sprintf("%.1f km away", d)
+		}
+	}
+	return ""
+}
+
+// catalogDetails finds the catalog restaurant of a place, by the ID the guard attached or else by name
+func catalogDetails(place recommendedPlace, known []knownRestaurant) (knownRestaurant, bool) {
+	candidates := make([]knownRestaurant, 0, len(known))
+	for _, k := range known {
+		if !k.fromCatalog {
+			continue
+		}
+		if place.RestaurantID != "" && k.ID == place.RestaurantID {
+			return k, true
+		}
+		candidates = append(candidates, k)
+	}
+	if match, ok := matchRestaurant(place.RestaurantName, candidates); ok {
+		return match, true
+	}
+	return knownRestaurant{}, false
+}
+
+// lookupTerm reads the words of a term as users write it, e.g. "Peanuts" for "peanut"
+func lookupTerm(words map[string][]string, term string) []string {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if found, ok := words[term]; ok {
+		return found
+	}
+	return words[strings.TrimSuffix(term, "s")]
+}
+
+// mentions reports whether text contains term as words, a plural of term counts
+func mentions(text, term string) bool {
+	term = normalizeRestaurantName(term)
+	if term == "" {
+		return false
+	}
+	text = " " + normalizeRestaurantName(text) + " "
+	for _, form := range []string{term, term + "s", term + "es"} {
+		if strings.Contains(text, " "+form+" ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// invokeWithState runs fn as the only node of a graph whose state holds history
+func invokeWithState[I, O any](t *testing.T, history map[string]any, fn func(ctx context.Context, in I) (O, error), in I) O {
+	t.Helper()
+	g := compose.NewGraph[I, O](compose.WithGenLocalState(func(ctx context.Context) EventAgentState {
+		return EventAgentState{History: history}
+	}))
+	_ = g.AddLambdaNode("node", compose.InvokableLambda(fn))
+	_ = g.AddEdge(compose.START, "node")
+	_ = g.AddEdge("node", compose.END)
+	r, err := g.Compile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.Invoke(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestFilterPreferences(t *testing.T) {
+	paris := &models.Coordinates{Latitude: 48.8566, Longitude: 2.3522}
+	known := []knownRestaurant{
+		{ID: "cat-1", Name: "Le Bistrot", fromCatalog: true, cuisine: "French", priceLevel: 4},
+		{ID: "cat-2", Name: "Sakura", fromCatalog: true, cuisine: "Japanese", priceLevel: 2},
+		{ID: "cat-3", Name: "Far Away Diner", fromCatalog: true, Coordinates: models.Coordinates{Latitude: 48.95, Longitude: 2.35}},
+	}
+	answer := `[
+		{"restaurant_name": "Le Bistrot", "main_dishes": "Mushroom risotto"},
+		{"restaurant_name": "Sakura", "main_dishes": "Vegetable tempura"},
+		{"restaurant_name": "Thai Garden", "main_dishes": "Chicken satay"},
+		{"restaurant_name": "Burger Shack", "main_dishes": "Beef burgers"},
+		{"restaurant_name": "Far Away Diner", "main_dishes": "Pancakes"},
+		{"restaurant_name": "Green Leaf", "main_dishes": "Falafel, hummus"}
+	]`
+	all := []string{"Le Bistrot", "Sakura", "Thai Garden", "Burger Shack", "Far Away Diner", "Green Leaf"}
+	tests := []struct {
+		name        string
+		preferences *models.UserPreferences
+		location    *models.Coordinates
+		want        []string
+	}{
+		{"no preferences", nil, nil, all},
+		{"no constraint", &models.UserPreferences{Language: "fr-FR"}, nil, all},
+		{"disliked restaurant", &models.UserPreferences{DislikedRestaurants: []string{"the burger shack"}},
+			nil, []string{"Le Bistrot", "Sakura", "Thai Garden", "Far Away Diner", "Green Leaf"}},
+		{"allergen ingredient", &models.UserPreferences{Allergens: []string{"Peanuts"}},
+			nil, []string{"Le Bistrot", "Sakura", "Burger Shack", "Far Away Diner", "Green Leaf"}},
+		{"diet", &models.UserPreferences{DietaryRestrictions: []string{"vegetarian"}},
+			nil, []string{"Le Bistrot", "Sakura", "Far Away Diner", "Green Leaf"}},
+		{"disliked cuisine of the catalog", &models.UserPreferences{DislikedCuisines: []string{"japanese"}},
+			nil, []string{"Le Bistrot", "Thai Garden", "Burger Shack", "Far Away Diner", "Green Leaf"}},
+		{"budget", &models.UserPreferences{MaxPriceLevel: 2},
+			nil, []string{"Sakura", "Thai Garden", "Burger Shack", "Far Away Diner", "Green Leaf"}},
+		{"distance", &models.UserPreferences{MaxDistanceKm: 5},
+			paris, []string{"Le Bistrot", "Sakura", "Thai Garden", "Burger Shack", "Green Leaf"}},
+		{"distance without location", &models.UserPreferences{MaxDistanceKm: 5}, nil, all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := map[string]any{"known_restaurants": known}
+			if tt.preferences != nil {
+				history["preferences"] = tt.preferences
+			}
+			if tt.location != nil {
+				history["location"] = tt.location
+			}
+			out := invokeWithState(t, history, filterPreferences, schema.AssistantMessage(answer, nil))
+			var places []recommendedPlace
+			if err := json.Unmarshal([]byte(out.Content), &places); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(places))
+			for _, p := range places {
+				got = append(got, p.RestaurantName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterPreferences() kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterPreferencesKeepsInvalidOutput(t *testing.T) {
+	history := map[string]any{"preferences": &models.UserPreferences{Allergens: []string{"peanut"}}}
+	in := schema.AssistantMessage("Sorry, I have no idea", nil)
+	if out := invokeWithState(t, history, filterPreferences, in); out.Content != in.Content {
+		t.Errorf("filterPreferences() = %q, want the input", out.Content)
+	}
+}
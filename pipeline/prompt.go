@@ -2,45 +2,76 @@ package pipeline
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"strings"
+
+	"mealmate-agent/models"
 
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/schema"
 )
 
+// defaultMaxResults is the recommendation cap when the request does not set one
+const defaultMaxResults = 5
+
 type ChatTemplateImpl struct {
 	config *ChatTemplateConfig
 }
 
 type ChatTemplateConfig struct {
+	// SystemPrompt is the system message template, {history}, {max_results} and {max_reason_length} are substituted on Format
+	SystemPrompt string
+	// ColdStartPrompt replaces SystemPrompt for users without history, {popular} and {max_results} are substituted
+	ColdStartPrompt string
+	// GroupPrompt replaces both for group requests, {history} and {max_results} are substituted
+	GroupPrompt string
 }
 
-// newChatTemplate component initialization function of node 'EventChatTemplate' in graph 'MealMateAgent'
-func newChatTemplate(ctx context.Context) (ctp prompt.ChatTemplate, err error) {
-	// TODO Modify component configuration here.
-	config := &ChatTemplateConfig{}
-	ctp = &ChatTemplateImpl{config: config}
-	return ctp, nil
-}
-
-func (impl *ChatTemplateImpl) Format(ctx context.Context, vs map[string]any, opts ...prompt.Option) ([]*schema.Message, error) {
-	history := vs["history"].(string)
-	username := vs["username"].(string)
-	userPrompt := vs["user_prompt"].(string)
-	systemPrompt := `You are a cute waitress, and the advice you give needs to reflect your cuteness. Your task is to recommend suitable dining options based on the user's historical event records.
-
-	Event history:
-	` + history + `
+// recommendationScheduleRule keeps recommendations off the restaurants the user already goes to that day
+const recommendationScheduleRule = "Do not recommend a restaurant the user already has scheduled that day, and fit the request to their usual meal times."
 
-	IMPORTANT OUTPUT REQUIREMENTS:
+// outputRules open the output requirements of every template, the rules on the objects of the array follow them
+const outputRules = `IMPORTANT OUTPUT REQUIREMENTS:
 	1. You MUST respond with ONLY a valid JSON array, no additional text or explanation
 	2. Do NOT wrap the JSON in markdown code blocks or any other formatting
-	3. The JSON array must contain 1-5 restaurant recommendation objects
-	4. Each object MUST have exactly these 4 fields with the correct types:
+	`
+
+// outputReminder closes every template
+const outputReminder = `Remember: Output ONLY the JSON array, nothing else.`
+
+// MaxReasonLength is the length in characters the templates allow for a reason, substituted for {max_reason_length} on Format
+const MaxReasonLength = 100
+
+// recommendationOutput asks for the fields of models.RestaurantRecommendation, {max_results} is substituted on Format
+const recommendationOutput = outputRules + `3. The JSON array must contain 1-{max_results} restaurant recommendation objects
+	4. Each object MUST have exactly these fields with the correct types:
 	- "restaurant_name" (string): Name of the restaurant
 	- "recommendation_rating" (number): Rating from 0.0 to 5.0
 	- "main_dishes" (string): Signature dishes
-	- "short_reason" (string): Brief explanation (max 100 characters)
+	- "short_reason" (string): Brief explanation (max {max_reason_length} characters)`
+
+// RecommendationViolations lists what in a recommendation breaks the output format the templates ask for
+func RecommendationViolations(r models.RestaurantRecommendation) []string {
+	var violations []string
+	if strings.TrimSpace(r.RestaurantName) == "" {
+		violations = append(violations, "recommendation without restaurant_name")
+	}
+	if r.RecommendationRating < 0 || r.RecommendationRating > 5 {
+		violations = append(violations, fmt.Sprintf("%s: rating %.1f outside 0-5", r.RestaurantName, r.RecommendationRating))
+	}
+	if len([]rune(r.ShortReason)) > MaxReasonLength {
+		violations = append(violations, fmt.Sprintf("%s: short_reason longer than %d characters", r.RestaurantName, MaxReasonLength))
+	}
+	return violations
+}
+
+// DefaultSystemPrompt is the system message template used unless WithSystemPrompt replaces it
+const DefaultSystemPrompt = `You are a cute waitress, and the advice you give needs to reflect your cuteness. Your task is to recommend suitable dining options based on the user's historical event records.
+
+	Event history:
+	{history}
+
+	` + recommendationOutput + `
 
 	Example of correct output format:
 	[
@@ -52,9 +83,89 @@ func (impl *ChatTemplateImpl) Format(ctx context.Context, vs map[string]any, opt
 	}
 	]
 
-	Remember: Output ONLY the JSON array, nothing else.`
+	` + outputReminder
+
+// DefaultColdStartPrompt is the system message template for users without any event history
+const DefaultColdStartPrompt = `You are a cute waitress, and the advice you give needs to reflect your cuteness. This user is new and has no dining history with us yet, so you know nothing about their personal taste. Never claim or imply that a recommendation is based on their previous visits.
+
+	Restaurants popular with other diners:
+	{popular}
+
+	Recommend from the popular restaurants above when they fit the request, and say in the reason that other diners like them. If none fit, suggest generally well-liked options and be clear they are general suggestions.
+
+	` + recommendationOutput + `
+
+	` + outputReminder
+
+// DefaultGroupPrompt is the system message template for group requests, each member is described after it
+const DefaultGroupPrompt = `You are a cute waitress, and the advice you give needs to reflect your cuteness. Your task is to recommend dining options for a group, based on the historical event records of its members. A good option suits every member, not only the one asking, so rate it for the whole group and say in the short reason why it suits the group.
+
+	Event history of the members, each line starts with the name of the member:
+	{history}
+
+	` + recommendationOutput + `
+	- "member_fit" (object): One entry per member, keyed by their name exactly as listed below, with a brief explanation of the fit for them (max 80 characters)
+
+	Example of correct output format:
+	[
+	{
+		"restaurant_name": "Example Restaurant",
+		"recommendation_rating": 4.5,
+		"main_dishes": "Signature Dish Name",
+		"short_reason": "Something for everyone, halfway between you.",
+		"member_fit": {"Alex": "Has the vegan curry Alex liked last time.", "Sam": "Sam's favourite cuisine, within budget."}
+	}
+	]
+
+	` + outputReminder
+
+// groupColdStartHistory replaces the history when no member has any, popular restaurants are offered instead
+const groupColdStartHistory = "No member has dining history with us yet, never claim a recommendation is based on their previous visits. Restaurants popular with other diners:\n"
+
+// newChatTemplate component initialization function of node 'EventChatTemplate' in graph 'MealMateAgent'
+func newChatTemplate(ctx context.Context, systemPrompt string) (ctp prompt.ChatTemplate, err error) {
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+	config := &ChatTemplateConfig{SystemPrompt: systemPrompt, ColdStartPrompt: DefaultColdStartPrompt, GroupPrompt: DefaultGroupPrompt}
+	ctp = &ChatTemplateImpl{config: config}
+	return ctp, nil
+}
+
+func (impl *ChatTemplateImpl) Format(ctx context.Context, vs map[string]any, opts ...prompt.Option) ([]*schema.Message, error) {
+	history, _ := vs["history"].(string)
+	username, _ := vs["username"].(string)
+	userPrompt, _ := vs["user_prompt"].(string)
+	maxResults, _ := vs["max_results"].(int)
+	if maxResults <= 0 {
+		maxResults = defaultMaxResults
+	}
+	template := impl.config.SystemPrompt
+	coldStart, _ := vs["cold_start"].(bool)
+	if coldStart {
+		template = impl.config.ColdStartPrompt
+	}
+	popular, _ := vs["popular"].(string)
+	group, _ := vs["group"].(string)
+	if group != "" {
+		template = impl.config.GroupPrompt
+		if coldStart {
+			history = groupColdStartHistory + popular
+		}
+	}
+	systemPrompt := strings.NewReplacer(
+		"{history}", history,
+		"{popular}", popular,
+		"{max_results}", fmt.Sprint(maxResults),
+		"{max_reason_length}", fmt.Sprint(MaxReasonLength),
+	).Replace(template)
+
+	systemPrompt = appendContext(systemPrompt, vs, recommendationScheduleRule)
 
 	query := "I'm " + username + ", " + userPrompt
+	if companions, _ := vs["companions"].(string); group != "" && companions != "" {
+		query = "I'm " + username + ", eating with " + companions + ", " + userPrompt
+	}
 	messages := []*schema.Message{
 		{
 			Role:    schema.System,
@@ -68,11 +179,65 @@ func (impl *ChatTemplateImpl) Format(ctx context.Context, vs map[string]any, opt
 	return messages, nil
 }
 
+// appendContext adds the sections every template shares: answer language, group, preferences, taste profile, catalog, feedback, schedule and location,
+// scheduleRule tells the model what to do with the meals already scheduled
+func appendContext(systemPrompt string, vs map[string]any, scheduleRule string) string {
+	if locale, _ := vs["locale"].(string); locale != "" {
+		systemPrompt += "\n\n\tWrite the string values in the language of locale " + locale + ", keep the field names in English."
+	}
+	if group, _ := vs["group"].(string); group != "" {
+		systemPrompt += "\n\n\tThe members of the group, every recommendation must suit all of them:\n" + group
+	}
+	if preferences, _ := vs["preferences"].(string); preferences != "" {
+		systemPrompt += "\n\n\tPreferences the user set, never recommend anything against them:\n" + preferences
+	}
+	if profile, _ := vs["taste_profile"].(string); profile != "" {
+		systemPrompt += "\n\n\tTaste profile of the user, summarised from their whole history, the retrieved events are the ones closest to this request:\n" + profile
+	}
+	if catalog, _ := vs["catalog"].(string); catalog != "" {
+		systemPrompt += "\n\n\tRestaurants from our catalog that match the request:\n" + catalog +
+			"\tOnly recommend restaurants from this catalog or from the user's own history, never invent a restaurant."
+	}
+	if feedback, _ := vs["feedback"].(string); feedback != "" {
+		systemPrompt += "\n\n\tThe user's feedback on earlier recommendations, most recent first:\n" + feedback +
+			"\tRecommend places they liked again when they fit the request, and avoid places they rejected."
+	}
+	if schedule, _ := vs["schedule"].(string); schedule != "" {
+		systemPrompt += "\n\n\tTime and schedule of the user:\n" + schedule +
+			"\t" + scheduleRule
+	}
+	if location, _ := vs["location"].(*models.Coordinates); location != nil {
+		systemPrompt += fmt.Sprintf("\n\n\tThe user is currently at latitude %f, longitude %f, prefer places nearby.", location.Latitude, location.Longitude)
+	}
+	return systemPrompt
+}
+
 func chatTemplatePreHandler(ctx context.Context, in map[string]any, state EventAgentState) (map[string]any, error) {
 	userPrompt := state.History["user_prompt"]
 	username := state.History["username"]
 	in["user_prompt"] = userPrompt
 	in["username"] = username
-	log.Println("Updated input in chatTemplatePreHandler:", in)
+	in["locale"] = state.History["locale"]
+	in["location"] = state.History["location"]
+	in["max_results"] = state.History["max_results"]
+	in["feedback"] = state.History["feedback"]
+	in["plan"] = state.History["plan"]
+	if group, _ := state.History["group"].(*diningGroup); group != nil {
+		// The group section states the merged constraints and the meeting point
+		in["group"] = formatGroup(group)
+		in["companions"] = companions(group)
+		in["location"] = nil
+	} else if preferences, _ := state.History["preferences"].(*models.UserPreferences); preferences != nil {
+		in["preferences"] = formatPreferences(preferences)
+	}
+	if profile, _ := state.History["taste_profile"].(*models.TasteProfile); profile != nil {
+		in["taste_profile"] = formatTasteProfile(profile)
+	}
+	return in, nil
+}
+
+// chatModelPreHandler keeps the prompt so the hallucination guard can continue the conversation
+func chatModelPreHandler(ctx context.Context, in []*schema.Message, state EventAgentState) ([]*schema.Message, error) {
+	state.History["messages"] = in
 	return in, nil
 }
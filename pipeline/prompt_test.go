@@ -0,0 +1,71 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"mealmate-agent/models"
+)
+
+func TestChatTemplateFormat(t *testing.T) {
+	ctp, err := newChatTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		vs   map[string]any
+		want []string
+	}{
+		{"history", map[string]any{"history": "- Sushi Zen, salmon nigiri"},
+			[]string{"Event history:\n\t- Sushi Zen, salmon nigiri", "1-5 restaurant", "Matches your taste"}},
+		{"max results", map[string]any{"max_results": 2}, []string{"1-2 restaurant"}},
+		{"cold start", map[string]any{"cold_start": true, "popular": "- La Cantina"},
+			[]string{"This user is new", "popular with other diners:\n\t- La Cantina"}},
+		{"group", map[string]any{"group": "- Alex\n", "history": "Alex: Sakura"},
+			[]string{"for a group", `"member_fit"`, "Alex: Sakura"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := ctp.Format(context.Background(), tt.vs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			system := messages[0].Content
+			for _, want := range append(tt.want, fmt.Sprintf("(max %d characters)", MaxReasonLength), "Remember: Output ONLY the JSON array") {
+				if !strings.Contains(system, want) {
+					t.Errorf("system prompt has no %q:\n%s", want, system)
+				}
+			}
+			for _, placeholder := range []string{"{history}", "{popular}", "{max_results}", "{max_reason_length}"} {
+				if strings.Contains(system, placeholder) {
+					t.Errorf("system prompt has %s left:\n%s", placeholder, system)
+				}
+			}
+		})
+	}
+}
+
+func TestRecommendationViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		r    models.RestaurantRecommendation
+		want int
+	}{
+		{"valid", models.RestaurantRecommendation{RestaurantName: "Sakura", RecommendationRating: 4.5, ShortReason: "Fresh fish"}, 0},
+		{"no name", models.RestaurantRecommendation{RestaurantName: " ", RecommendationRating: 4}, 1},
+		{"rating", models.RestaurantRecommendation{RestaurantName: "Sakura", RecommendationRating: 5.5}, 1},
+		{"reason at the limit", models.RestaurantRecommendation{RestaurantName: "Sakura", ShortReason: strings.Repeat("é", MaxReasonLength)}, 0},
+		{"reason over the limit", models.RestaurantRecommendation{RestaurantName: "Sakura", ShortReason: strings.Repeat("a", MaxReasonLength+1)}, 1},
+		{"everything", models.RestaurantRecommendation{RecommendationRating: -1, ShortReason: strings.Repeat("a", MaxReasonLength+1)}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecommendationViolations(tt.r); len(got) != tt.want {
+				t.Errorf("RecommendationViolations() = %v, want %d violations", got, tt.want)
+			}
+		})
+	}
+}
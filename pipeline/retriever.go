@@ -3,136 +3,103 @@ package pipeline
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 
-	"github.com/bytedance/sonic"
-	"github.com/cloudwego/eino-ext/components/embedding/ark"
-	"github.com/cloudwego/eino-ext/components/retriever/milvus"
+	"mealmate-agent/models"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/retriever"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
-	"github.com/milvus-io/milvus-sdk-go/v2/client"
-	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 )
 
-func newRetriever(ctx context.Context, embedder *ark.Embedder, milvusClient *client.Client) (*milvus.Retriever, error) {
-	// check if shared embedder and milvus client are initialized
-	if embedder == nil {
-		panic("embedder not initialized, call SetSharedInstances first")
-	}
-	if milvusClient == nil {
-		panic("milvus client not initialized, call SetSharedInstances first")
-	}
-
-	searchParam, err := entity.NewIndexHNSWSearchParam(10)
-	if err != nil {
-		panic(err)
+// newRetriever component initialization function of node 'UserProfileRetriever' in graph 'MealMateAgent'
+func newRetriever(store vectorstore.Store) (retriever.Retriever, error) {
+	if store == nil {
+		return nil, fmt.Errorf("vector store not initialized")
 	}
-	r, err := milvus.NewRetriever(ctx, &milvus.RetrieverConfig{
-		Client:      *milvusClient,
-		Collection:  os.Getenv("MILVUS_EVENT_COLLECTION"),
-		VectorField: "vector",
-		OutputFields: []string{
-			"event_id",
-			"content",
-			"meta_data",
-			"user_id",
-		},
-		TopK:      3,
-		Embedding: embedder,
-		DocumentConverter: func(ctx context.Context, doc client.SearchResult) ([]*schema.Document, error) {
-			var err error
-			result := make([]*schema.Document, doc.IDs.Len())
-			for i := range result {
-				result[i] = &schema.Document{
-					MetaData: make(map[string]any),
-				}
-			}
-			for _, field := range doc.Fields {
-				switch field.Name() {
-				case "event_id":
-					for i, document := range result {
-						document.ID, err = doc.IDs.GetAsString(i)
-						if err != nil {
-							return nil, fmt.Errorf("failed to get id: %w", err)
-						}
-					}
-				case "content":
-					for i, document := range result {
-						document.Content, err = field.GetAsString(i)
-						if err != nil {
-							return nil, fmt.Errorf("failed to get content: %w", err)
-						}
-					}
-				case "meta_data":
-					for i, document := range result {
-						b, err := field.Get(i)
-						bytes, ok := b.([]byte)
-						if !ok {
-							return nil, fmt.Errorf("failed to get metadata: %w", err)
-						}
-						if err := sonic.Unmarshal(bytes, &document.MetaData); err != nil {
-							return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
-						}
-					}
-				case "user_id":
-					for i, document := range result {
-						document.MetaData["user_id"], err = field.GetAsString(i)
-						if err != nil {
-							return nil, fmt.Errorf("failed to get user_id: %w", err)
-						}
-					}
-				}
-			}
-			return result, nil
-		},
-		VectorConverter: func(ctx context.Context, vectors [][]float64) ([]entity.Vector, error) {
-			vecs := make([]entity.Vector, len(vectors))
-			for i, vector := range vectors {
-				float32Vec := make([]float32, len(vector))
-				for j, v := range vector {
-					float32Vec[j] = float32(v)
-				}
-				vecs[i] = entity.FloatVector(float32Vec)
-			}
-			return vecs, nil
-		},
-		MetricType: entity.COSINE,
-		Sp:         searchParam,
-	})
-	if err != nil {
-		return nil, err
-	}
-	return r, nil
+	return store, nil
 }
 
-func WithFilter(filterExpr string) retriever.Option {
-	return retriever.WrapImplSpecificOptFn(func(io *milvus.ImplOptions) {
-		io.Filter = filterExpr
-	})
-}
+// ErrInvalidInput is wrapped by every error caused by a malformed graph input
+var ErrInvalidInput = errors.New("invalid agent input")
+
+// ErrNotShared is wrapped when a participant of a group request did not agree to share with the user asking
+var ErrNotShared = errors.New("participant does not share with the user")
 
 // Input JSON for retriever
 type RetrieverInput struct {
-	UserPrompt string `json:"user_prompt"`
-	UserID     string `json:"user_id"`
-	Username   string `json:"username"`
+	UserPrompt string              `json:"user_prompt"`
+	UserID     string              `json:"user_id"`
+	Username   string              `json:"username"`
+	Locale     string              `json:"locale,omitempty"`
+	Location   *models.Coordinates `json:"location,omitempty"`
+	MaxResults int                 `json:"max_results,omitempty"`
+	// Timezone is the IANA zone of the user, the zone of their latest scheduled meal is used when empty
+	Timezone string `json:"timezone,omitempty"`
+	// Participants make it a group request, see 'GroupResolver'
+	Participants []models.Participant `json:"participants,omitempty"`
+	// Plan asks the meal plan agent for these days and slots, the recommendation agent ignores it
+	Plan *PlanInput `json:"plan,omitempty"`
 }
 
 // Wrapped retriever to support dynamic filter
 type DynamicFilterRetriever struct {
 	baseRetriever retriever.Retriever
+	// topK overrides the store default when positive
+	topK int
+	// feedback reorders the history of the user, nil to keep the store order
+	feedback FeedbackSource
+	// preferences are stated in the prompt and enforced by 'PreferenceFilter', nil when users have none
+	preferences PreferenceSource
+	// profiles summarise the whole history of the user next to the retrieved events, nil to use the events only
+	profiles ProfileSource
 }
 
-func NewDynamicFilterRetriever(embedder *ark.Embedder, milvusClient *client.Client) *DynamicFilterRetriever {
-	base, err := newRetriever(context.Background(), embedder, milvusClient)
+func NewDynamicFilterRetriever(store vectorstore.Store) (*DynamicFilterRetriever, error) {
+	base, err := newRetriever(store)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	return &DynamicFilterRetriever{
 		baseRetriever: base,
+	}, nil
+}
+
+// IsCallbacksEnabled lets the wrapped store retriever report callbacks itself, so the node is not counted twice.
+// Inputs rejected before the store is called are reported by inputError.
+func (r *DynamicFilterRetriever) IsCallbacksEnabled() bool {
+	return true
+}
+
+// inputError reports an invalid input as an error of the retriever node, the store that would otherwise report the
+// node is never called
+func inputError(ctx context.Context, query string, err error) error {
+	ctx = callbacks.OnStart(ctx, &retriever.CallbackInput{Query: query})
+	callbacks.OnError(ctx, err)
+	return err
+}
+
+// validate checks the fields every agent needs, 'GroupResolver' runs it too before looking up any participant
+func (input RetrieverInput) validate() error {
+	if input.UserPrompt == "" {
+		return fmt.Errorf("%w: user prompt is empty", ErrInvalidInput)
+	}
+	if input.UserID == "" {
+		return fmt.Errorf("%w: user id is empty", ErrInvalidInput)
+	}
+	if input.Username == "" {
+		return fmt.Errorf("%w: username is empty", ErrInvalidInput)
 	}
+	if input.Plan != nil {
+		if _, err := input.Plan.meals(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+	return nil
 }
 
 // Implement the retriever.Retriever interface
@@ -140,25 +107,66 @@ func (r *DynamicFilterRetriever) Retrieve(ctx context.Context, query string, opt
 	var input RetrieverInput
 	if err := json.Unmarshal([]byte(query), &input); err == nil {
 		actualQuery := input.UserPrompt
-		if actualQuery == "" {
-			return nil, fmt.Errorf("user prompt is empty")
-		}
-		if input.UserID == "" {
-			return nil, fmt.Errorf("user id is empty")
-		}
-		if input.Username == "" {
-			return nil, fmt.Errorf("username is empty")
+		if err := input.validate(); err != nil {
+			return nil, inputError(ctx, query, err)
 		}
 		compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
 			state.History["user_id"] = input.UserID
 			state.History["user_prompt"] = input.UserPrompt
 			state.History["username"] = input.Username
+			state.History["locale"] = input.Locale
+			state.History["location"] = input.Location
+			state.History["max_results"] = input.MaxResults
+			state.History["plan"] = input.Plan
+			return nil
+		})
+		if len(input.Participants) > 0 {
+			return r.retrieveGroup(ctx, input, opts)
+		}
+		r.userPreferences(ctx, input.UserID)
+		r.tasteProfile(ctx, input.UserID)
+		opts = append(opts, vectorstore.WithFilter(vectorstore.Filter{UserID: input.UserID}))
+		feedback := r.userFeedback(ctx, input.UserID)
+		// Disliked restaurants are ranked last, so fetch enough to still fill topK with others
+		if r.topK > 0 {
+			opts = append(opts, retriever.WithTopK(feedbackTopK(r.topK, feedback)))
+		}
+
+		docs, err := r.baseRetriever.Retrieve(ctx, actualQuery, opts...)
+		if err != nil {
+			return nil, err
+		}
+		docs = rerankByFeedback(docs, feedback)
+		if r.topK > 0 && len(docs) > r.topK {
+			docs = docs[:r.topK]
+		}
+		return docs, nil
+	}
+	return nil, inputError(ctx, query, fmt.Errorf("%w: input is not a valid json", ErrInvalidInput))
+}
+
+// userFeedback loads the latest feedback of the user and keeps it in the state for the prompt
+func (r *DynamicFilterRetriever) userFeedback(ctx context.Context, userID string) []restaurantFeedback {
+	feedback := r.loadFeedback(ctx, userID)
+	if len(feedback) > 0 {
+		_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+			state.History["feedback"] = formatFeedback(feedback)
 			return nil
 		})
-		filterExpr := fmt.Sprintf("user_id == \"%s\"", input.UserID)
-		opts = append(opts, WithFilter(filterExpr))
+	}
+	return feedback
+}
 
-		return r.baseRetriever.Retrieve(ctx, actualQuery, opts...)
+// loadFeedback returns the latest feedback of the user on every restaurant
+func (r *DynamicFilterRetriever) loadFeedback(ctx context.Context, userID string) []restaurantFeedback {
+	if r.feedback == nil {
+		return nil
+	}
+	all, err := r.feedback.FeedbackByUser(ctx, userID)
+	if err != nil {
+		// Feedback only tunes the answer, a failed lookup must not fail it
+		hlog.SystemLogger().Errorf("Failed to load feedback of user %s: %v", userID, err)
+		return nil
 	}
-	return nil, fmt.Errorf("input is not a valid json")
+	return latestFeedback(all)
 }
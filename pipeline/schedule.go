@@ -0,0 +1,201 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mealmate-agent/schedule"
+	"mealmate-agent/vectorstore"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// upcomingMeals is how many scheduled meals after now are shown to the model
+	upcomingMeals = 10
+	// habitLookback is how many history events meal times are learned from
+	habitLookback = 50
+)
+
+// WithClock sets the current time of the agent, replays use a fixed clock so their prompts do not change
+func WithClock(now func() time.Time) AgentOption {
+	return func(o *agentOptions) {
+		o.clock = now
+	}
+}
+
+// scheduleRequest is what 'ScheduleGen' needs from the input, kept in the state by 'ScheduleRetriever'
+type scheduleRequest struct {
+	now    time.Time
+	prompt string
+	// location is the zone of the user, nil when the request gave none
+	location *time.Location
+}
+
+// MealScheduleRetriever finds the upcoming scheduled meals of the user and enough history to learn their meal times
+type MealScheduleRetriever struct {
+	store vectorstore.Store
+	clock func() time.Time
+}
+
+// IsCallbacksEnabled lets the store report callbacks itself, like DynamicFilterRetriever
+func (r *MealScheduleRetriever) IsCallbacksEnabled() bool {
+	return true
+}
+
+// Retrieve reads the same JSON input as DynamicFilterRetriever, which validates it
+func (r *MealScheduleRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	var input RetrieverInput
+	if err := json.Unmarshal([]byte(query), &input); err != nil {
+		return nil, inputError(ctx, query, fmt.Errorf("%w: input is not a valid json", ErrInvalidInput))
+	}
+	if input.UserID == "" || input.UserPrompt == "" {
+		return nil, nil
+	}
+	request := scheduleRequest{now: r.clock(), prompt: input.UserPrompt}
+	if input.Timezone != "" {
+		location, err := time.LoadLocation(input.Timezone)
+		if err != nil {
+			return nil, inputError(ctx, query, fmt.Errorf("%w: unknown timezone %q", ErrInvalidInput, input.Timezone))
+		}
+		request.location = location
+	}
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		state.History["schedule"] = request
+		return nil
+	})
+
+	upcoming, err := r.store.Retrieve(ctx, input.UserPrompt, retriever.WithTopK(upcomingMeals), vectorstore.WithFilter(vectorstore.Filter{
+		UserID: input.UserID,
+		Where:  vectorstore.Meta("schedule_unix").Gte(request.now.Unix()),
+	}))
+	if err != nil {
+		return nil, err
+	}
+	history, err := r.store.Retrieve(ctx, input.UserPrompt, retriever.WithTopK(habitLookback), vectorstore.WithFilter(vectorstore.Filter{UserID: input.UserID}))
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(upcoming))
+	docs := make([]*schema.Document, 0, len(upcoming)+len(history))
+	for _, doc := range append(upcoming, history...) {
+		if !seen[doc.ID] {
+			seen[doc.ID] = true
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// scheduledMeal is a history event with a valid schedule time
+type scheduledMeal struct {
+	restaurant string
+	at         time.Time
+}
+
+// genSchedule component initialization function of node 'ScheduleGen' in graph 'MealMateAgent'
+func genSchedule(ctx context.Context, input []*schema.Document) (output map[string]any, err error) {
+	var request scheduleRequest
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		request, _ = state.History["schedule"].(scheduleRequest)
+		return nil
+	})
+	if request.now.IsZero() {
+		return map[string]any{"schedule": ""}, nil
+	}
+
+	known := make([]knownRestaurant, 0, len(input))
+	var upcoming, past []scheduledMeal
+	for _, doc := range input {
+		known = append(known, historyRestaurant(doc))
+		raw, _ := doc.MetaData["schedule"].(string)
+		at, err := schedule.Parse(raw)
+		if err != nil {
+			continue
+		}
+		name, _ := doc.MetaData["restaurant_name"].(string)
+		if name == "" {
+			name = doc.Content
+		}
+		meal := scheduledMeal{restaurant: name, at: at}
+		if at.Before(request.now) {
+			past = append(past, meal)
+		} else {
+			upcoming = append(upcoming, meal)
+		}
+	}
+	rememberRestaurants(ctx, known...)
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].at.Before(upcoming[j].at) })
+
+	// Without a zone in the request, meals keep the offset they were scheduled with, which follows daylight saving
+	localTime := func(t time.Time) time.Time {
+		if request.location != nil {
+			return t.In(request.location)
+		}
+		return t
+	}
+	location := request.location
+	if location == nil {
+		location = userLocation(past, upcoming, request.now)
+	}
+	now := request.now.In(location)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "- Now it is %s for the user, %s time.\n", formatMealTime(now), schedule.SlotOf(now))
+	target, hasTarget := schedule.ParseTarget(request.prompt, now)
+	if hasTarget {
+		meal := "a meal"
+		if target.Slot != "" {
+			meal = string(target.Slot)
+		}
+		fmt.Fprintf(&b, "- The request is about %s on %s.\n", meal, target.Day.Format("Monday 2006-01-02"))
+	}
+	if len(upcoming) > 0 {
+		b.WriteString("- Upcoming scheduled meals:\n")
+		for _, meal := range upcoming {
+			at := localTime(meal.at)
+			fmt.Fprintf(&b, "  - %s, %s at %s", formatMealTime(at), schedule.SlotOf(at), meal.restaurant)
+			if hasTarget && target.Contains(at) {
+				b.WriteString(", already planned for the requested meal")
+			}
+			b.WriteString("\n")
+		}
+	}
+	// Habits are read on the clock where each meal took place, even when the user travels now
+	pastTimes := make([]time.Time, 0, len(past))
+	for _, meal := range past {
+		pastTimes = append(pastTimes, meal.at)
+	}
+	if habits := schedule.LearnHabits(pastTimes); len(habits) > 0 {
+		b.WriteString("- Usual meal times: " + schedule.FormatHabits(habits) + ".\n")
+	}
+	return map[string]any{"schedule": b.String()}, nil
+}
+
+// userLocation is the zone of the meal closest to now, events keep the offset of the user
+func userLocation(past, upcoming []scheduledMeal, now time.Time) *time.Location {
+	var latest *scheduledMeal
+	for i := range past {
+		if latest == nil || past[i].at.After(latest.at) {
+			latest = &past[i]
+		}
+	}
+	// The next meal is closer to now than the last one when daylight saving changed in between
+	if len(upcoming) > 0 && (latest == nil || upcoming[0].at.Sub(now) < now.Sub(latest.at)) {
+		latest = &upcoming[0]
+	}
+	if latest == nil {
+		return now.Location()
+	}
+	return latest.at.Location()
+}
+
+func formatMealTime(t time.Time) string {
+	return t.Format("Monday 2006-01-02 15:04 (-07:00)")
+}
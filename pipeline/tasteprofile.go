@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// ProfileSource returns the taste profile summarised from the whole history of a user, implemented by db.ProfileStore
+type ProfileSource interface {
+	GetProfile(ctx context.Context, userID string) (*models.TasteProfile, error)
+}
+
+// WithTasteProfiles gives the model the taste profile of the user alongside the events retrieved for the request
+func WithTasteProfiles(source ProfileSource) AgentOption {
+	return func(o *agentOptions) {
+		o.profiles = source
+	}
+}
+
+// tasteProfile loads the taste profile of the user into the state
+func (r *DynamicFilterRetriever) tasteProfile(ctx context.Context, userID string) {
+	if r.profiles == nil {
+		return
+	}
+	profile, err := r.profiles.GetProfile(ctx, userID)
+	if err != nil {
+		// The retrieved events still personalise the answer
+		hlog.SystemLogger().Errorf("Failed to load the taste profile of user %s: %v", userID, err)
+		return
+	}
+	if profile == nil {
+		return
+	}
+	_ = compose.ProcessState(ctx, func(ctx context.Context, state EventAgentState) error {
+		state.History["taste_profile"] = profile
+		return nil
+	})
+}
+
+// formatTasteProfile writes one line per field the profile has
+func formatTasteProfile(p *models.TasteProfile) string {
+	var b strings.Builder
+	line := func(label, value string) {
+		if value != "" {
+			b.WriteString("- " + label + ": " + value + "\n")
+		}
+	}
+	line("Favourite cuisines", strings.Join(p.FavouriteCuisines, ", "))
+	line("Price band", p.PriceBand)
+	line("Usual areas", strings.Join(p.UsualAreas, ", "))
+	line("Habits", strings.Join(p.TimePatterns, "; "))
+	line("Summary", p.Summary)
+	if b.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("- Summarised from %d events\n", p.EventCount) + b.String()
+}
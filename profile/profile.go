@@ -0,0 +1,270 @@
+// Package profile keeps a taste profile per user: the chat model summarises their whole history once, then folds in
+// the events that sync later
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+	"mealmate-agent/schedule"
+	"mealmate-agent/telemetry"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// historyLimit bounds how many events one summary reads, the most recent ones
+const historyLimit = 200
+
+// systemPrompt asks for the fields of models.TasteProfile the model writes
+const systemPrompt = `You keep the taste profile of a MealMate user, a compact summary of their dining history used to personalise restaurant recommendations.
+
+	IMPORTANT OUTPUT REQUIREMENTS:
+	1. You MUST respond with ONLY a valid JSON object, no additional text or explanation
+	2. Do NOT wrap the JSON in markdown code blocks or any other formatting
+	3. The object MUST have exactly these 5 fields with the correct types:
+	- "favourite_cuisines" (array of strings): Up to 5 cuisines the user eats most or enjoys most, favourite first
+	- "price_band" (string): How much the user usually spends, on a scale from $ to $$$$
+	- "usual_areas" (array of strings): Up to 3 neighbourhoods or cities the user usually eats in
+	- "time_patterns" (array of strings): Up to 4 short patterns of when and with whom the user eats out
+	- "summary" (string): Two sentences on the taste of the user (max 300 characters)
+
+	Only state what the events support, leave a field empty rather than guess.`
+
+// Refresher builds and updates taste profiles in the background as events are indexed
+type Refresher struct {
+	chatModel model.BaseChatModel
+	source    db.EventSource
+	store     db.ProfileStore
+	// delay gathers the events of a sync before refreshing, so a burst of events of one user costs one call
+	delay time.Duration
+
+	mu      sync.Mutex
+	pending map[string]bool
+	wake    chan struct{}
+	// wg tracks the refresh goroutine so shutdown can wait for a running refresh
+	wg sync.WaitGroup
+}
+
+/**
+* @description: Create a refresher, start it with Start and feed it with Notify
+* @param cm chat model writing the profiles
+* @param source event source the history of a user is read from
+* @param store where the profiles are kept
+* @param delay how long events are gathered before the profiles of their users are refreshed
+* @return refresher
+ */
+func NewRefresher(cm model.BaseChatModel, source db.EventSource, store db.ProfileStore, delay time.Duration) *Refresher {
+	return &Refresher{
+		chatModel: cm,
+		source:    source,
+		store:     store,
+		delay:     delay,
+		pending:   make(map[string]bool),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Notify queues the profiles of the users of events for a refresh without blocking, it is the db.MilvusDatabase.OnIndexed hook
+func (r *Refresher) Notify(events []models.Event) {
+	r.mu.Lock()
+	for _, event := range events {
+		if event.UserID != "" {
+			r.pending[event.UserID] = true
+		}
+	}
+	r.mu.Unlock()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start refreshes the queued profiles in a background goroutine until ctx is cancelled.
+// Users still queued then are caught up by their next refresh, which reads every event after the profile.
+func (r *Refresher) Start(ctx context.Context) {
+	// A refresh that has started is allowed to finish, so its model call is not wasted
+	runCtx := context.WithoutCancel(ctx)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.wake:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.delay):
+			}
+			for _, userID := range r.takePending() {
+				if ctx.Err() != nil {
+					return
+				}
+				if _, err := r.Refresh(runCtx, userID, false); err != nil {
+					hlog.SystemLogger().Errorf("Failed to refresh the taste profile of user %s: %v", userID, err)
+				}
+			}
+		}
+	}()
+	hlog.SystemLogger().Info("Taste profile refresh started")
+}
+
+func (r *Refresher) takePending() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := make([]string, 0, len(r.pending))
+	for userID := range r.pending {
+		users = append(users, userID)
+	}
+	r.pending = make(map[string]bool)
+	sort.Strings(users)
+	return users
+}
+
+/**
+* @description: Wait for the refresh goroutine to exit, call after cancelling the context passed to Start
+* @param ctx context.Context, its deadline bounds the wait
+* @return nil if the running refresh finished, ctx error if the deadline passed first
+ */
+func (r *Refresher) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("taste profile refresh still running at shutdown deadline: %w", ctx.Err())
+	}
+}
+
+// datedEvent is an event with its parsed creation time, events are summarised in creation order
+type datedEvent struct {
+	models.Event
+	created time.Time
+}
+
+/**
+* @description: Update the taste profile of a user with the events created after it, or build it from their whole history
+* @param ctx context.Context
+* @param userID user whose profile is refreshed
+* @param rebuild summarise the whole history again, e.g. after events were edited or deleted
+* @return the saved profile, the current one when there is no new event, nil if the user has no event
+ */
+func (r *Refresher) Refresh(ctx context.Context, userID string, rebuild bool) (*models.TasteProfile, error) {
+	current, err := r.store.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if rebuild {
+		current = nil
+	}
+	events, err := r.source.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]datedEvent, 0, len(events))
+	for _, event := range events {
+		// An event without a valid time sorts first, it is summarised by the first build
+		created, _ := schedule.Parse(event.CreatedAt)
+		history = append(history, datedEvent{Event: event, created: created})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].created.Equal(history[j].created) {
+			return history[i].created.Before(history[j].created)
+		}
+		return history[i].ID < history[j].ID
+	})
+
+	fresh := history
+	if current != nil {
+		fresh = history[sort.Search(len(history), func(i int) bool {
+			e := history[i]
+			return e.created.After(current.LastEventAt) || (e.created.Equal(current.LastEventAt) && e.ID > current.LastEventID)
+		}):]
+	}
+	if len(fresh) == 0 {
+		return current, nil
+	}
+	fresh = fresh[max(0, len(fresh)-historyLimit):]
+
+	messages, err := prompt(current, fresh, history)
+	if err != nil {
+		return nil, err
+	}
+	// Metered and traced like the chat model calls of the agent
+	ctx = callbacks.InitCallbacks(ctx, &callbacks.RunInfo{Name: "TasteProfile", Component: components.ComponentOfChatModel}, telemetry.CallbackHandlers()...)
+	answer, err := r.chatModel.Generate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	var profile models.TasteProfile
+	if err := json.Unmarshal([]byte(strings.TrimSpace(answer.Content)), &profile); err != nil {
+		return nil, fmt.Errorf("model returned an invalid taste profile: %w", err)
+	}
+	last := history[len(history)-1]
+	profile.UserID = userID
+	profile.EventCount = len(history)
+	profile.LastEventAt, profile.LastEventID = last.created, last.ID
+	profile.UpdatedAt = time.Now().UTC()
+	if err := r.store.SaveProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	hlog.SystemLogger().Infof("Refreshed the taste profile of user %s with %d events", userID, len(fresh))
+	return &profile, nil
+}
+
+// prompt asks for a new profile from the events, or for current updated with them. Meal times are learned from
+// the whole history rather than counted by the model.
+func prompt(current *models.TasteProfile, fresh, history []datedEvent) ([]*schema.Message, error) {
+	var b strings.Builder
+	if current != nil {
+		previous, err := json.Marshal(struct {
+			FavouriteCuisines []string `json:"favourite_cuisines"`
+			PriceBand         string   `json:"price_band"`
+			UsualAreas        []string `json:"usual_areas"`
+			TimePatterns      []string `json:"time_patterns"`
+			Summary           string   `json:"summary"`
+		}{current.FavouriteCuisines, current.PriceBand, current.UsualAreas, current.TimePatterns, current.Summary})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "Current profile, summarising %d events:\n%s\n\nUpdate it with the new events, in the order they were logged:\n", current.EventCount, previous)
+	} else {
+		b.WriteString("Events of the user, in the order they were logged:\n")
+	}
+	for _, event := range fresh {
+		b.WriteString("- ")
+		if scheduled, err := schedule.Parse(event.ScheduleTime); err == nil {
+			b.WriteString(scheduled.Format("Mon 2006-01-02 15:04") + ", ")
+		}
+		fmt.Fprintf(&b, "%s (%.4f, %.4f): %s\n", event.RestaurantName,
+			event.RestaurantCoordinates.Latitude, event.RestaurantCoordinates.Longitude, event.Message)
+	}
+
+	times := make([]time.Time, 0, len(history))
+	for _, event := range history {
+		if scheduled, err := schedule.Parse(event.ScheduleTime); err == nil {
+			times = append(times, scheduled)
+		}
+	}
+	if habits := schedule.LearnHabits(times); len(habits) > 0 {
+		fmt.Fprintf(&b, "\nUsual meal times over all %d events: %s.\n", len(history), schedule.FormatHabits(habits))
+	}
+	return []*schema.Message{schema.SystemMessage(systemPrompt), schema.UserMessage(b.String())}, nil
+}
@@ -0,0 +1,162 @@
+package profile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mealmate-agent/db"
+	"mealmate-agent/models"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const answer = `{"favourite_cuisines": ["japanese"], "price_band": "$$", "usual_areas": ["Paris"], "time_patterns": [], "summary": "Loves sushi."}`
+
+// recordingModel answers content and keeps the user message of every call
+type recordingModel struct {
+	content string
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (m *recordingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prompts = append(m.prompts, input[len(input)-1].Content)
+	return schema.AssistantMessage(m.content, nil), nil
+}
+
+func (m *recordingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *recordingModel) calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.prompts...)
+}
+
+// newTestRefresher runs on a JSONL event source holding events and a profile store, both in a temporary directory
+func newTestRefresher(t *testing.T, content string, events ...models.Event) (*Refresher, *recordingModel, *db.JSONLEventSource, *db.JSONLProfileStore) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := db.NewJSONLEventSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.InsertEvents(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	store := db.NewJSONLProfileStore(filepath.Join(dir, "profiles.jsonl"))
+	cm := &recordingModel{content: content}
+	return NewRefresher(cm, source, store, 0), cm, source, store
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	r, cm, source, store := newTestRefresher(t, answer,
+		models.Event{ID: 2, UserID: "u1", RestaurantName: "Le Bistrot", CreatedAt: "2025-03-03T12:00:00Z"},
+		models.Event{ID: 1, UserID: "u1", RestaurantName: "Sakura", CreatedAt: "2025-03-01T12:00:00Z", ScheduleTime: "2025-03-01T19:30:00Z"},
+		models.Event{ID: 3, UserID: "u2", RestaurantName: "La Cantina", CreatedAt: "2025-03-02T12:00:00Z"},
+	)
+
+	built, err := r.Refresh(ctx, "u1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built.UserID != "u1" || built.EventCount != 2 || built.LastEventID != 2 || built.Summary != "Loves sushi." {
+		t.Errorf("Refresh() = %+v", built)
+	}
+	prompt := cm.calls()[0]
+	if !strings.HasPrefix(prompt, "Events of the user") || strings.Index(prompt, "Sakura") > strings.Index(prompt, "Le Bistrot") || strings.Contains(prompt, "La Cantina") {
+		t.Errorf("first prompt = %q, want the events of u1 in creation order", prompt)
+	}
+	if saved, err := store.GetProfile(ctx, "u1"); err != nil || saved == nil || saved.EventCount != 2 {
+		t.Errorf("GetProfile() = %+v, %v", saved, err)
+	}
+
+	// Nothing new, the model is not called
+	if current, err := r.Refresh(ctx, "u1", false); err != nil || current.EventCount != 2 || len(cm.calls()) != 1 {
+		t.Errorf("Refresh() without new events = %+v, %v after %d calls", current, err, len(cm.calls()))
+	}
+
+	// A new event updates the profile with that event only
+	if err := source.InsertEvents(ctx, []models.Event{{ID: 4, UserID: "u1", RestaurantName: "Ramen Ya", CreatedAt: "2025-03-04T12:00:00Z"}}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := r.Refresh(ctx, "u1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt = cm.calls()[1]
+	if updated.EventCount != 3 || updated.LastEventID != 4 || !strings.HasPrefix(prompt, "Current profile, summarising 2 events") ||
+		!strings.Contains(prompt, "Ramen Ya") || strings.Contains(prompt, "Sakura") {
+		t.Errorf("Refresh() with a new event = %+v, prompt %q", updated, prompt)
+	}
+
+	// A rebuild reads the whole history again
+	if _, err := r.Refresh(ctx, "u1", true); err != nil {
+		t.Fatal(err)
+	}
+	if prompt := cm.calls()[2]; !strings.HasPrefix(prompt, "Events of the user") || !strings.Contains(prompt, "Sakura") {
+		t.Errorf("rebuild prompt = %q, want the whole history", prompt)
+	}
+}
+
+func TestRefreshWithoutEvents(t *testing.T) {
+	r, cm, _, _ := newTestRefresher(t, answer)
+	if profile, err := r.Refresh(context.Background(), "u1", false); err != nil || profile != nil || len(cm.calls()) != 0 {
+		t.Errorf("Refresh() = %+v, %v after %d calls, want nil without calling the model", profile, err, len(cm.calls()))
+	}
+}
+
+func TestRefreshInvalidAnswer(t *testing.T) {
+	ctx := context.Background()
+	r, _, _, store := newTestRefresher(t, "The user likes sushi.", models.Event{ID: 1, UserID: "u1", RestaurantName: "Sakura"})
+	if _, err := r.Refresh(ctx, "u1", false); err == nil || !strings.Contains(err.Error(), "invalid taste profile") {
+		t.Errorf("Refresh() error = %v, want an invalid taste profile", err)
+	}
+	if saved, _ := store.GetProfile(ctx, "u1"); saved != nil {
+		t.Errorf("GetProfile() = %+v, want nothing saved", saved)
+	}
+}
+
+func TestNotifyRefreshesInBackground(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r, _, _, store := newTestRefresher(t, answer, models.Event{ID: 1, UserID: "u1", RestaurantName: "Sakura"})
+	r.Start(ctx)
+	r.Notify([]models.Event{{ID: 1, UserID: "u1"}, {ID: 1, UserID: ""}})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if saved, err := store.GetProfile(ctx, "u1"); err == nil && saved != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the profile of u1 was not refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	waitCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if err := r.Wait(waitCtx); err != nil {
+		t.Error(err)
+	}
+}
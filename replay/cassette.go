@@ -0,0 +1,188 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Mode selects whether wrapped components call the real backend
+type Mode string
+
+const (
+	// ModeOff passes every call through untouched
+	ModeOff Mode = "off"
+	// ModeRecord calls the real backend and captures each request/response pair
+	ModeRecord Mode = "record"
+	// ModeReplay serves captured responses and never calls the real backend
+	ModeReplay Mode = "replay"
+)
+
+var (
+	// ErrFixtureMissing is returned in replay mode when a request was never recorded
+	ErrFixtureMissing = errors.New("replay fixture missing")
+	// ErrFixtureStale is returned in replay mode when a request was recorded from a different prompt, it must be recorded again
+	ErrFixtureStale = errors.New("replay fixture recorded from a different prompt, record it again")
+)
+
+/**
+* @description: Parse a replay mode, the empty string means off
+* @param s mode name
+* @return the mode, error if unknown
+ */
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeRecord, ModeReplay:
+		return Mode(s), nil
+	default:
+		return "", fmt.Errorf("unknown replay mode %q", s)
+	}
+}
+
+// Interaction is one captured call, Request and Response are the component specific payloads.
+// Key identifies the call by the part of the request that stays the same across prompt changes,
+// Fingerprint hashes the whole request so a replay from a changed prompt is detected.
+type Interaction struct {
+	Kind        string          `json:"kind"`
+	Key         string          `json:"key"`
+	Fingerprint string          `json:"fingerprint"`
+	Request     json.RawMessage `json:"request"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// Cassette is a fixture file holding every interaction of one scenario
+type Cassette struct {
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions map[string]Interaction
+	dirty        bool
+}
+
+/**
+* @description: Open a cassette, loading the file in replay mode and when it already exists in record mode
+* @param path fixture file path
+* @param mode replay mode
+* @return the cassette, error if the file cannot be read
+ */
+func OpenCassette(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, interactions: make(map[string]Interaction)}
+	if mode == ModeOff {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && mode == ModeRecord {
+		return c, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no cassette at %s", ErrFixtureMissing, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open cassette: %w", err)
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	for _, i := range interactions {
+		c.interactions[i.Key] = i
+	}
+	return c, nil
+}
+
+// Mode returns the cassette mode
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// lookup decodes the response recorded under key into response, request must be the one recorded
+func (c *Cassette) lookup(kind string, key any, request any, response any) error {
+	hashedKey, _, err := hash(kind, key)
+	if err != nil {
+		return err
+	}
+	fingerprint, _, err := hash(kind, request)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	interaction, ok := c.interactions[hashedKey]
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s request %s in %s", ErrFixtureMissing, kind, hashedKey[:12], c.path)
+	}
+	if interaction.Fingerprint != fingerprint {
+		return fmt.Errorf("%w: %s request %s in %s", ErrFixtureStale, kind, hashedKey[:12], c.path)
+	}
+	return json.Unmarshal(interaction.Response, response)
+}
+
+// record stores the response of request under key, replacing any previous recording
+func (c *Cassette) record(kind string, key any, request any, response any) error {
+	hashedKey, _, err := hash(kind, key)
+	if err != nil {
+		return err
+	}
+	fingerprint, rawRequest, err := hash(kind, request)
+	if err != nil {
+		return err
+	}
+	rawResponse, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions[hashedKey] = Interaction{Kind: kind, Key: hashedKey, Fingerprint: fingerprint, Request: rawRequest, Response: rawResponse}
+	c.dirty = true
+	return nil
+}
+
+/**
+* @description: Write recorded interactions to the fixture file, a no-op unless something was recorded
+* @return nil if success, error if failed
+ */
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mode != ModeRecord || !c.dirty {
+		return nil
+	}
+	interactions := make([]Interaction, 0, len(c.interactions))
+	for _, i := range c.interactions {
+		interactions = append(interactions, i)
+	}
+	// Sorted so re-recording an unchanged scenario gives an identical file
+	sort.Slice(interactions, func(a, b int) bool { return interactions[a].Key < interactions[b].Key })
+	data, err := json.MarshalIndent(interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// hash hashes the canonical JSON of v, prefixed by kind so embedder and model never collide
+func hash(kind string, v any) (string, json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(append([]byte(kind+":"), raw...))
+	return hex.EncodeToString(sum[:]), raw, nil
+}
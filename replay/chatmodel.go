@@ -0,0 +1,131 @@
+package replay
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const kindChat = "chat"
+
+type chatMessage struct {
+	Role    schema.RoleType `json:"role"`
+	Content string          `json:"content"`
+}
+
+type chatRequest struct {
+	Messages []chatMessage `json:"messages"`
+}
+
+// chatKey identifies a chat call by its user messages, they hold the request and survive changes of the system prompt
+type chatKey struct {
+	UserMessages []string `json:"user_messages"`
+}
+
+type chatResponse struct {
+	Content    string             `json:"content"`
+	TokenUsage *schema.TokenUsage `json:"token_usage,omitempty"`
+}
+
+// ChatModel records or replays the replies of a wrapped chat model. A reply is found by the user messages
+// and only served when the rest of the prompt is the one it was recorded from, otherwise replay fails with ErrFixtureStale.
+type ChatModel struct {
+	inner    model.BaseChatModel
+	cassette *Cassette
+}
+
+/**
+* @description: Wrap a chat model with a cassette
+* @param inner real chat model, may be nil in replay mode
+* @param cassette fixture storage
+* @return the wrapped chat model
+ */
+func NewChatModel(inner model.BaseChatModel, cassette *Cassette) *ChatModel {
+	return &ChatModel{inner: inner, cassette: cassette}
+}
+
+func (m *ChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	request := newChatRequest(input)
+	switch m.cassette.Mode() {
+	case ModeReplay:
+		return m.replay(ctx, input, request)
+	case ModeRecord:
+		output, err := m.inner.Generate(ctx, input, opts...)
+		if err != nil {
+			return nil, err
+		}
+		response := chatResponse{Content: output.Content}
+		if output.ResponseMeta != nil {
+			response.TokenUsage = output.ResponseMeta.Usage
+		}
+		return output, m.cassette.record(kindChat, request.key(), request, response)
+	default:
+		return m.inner.Generate(ctx, input, opts...)
+	}
+}
+
+// Stream answers with the whole reply as a single chunk, the agent graph only uses Generate
+func (m *ChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	if m.cassette.Mode() == ModeOff {
+		return m.inner.Stream(ctx, input, opts...)
+	}
+	output, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{output}), nil
+}
+
+// replay serves a reply from the cassette, reporting callbacks as the real model would
+func (m *ChatModel) replay(ctx context.Context, input []*schema.Message, request chatRequest) (output *schema.Message, err error) {
+	ctx = callbacks.EnsureRunInfo(ctx, m.GetType(), components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input})
+	var response chatResponse
+	if err = m.cassette.lookup(kindChat, request.key(), request, &response); err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	output = schema.AssistantMessage(response.Content, nil)
+	cbOutput := &model.CallbackOutput{Message: output}
+	if response.TokenUsage != nil {
+		output.ResponseMeta = &schema.ResponseMeta{Usage: response.TokenUsage}
+		cbOutput.TokenUsage = &model.TokenUsage{
+			PromptTokens:     response.TokenUsage.PromptTokens,
+			CompletionTokens: response.TokenUsage.CompletionTokens,
+			TotalTokens:      response.TokenUsage.TotalTokens,
+		}
+	}
+	callbacks.OnEnd(ctx, cbOutput)
+	return output, nil
+}
+
+// GetType reports the component type for eino callbacks
+func (m *ChatModel) GetType() string {
+	return "Replay"
+}
+
+// IsCallbacksEnabled is true because the inner model or replay reports callbacks
+func (m *ChatModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+func newChatRequest(input []*schema.Message) chatRequest {
+	messages := make([]chatMessage, 0, len(input))
+	for _, msg := range input {
+		messages = append(messages, chatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return chatRequest{Messages: messages}
+}
+
+func (r chatRequest) key() chatKey {
+	var key chatKey
+	for _, msg := range r.Messages {
+		if msg.Role == schema.User {
+			key.UserMessages = append(key.UserMessages, msg.Content)
+		}
+	}
+	return key
+}
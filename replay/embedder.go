@@ -0,0 +1,69 @@
+package replay
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+const kindEmbedding = "embedding"
+
+type embeddingRequest struct {
+	Texts []string `json:"texts"`
+}
+
+// Embedder records or replays the vectors of a wrapped embedder, keyed by the embedded texts
+type Embedder struct {
+	inner    embedding.Embedder
+	cassette *Cassette
+}
+
+/**
+* @description: Wrap an embedder with a cassette
+* @param inner real embedder, may be nil in replay mode
+* @param cassette fixture storage
+* @return the wrapped embedder
+ */
+func NewEmbedder(inner embedding.Embedder, cassette *Cassette) *Embedder {
+	return &Embedder{inner: inner, cassette: cassette}
+}
+
+func (e *Embedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	request := embeddingRequest{Texts: texts}
+	switch e.cassette.Mode() {
+	case ModeReplay:
+		return e.replay(ctx, request)
+	case ModeRecord:
+		vectors, err := e.inner.EmbedStrings(ctx, texts, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return vectors, e.cassette.record(kindEmbedding, request, request, vectors)
+	default:
+		return e.inner.EmbedStrings(ctx, texts, opts...)
+	}
+}
+
+// replay serves vectors from the cassette, reporting callbacks as the real embedder would
+func (e *Embedder) replay(ctx context.Context, request embeddingRequest) (vectors [][]float64, err error) {
+	ctx = callbacks.EnsureRunInfo(ctx, e.GetType(), components.ComponentOfEmbedding)
+	ctx = callbacks.OnStart(ctx, &embedding.CallbackInput{Texts: request.Texts})
+	if err = e.cassette.lookup(kindEmbedding, request, request, &vectors); err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &embedding.CallbackOutput{Embeddings: vectors})
+	return vectors, nil
+}
+
+// GetType reports the component type for eino callbacks
+func (e *Embedder) GetType() string {
+	return "Replay"
+}
+
+// IsCallbacksEnabled is true because the inner embedder or replay reports callbacks
+func (e *Embedder) IsCallbacksEnabled() bool {
+	return true
+}
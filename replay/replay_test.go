@@ -0,0 +1,190 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// countingModel answers the last user message back and counts its calls
+type countingModel struct {
+	calls int
+}
+
+func (m *countingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	output := schema.AssistantMessage("echo: "+input[len(input)-1].Content, nil)
+	output.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13}}
+	return output, nil
+}
+
+func (m *countingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not streamed")
+}
+
+// lengthEmbedder embeds a text into its length and counts its calls
+type lengthEmbedder struct {
+	calls int
+}
+
+func (e *lengthEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.calls++
+	vectors := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		vectors = append(vectors, []float64{float64(len(text)), 0.5})
+	}
+	return vectors, nil
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Mode
+		wantErr bool
+	}{
+		{"", ModeOff, false},
+		{"off", ModeOff, false},
+		{"record", ModeRecord, false},
+		{"replay", ModeReplay, false},
+		{"REPLAY", "", true},
+		{"rewind", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.input)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fixtures", "scenario.json")
+	input := []*schema.Message{schema.SystemMessage("You recommend restaurants."), schema.UserMessage("sushi tonight")}
+	texts := []string{"sushi", "ramen bar"}
+
+	recording, err := OpenCassette(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	realModel, realEmbedder := &countingModel{}, &lengthEmbedder{}
+	recorded, err := NewChatModel(realModel, recording).Generate(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := NewEmbedder(realEmbedder, recording).EmbedStrings(ctx, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recording.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay serves the recorded answers without a backend
+	replaying, err := OpenCassette(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := NewChatModel(nil, replaying).Generate(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Content != recorded.Content || !reflect.DeepEqual(replayed.ResponseMeta.Usage, recorded.ResponseMeta.Usage) {
+		t.Errorf("replayed %+v, recorded %+v", replayed, recorded)
+	}
+	stream, err := NewChatModel(nil, replaying).Stream(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk, err := stream.Recv(); err != nil || chunk.Content != recorded.Content {
+		t.Errorf("Stream() = %+v, %v", chunk, err)
+	}
+	replayedVectors, err := NewEmbedder(nil, replaying).EmbedStrings(ctx, texts)
+	if err != nil || !reflect.DeepEqual(replayedVectors, vectors) {
+		t.Errorf("EmbedStrings() = %v, %v, want %v", replayedVectors, err, vectors)
+	}
+	if realModel.calls != 1 || realEmbedder.calls != 1 {
+		t.Errorf("backends called %d and %d times, want once each", realModel.calls, realEmbedder.calls)
+	}
+
+	// An unrecorded request fails instead of reaching a backend
+	if _, err := NewChatModel(nil, replaying).Generate(ctx, []*schema.Message{schema.UserMessage("pizza")}); !errors.Is(err, ErrFixtureMissing) {
+		t.Errorf("Generate() of an unrecorded request = %v, want ErrFixtureMissing", err)
+	}
+	if _, err := NewEmbedder(nil, replaying).EmbedStrings(ctx, []string{"pizza"}); !errors.Is(err, ErrFixtureMissing) {
+		t.Errorf("EmbedStrings() of an unrecorded request = %v, want ErrFixtureMissing", err)
+	}
+
+	// The same user request under another system prompt is found but must be recorded again
+	changed := []*schema.Message{schema.SystemMessage("You recommend cafés."), schema.UserMessage("sushi tonight")}
+	if _, err := NewChatModel(nil, replaying).Generate(ctx, changed); !errors.Is(err, ErrFixtureStale) {
+		t.Errorf("Generate() after a system prompt change = %v, want ErrFixtureStale", err)
+	}
+}
+
+func TestSaveIsDeterministic(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	record := func(name string, texts ...string) []byte {
+		path := filepath.Join(dir, name)
+		cassette, err := OpenCassette(path, ModeRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		emb := NewEmbedder(&lengthEmbedder{}, cassette)
+		for _, text := range texts {
+			if _, err := emb.EmbedStrings(ctx, []string{text}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := cassette.Save(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if first, second := record("a.json", "sushi", "ramen", "pizza"), record("b.json", "pizza", "sushi", "ramen"); string(first) != string(second) {
+		t.Errorf("recording the same requests in another order gave a different file:\n%s\n%s", first, second)
+	}
+}
+
+func TestOpenCassette(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.json")
+	if _, err := OpenCassette(missing, ModeReplay); !errors.Is(err, ErrFixtureMissing) {
+		t.Errorf("OpenCassette() of a missing file in replay = %v, want ErrFixtureMissing", err)
+	}
+	if _, err := OpenCassette(missing, ModeRecord); err != nil {
+		t.Errorf("OpenCassette() of a missing file in record = %v", err)
+	}
+	// Off and unchanged cassettes never write
+	for _, mode := range []Mode{ModeOff, ModeRecord} {
+		cassette, err := OpenCassette(missing, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cassette.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(missing); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Save() in %s mode wrote %s", mode, missing)
+		}
+	}
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCassette(broken, ModeReplay); err == nil {
+		t.Error("OpenCassette() of a broken file succeeded")
+	}
+}
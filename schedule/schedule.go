@@ -0,0 +1,241 @@
+// Package schedule parses event schedule times and reasons about meal slots, the user's meal habits and requested meals
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// layouts are the schedule time formats accepted by Parse, RFC 3339 first, then what Postgres prints for timestamptz
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+/**
+* @description: Parse a schedule time, a time without zone is taken as UTC
+* @param s schedule time, e.g. "2025-03-02T19:00:00+01:00" or "2025-03-02 19:00:00+01"
+* @return the time in its own zone, error if s matches no layout
+ */
+func Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid schedule time %q, use RFC 3339 like 2025-03-02T19:00:00+01:00", s)
+}
+
+// Slot is the meal of a time of day
+type Slot string
+
+const (
+	Breakfast Slot = "breakfast"
+	Lunch     Slot = "lunch"
+	Snack     Slot = "snack"
+	Dinner    Slot = "dinner"
+	LateNight Slot = "late night"
+)
+
+// slotStart is the minute of the day a slot starts, it ends where the next one starts
+type slotStart struct {
+	slot  Slot
+	start int
+}
+
+// slots are in day order
+var slots = []slotStart{
+	{Breakfast, 5 * 60},
+	{Lunch, 10*60 + 30},
+	{Snack, 15 * 60},
+	{Dinner, 17*60 + 30},
+	{LateNight, 22*60 + 30},
+}
+
+// typicalTimes are the usual clock times of the meals, for plans when nothing better is known
+var typicalTimes = map[Slot]string{
+	Breakfast: "08:30",
+	Lunch:     "12:30",
+	Snack:     "16:00",
+	Dinner:    "19:30",
+	LateNight: "23:00",
+}
+
+// ParseSlot returns the slot named s, e.g. "lunch"
+func ParseSlot(s string) (Slot, error) {
+	slot := Slot(strings.ToLower(strings.TrimSpace(s)))
+	if slotOrder(slot) < 0 {
+		return "", fmt.Errorf("unknown meal slot %q, use breakfast, lunch, snack, dinner or late night", s)
+	}
+	return slot, nil
+}
+
+// Order is the position of the slot in the day, breakfast first and late night last
+func (s Slot) Order() int {
+	return slotOrder(s)
+}
+
+// TypicalTime is the usual HH:MM of the meal
+func (s Slot) TypicalTime() string {
+	return typicalTimes[s]
+}
+
+// SlotOf returns the meal slot of a time, read on the clock of its zone
+func SlotOf(t time.Time) Slot {
+	minute := t.Hour()*60 + t.Minute()
+	slot := LateNight
+	for _, s := range slots {
+		if minute >= s.start {
+			slot = s.slot
+		}
+	}
+	return slot
+}
+
+// slotOrder is the position of a slot in the day, late night last
+func slotOrder(slot Slot) int {
+	return slices.IndexFunc(slots, func(s slotStart) bool {
+		return s.slot == slot
+	})
+}
+
+// Habit is when a user usually has one meal
+type Habit struct {
+	Slot Slot
+	// Minute is the median minute of the day of the meals in the slot
+	Minute int
+	Meals  int
+}
+
+// Clock formats the habit time as HH:MM
+func (h Habit) Clock() string {
+	return fmt.Sprintf("%02d:%02d", h.Minute/60, h.Minute%60)
+}
+
+/**
+* @description: Learn the usual meal times from past meals, each read on the clock of its own zone
+* @param times times of past meals
+* @return one habit per slot with meals, in day order
+ */
+func LearnHabits(times []time.Time) []Habit {
+	minutes := make(map[Slot][]int)
+	for _, t := range times {
+		minute := t.Hour()*60 + t.Minute()
+		slot := SlotOf(t)
+		// Late night meals after midnight belong after the ones before it
+		if slot == LateNight && minute < slots[0].start {
+			minute += 24 * 60
+		}
+		minutes[slot] = append(minutes[slot], minute)
+	}
+	habits := make([]Habit, 0, len(minutes))
+	for slot, values := range minutes {
+		sort.Ints(values)
+		habits = append(habits, Habit{Slot: slot, Minute: values[len(values)/2] % (24 * 60), Meals: len(values)})
+	}
+	sort.Slice(habits, func(i, j int) bool {
+		return slotOrder(habits[i].Slot) < slotOrder(habits[j].Slot)
+	})
+	return habits
+}
+
+// FormatHabits writes the habits in one line, e.g. "lunch around 12:30 (3 meals), dinner around 19:45 (1 meal)"
+func FormatHabits(habits []Habit) string {
+	usual := make([]string, 0, len(habits))
+	for _, habit := range habits {
+		meals := "meals"
+		if habit.Meals == 1 {
+			meals = "meal"
+		}
+		usual = append(usual, fmt.Sprintf("%s around %s (%d %s)", habit.Slot, habit.Clock(), habit.Meals, meals))
+	}
+	return strings.Join(usual, ", ")
+}
+
+// Target is the meal a request is about
+type Target struct {
+	// Day is midnight of the requested day in the zone of now
+	Day time.Time
+	// Slot is empty when the request names a day but no meal
+	Slot Slot
+}
+
+// slotWords map words of a request to the meal they name
+var slotWords = map[string]Slot{
+	"breakfast": Breakfast,
+	"brunch":    Breakfast,
+	"lunch":     Lunch,
+	"snack":     Snack,
+	"dinner":    Dinner,
+	"supper":    Dinner,
+	"tonight":   Dinner,
+	"midnight":  LateNight,
+}
+
+/**
+* @description: Find the day and meal a request is about, e.g. "plan lunch for tomorrow"
+* @param prompt request of the user, in English
+* @param now current time in the user's zone
+* @return the target, false if the request names neither a day nor a meal
+ */
+func ParseTarget(prompt string, now time.Time) (Target, bool) {
+	words := strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return (r < 'a' || r > 'z') && r != '-'
+	})
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	days := -1
+	var slot Slot
+	for i, word := range words {
+		if s, ok := slotWords[word]; ok && slot == "" {
+			slot = s
+		}
+		if word == "night" && i > 0 && words[i-1] == "late" && slot == "" {
+			slot = LateNight
+		}
+		switch {
+		case word == "today" || word == "tonight":
+			days = 0
+		case word == "tomorrow":
+			days = 1
+			if i >= 2 && words[i-2] == "day" && words[i-1] == "after" {
+				days = 2
+			}
+		default:
+			for d := time.Sunday; d <= time.Saturday; d++ {
+				if word == strings.ToLower(d.String()) {
+					days = (int(d) - int(now.Weekday()) + 7) % 7
+					if days == 0 && i > 0 && words[i-1] == "next" {
+						days = 7
+					}
+				}
+			}
+		}
+	}
+	if days < 0 && slot == "" {
+		return Target{}, false
+	}
+	if days < 0 {
+		// A meal without a day is the next one, today unless its slot is already over
+		days = 0
+		if slotOrder(slot) < slotOrder(SlotOf(now)) {
+			days = 1
+		}
+	}
+	return Target{Day: today.AddDate(0, 0, days), Slot: slot}, true
+}
+
+// Contains reports whether a time is on the target day and, when the target names a meal, in its slot.
+// The time is read on the clock of its own zone, convert it first to compare in the zone of the target.
+func (t Target) Contains(at time.Time) bool {
+	if at.Year() != t.Day.Year() || at.YearDay() != t.Day.YearDay() {
+		return false
+	}
+	return t.Slot == "" || SlotOf(at) == t.Slot
+}
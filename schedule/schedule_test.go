@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	paris := time.FixedZone("", 3600)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2025-03-02T19:00:00+01:00", want: time.Date(2025, 3, 2, 19, 0, 0, 0, paris)},
+		{in: "2025-03-02T18:00:00.5Z", want: time.Date(2025, 3, 2, 18, 0, 0, 5e8, time.UTC)},
+		{in: " 2025-03-02 19:00:00+01:00 ", want: time.Date(2025, 3, 2, 19, 0, 0, 0, paris)},
+		{in: "2025-03-02 19:00:00+01", want: time.Date(2025, 3, 2, 19, 0, 0, 0, paris)},
+		{in: "2025-03-02T19:00:00", want: time.Date(2025, 3, 2, 19, 0, 0, 0, time.UTC)},
+		{in: "2025-03-02 19:00:00.123456", want: time.Date(2025, 3, 2, 19, 0, 0, 123456000, time.UTC)},
+		{in: "2025-03-02", wantErr: true},
+		{in: "tomorrow at 7", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+			// The time keeps its own zone, it is read on that clock
+			_, gotOffset := got.Zone()
+			_, wantOffset := tt.want.Zone()
+			if gotOffset != wantOffset {
+				t.Errorf("Parse() offset = %d, want %d", gotOffset, wantOffset)
+			}
+		})
+	}
+}
+
+func TestSlotOf(t *testing.T) {
+	tests := []struct {
+		clock string
+		want  Slot
+	}{
+		{"00:30", LateNight},
+		{"04:59", LateNight},
+		{"05:00", Breakfast},
+		{"10:29", Breakfast},
+		{"10:30", Lunch},
+		{"15:00", Snack},
+		{"17:30", Dinner},
+		{"22:29", Dinner},
+		{"22:30", LateNight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clock, func(t *testing.T) {
+			at, err := time.Parse("15:04", tt.clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := SlotOf(at); got != tt.want {
+				t.Errorf("SlotOf(%s) = %q, want %q", tt.clock, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSlot(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Slot
+		wantErr bool
+	}{
+		{in: "lunch", want: Lunch},
+		{in: " Late Night ", want: LateNight},
+		{in: "brunch", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSlot(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseSlot() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	// A Monday at noon
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2025, time.March, 10+d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		prompt string
+		want   Target
+		ok     bool
+	}{
+		{"Where should I have lunch tomorrow?", Target{Day: day(1), Slot: Lunch}, true},
+		{"Dinner tonight", Target{Day: day(0), Slot: Dinner}, true},
+		{"something for tonight", Target{Day: day(0), Slot: Dinner}, true},
+		{"a nice breakfast place", Target{Day: day(1), Slot: Breakfast}, true},
+		{"a nice dinner place", Target{Day: day(0), Slot: Dinner}, true},
+		{"brunch on Sunday", Target{Day: day(6), Slot: Breakfast}, true},
+		{"the day after tomorrow", Target{Day: day(2)}, true},
+		{"friday", Target{Day: day(4)}, true},
+		{"monday", Target{Day: day(0)}, true},
+		{"next monday", Target{Day: day(7)}, true},
+		{"late night ramen", Target{Day: day(0), Slot: LateNight}, true},
+		{"supper or lunch", Target{Day: day(0), Slot: Dinner}, true},
+		{"somewhere with good sushi", Target{}, false},
+		{"wed", Target{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prompt, func(t *testing.T) {
+			got, ok := ParseTarget(tt.prompt, now)
+			if ok != tt.ok || !got.Day.Equal(tt.want.Day) || got.Slot != tt.want.Slot {
+				t.Errorf("ParseTarget() = %v %q %v, want %v %q %v", got.Day, got.Slot, ok, tt.want.Day, tt.want.Slot, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTargetContains(t *testing.T) {
+	target := Target{Day: time.Date(2025, time.March, 11, 0, 0, 0, 0, time.UTC), Slot: Lunch}
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"lunch that day", time.Date(2025, time.March, 11, 12, 30, 0, 0, time.UTC), true},
+		{"dinner that day", time.Date(2025, time.March, 11, 19, 0, 0, 0, time.UTC), false},
+		{"lunch the day before", time.Date(2025, time.March, 10, 12, 30, 0, 0, time.UTC), false},
+		{"lunch a year later", time.Date(2026, time.March, 11, 12, 30, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := target.Contains(tt.at); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
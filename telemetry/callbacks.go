@@ -0,0 +1,171 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	ucb "github.com/cloudwego/eino/utils/callbacks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type startTimeKey struct {
+	name string
+}
+
+func withStartTime(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, startTimeKey{name: name}, time.Now())
+}
+
+// agentKey holds the name of the agent graph running, its chat model answers are JSON arrays
+type agentKey struct{}
+
+func sinceStart(ctx context.Context, name string) (time.Duration, bool) {
+	start, ok := ctx.Value(startTimeKey{name: name}).(time.Time)
+	if !ok {
+		return 0, false
+	}
+	return time.Since(start), true
+}
+
+/**
+* @description: Build the eino callback handlers that feed the prometheus collectors
+* @return handlers to pass to compose.WithCallbacks or callbacks.InitCallbacks
+ */
+func MetricsCallbackHandlers() []callbacks.Handler {
+	// Per node latency and errors, for every node in the graph
+	nodeHandler := callbacks.NewHandlerBuilder().
+		OnStartFn(func(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+			if info.Component == compose.ComponentOfGraph {
+				ctx = context.WithValue(ctx, agentKey{}, info.Name)
+			}
+			return withStartTime(ctx, "node:"+info.Name)
+		}).
+		OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+			if d, ok := sinceStart(ctx, "node:"+info.Name); ok && info.Name != "" {
+				NodeDuration.WithLabelValues(info.Name).Observe(d.Seconds())
+			}
+			return ctx
+		}).
+		OnErrorFn(func(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+			if info.Name != "" {
+				NodeErrors.WithLabelValues(info.Name).Inc()
+			}
+			return ctx
+		}).
+		Build()
+
+	componentHandler := ucb.NewHandlerHelper().
+		Retriever(&ucb.RetrieverCallbackHandler{
+			OnStart: func(ctx context.Context, info *callbacks.RunInfo, input *retriever.CallbackInput) context.Context {
+				return withStartTime(ctx, "retriever")
+			},
+			OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *retriever.CallbackOutput) context.Context {
+				if d, ok := sinceStart(ctx, "retriever"); ok {
+					RetrievalDuration.Observe(d.Seconds())
+				}
+				RetrievalHits.Observe(float64(len(output.Docs)))
+				return ctx
+			},
+		}).
+		Embedding(&ucb.EmbeddingCallbackHandler{
+			OnStart: func(ctx context.Context, info *callbacks.RunInfo, input *embedding.CallbackInput) context.Context {
+				EmbeddingTexts.Add(float64(len(input.Texts)))
+				return ctx
+			},
+			OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *embedding.CallbackOutput) context.Context {
+				EmbeddingCalls.WithLabelValues("success").Inc()
+				return ctx
+			},
+			OnError: func(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+				EmbeddingCalls.WithLabelValues("failure").Inc()
+				return ctx
+			},
+		}).
+		ChatModel(&ucb.ModelCallbackHandler{
+			OnStart: func(ctx context.Context, info *callbacks.RunInfo, input *model.CallbackInput) context.Context {
+				return withStartTime(ctx, "chat_model")
+			},
+			OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *model.CallbackOutput) context.Context {
+				if d, ok := sinceStart(ctx, "chat_model"); ok {
+					ChatModelDuration.Observe(d.Seconds())
+				}
+				if output.TokenUsage != nil {
+					ChatModelTokens.WithLabelValues("prompt").Add(float64(output.TokenUsage.PromptTokens))
+					ChatModelTokens.WithLabelValues("completion").Add(float64(output.TokenUsage.CompletionTokens))
+				}
+				// Only the agents answer JSON, a taste profile summary is free text
+				if agent, ok := ctx.Value(agentKey{}).(string); ok && output.Message != nil {
+					var items []map[string]any
+					if err := sonic.UnmarshalString(output.Message.Content, &items); err != nil {
+						ModelOutputParseFailures.WithLabelValues(agent).Inc()
+					}
+				}
+				return ctx
+			},
+		}).
+		Handler()
+
+	return []callbacks.Handler{nodeHandler, componentHandler}
+}
+
+/**
+* @description: Build the eino callback handler that opens a span per graph node and nested component
+* @return handler to pass to compose.WithCallbacks or callbacks.InitCallbacks
+ */
+func TracingCallbackHandler() callbacks.Handler {
+	return callbacks.NewHandlerBuilder().
+		OnStartFn(func(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+			ctx, _ = StartSpan(ctx, spanName(info),
+				attribute.String("eino.component", string(info.Component)),
+				attribute.String("eino.type", info.Type),
+			)
+			return ctx
+		}).
+		OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+			if out := model.ConvCallbackOutput(output); info.Component == components.ComponentOfChatModel && out != nil && out.TokenUsage != nil {
+				trace.SpanFromContext(ctx).SetAttributes(
+					attribute.Int("llm.usage.prompt_tokens", out.TokenUsage.PromptTokens),
+					attribute.Int("llm.usage.completion_tokens", out.TokenUsage.CompletionTokens),
+				)
+			}
+			if out := retriever.ConvCallbackOutput(output); info.Component == components.ComponentOfRetriever && out != nil {
+				trace.SpanFromContext(ctx).SetAttributes(attribute.Int("retriever.hits", len(out.Docs)))
+			}
+			EndSpan(trace.SpanFromContext(ctx), nil)
+			return ctx
+		}).
+		OnEndWithStreamOutputFn(func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+			output.Close()
+			EndSpan(trace.SpanFromContext(ctx), nil)
+			return ctx
+		}).
+		OnErrorFn(func(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+			EndSpan(trace.SpanFromContext(ctx), err)
+			return ctx
+		}).
+		Build()
+}
+
+// CallbackHandlers returns every telemetry callback handler, tracing first so metrics run inside the span
+func CallbackHandlers() []callbacks.Handler {
+	return append([]callbacks.Handler{TracingCallbackHandler()}, MetricsCallbackHandlers()...)
+}
+
+func spanName(info *callbacks.RunInfo) string {
+	if info.Name != "" {
+		return info.Name
+	}
+	if info.Type != "" {
+		return info.Type + string(info.Component)
+	}
+	return string(info.Component)
+}
@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// generate reports one chat model call answering content to the metrics callbacks, inside the graph agent unless empty
+func generate(agent, name, content string) {
+	ctx := callbacks.InitCallbacks(context.Background(), &callbacks.RunInfo{Name: agent, Component: compose.ComponentOfGraph}, MetricsCallbackHandlers()...)
+	if agent != "" {
+		ctx = callbacks.OnStart(ctx, "input")
+	}
+	ctx = callbacks.ReuseHandlers(ctx, &callbacks.RunInfo{Name: name, Component: components.ComponentOfChatModel})
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: []*schema.Message{schema.UserMessage("sushi?")}})
+	callbacks.OnEnd(ctx, &model.CallbackOutput{
+		Message:    schema.AssistantMessage(content, nil),
+		TokenUsage: &model.TokenUsage{PromptTokens: 10, CompletionTokens: 4},
+	})
+}
+
+func TestChatModelMetrics(t *testing.T) {
+	agent := map[string]string{"agent": "MealMateAgent"}
+	failures := counterValue(t, "mealmate_model_output_parse_failures_total", agent)
+	profileFailures := counterValue(t, "mealmate_model_output_parse_failures_total", map[string]string{"agent": ""})
+	promptTokens := counterValue(t, "mealmate_chat_model_tokens_total", map[string]string{"type": "prompt"})
+	completionTokens := counterValue(t, "mealmate_chat_model_tokens_total", map[string]string{"type": "completion"})
+
+	generate("MealMateAgent", "ChatModel", `[{"restaurant_name": "Sakura", "recommendation_rating": 4.5}]`)
+	generate("MealMateAgent", "ChatModel", "Here are my picks!")
+	// A taste profile is summarised outside the agents, its free text is not a parse failure
+	generate("", "TasteProfile", "Loves sushi and ramen.")
+
+	if got := counterValue(t, "mealmate_model_output_parse_failures_total", agent) - failures; got != 1 {
+		t.Errorf("parse failures increased by %v, want 1", got)
+	}
+	if got := counterValue(t, "mealmate_model_output_parse_failures_total", map[string]string{"agent": ""}) - profileFailures; got != 0 {
+		t.Errorf("parse failures outside an agent increased by %v, want 0", got)
+	}
+	if got := counterValue(t, "mealmate_chat_model_tokens_total", map[string]string{"type": "prompt"}) - promptTokens; got != 30 {
+		t.Errorf("prompt tokens increased by %v, want 30", got)
+	}
+	if got := counterValue(t, "mealmate_chat_model_tokens_total", map[string]string{"type": "completion"}) - completionTokens; got != 12 {
+		t.Errorf("completion tokens increased by %v, want 12", got)
+	}
+}
+
+func TestNodeErrorMetrics(t *testing.T) {
+	errorsBefore := counterValue(t, "mealmate_graph_node_errors_total", map[string]string{"node": "Retrieve"})
+	ctx := callbacks.InitCallbacks(context.Background(), &callbacks.RunInfo{Name: "Retrieve", Component: components.ComponentOfRetriever}, MetricsCallbackHandlers()...)
+	ctx = callbacks.OnStart(ctx, "input")
+	callbacks.OnError(ctx, errors.New("milvus unavailable"))
+
+	if got := counterValue(t, "mealmate_graph_node_errors_total", map[string]string{"node": "Retrieve"}) - errorsBefore; got != 1 {
+		t.Errorf("node errors increased by %v, want 1", got)
+	}
+}
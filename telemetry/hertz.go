@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MetricsMiddleware records request count and latency per matched route
+func MetricsMiddleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		start := time.Now()
+		c.Next(ctx)
+
+		route := c.FullPath()
+		if route == "" {
+			// Unmatched paths share one label to keep cardinality bounded
+			route = "unmatched"
+		}
+		method := string(c.Method())
+		HTTPRequests.WithLabelValues(route, method, strconv.Itoa(c.Response.StatusCode())).Inc()
+		HTTPRequestDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler serves the prometheus exposition format for Registry
+func MetricsHandler() app.HandlerFunc {
+	return adaptor.HertzHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// headerCarrier adapts hertz request headers to the otel propagation carrier
+type headerCarrier struct {
+	c *app.RequestContext
+}
+
+func (h headerCarrier) Get(key string) string {
+	return string(h.c.Request.Header.Peek(key))
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request.Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.c.Request.Header.VisitAll(func(key, value []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// TracingMiddleware starts a server span per request, continuing any W3C trace context sent by the caller
+func TracingMiddleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{c: c})
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := string(c.Method())
+		ctx, span := Tracer().Start(ctx, method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", string(c.Path())),
+			),
+		)
+		defer span.End()
+
+		c.Next(ctx)
+
+		status := c.Response.StatusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= consts.StatusInternalServerError {
+			span.SetStatus(codes.Error, consts.StatusMessage(status))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	h := server.New()
+	h.Use(MetricsMiddleware())
+	h.GET("/v1/items/:id", func(ctx context.Context, c *app.RequestContext) {
+		c.String(http.StatusOK, "item")
+	})
+
+	matched := map[string]string{"route": "/v1/items/:id", "method": http.MethodGet, "status": "200"}
+	unmatched := map[string]string{"route": "unmatched", "method": http.MethodGet, "status": "404"}
+	matchedBefore := counterValue(t, "mealmate_http_requests_total", matched)
+	unmatchedBefore := counterValue(t, "mealmate_http_requests_total", unmatched)
+
+	ut.PerformRequest(h.Engine, http.MethodGet, "/v1/items/1", nil)
+	ut.PerformRequest(h.Engine, http.MethodGet, "/v1/items/2", nil)
+	ut.PerformRequest(h.Engine, http.MethodGet, "/missing", nil)
+
+	// Requests are counted by route, not by path
+	if got := counterValue(t, "mealmate_http_requests_total", matched) - matchedBefore; got != 2 {
+		t.Errorf("requests of the item route increased by %v, want 2", got)
+	}
+	if got := counterValue(t, "mealmate_http_requests_total", unmatched) - unmatchedBefore; got != 1 {
+		t.Errorf("unmatched requests increased by %v, want 1", got)
+	}
+}
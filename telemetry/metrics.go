@@ -0,0 +1,124 @@
+package telemetry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "mealmate"
+
+// Registry holds every MealMate collector, it is served on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	SyncRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_runs_total",
+		Help:      "Automatic sync runs, by result (success or failure).",
+	}, []string{"result"})
+
+	SyncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of automatic sync runs.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	SyncedEvents = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_events_total",
+		Help:      "Events synced from supabase to milvus by the automatic sync.",
+	})
+
+	NodeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graph_node_duration_seconds",
+		Help:      "Latency of agent graph nodes, by node name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"node"})
+
+	NodeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graph_node_errors_total",
+		Help:      "Errors returned by agent graph nodes, by node name.",
+	}, []string{"node"})
+
+	RetrievalDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "retrieval_duration_seconds",
+		Help:      "Latency of vector retrieval, including query embedding.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	RetrievalHits = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "retrieval_hits",
+		Help:      "Number of documents returned per retrieval.",
+		Buckets:   []float64{0, 1, 2, 3, 5, 10, 20},
+	})
+
+	EmbeddingCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embedding_calls_total",
+		Help:      "Calls to the embedder, by result (success or failure).",
+	}, []string{"result"})
+
+	EmbeddingTexts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embedding_texts_total",
+		Help:      "Texts sent to the embedder.",
+	})
+
+	ChatModelDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "chat_model_duration_seconds",
+		Help:      "Latency of chat model generations.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	})
+
+	ChatModelTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_model_tokens_total",
+		Help:      "Tokens consumed by the chat model, by type (prompt or completion).",
+	}, []string{"type"})
+
+	ModelOutputParseFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_output_parse_failures_total",
+		Help:      "Chat model outputs of an agent graph that were not a JSON array of objects, by agent.",
+	}, []string{"agent"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		SyncRuns,
+		SyncDuration,
+		SyncedEvents,
+		NodeDuration,
+		NodeErrors,
+		RetrievalDuration,
+		RetrievalHits,
+		EmbeddingCalls,
+		EmbeddingTexts,
+		ChatModelDuration,
+		ChatModelTokens,
+		ModelOutputParseFailures,
+	)
+}
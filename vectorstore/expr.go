@@ -0,0 +1,340 @@
+package vectorstore
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// ErrInvalidFilter is wrapped by every error caused by an identifier or value that cannot be rendered safely
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// identifierPattern guards field names and meta_data keys, they are interpolated into expressions unquoted
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,254}$`)
+
+// Expr is a boolean filter over stored documents. It renders as a Milvus expression with every literal
+// escaped, and the memory store evaluates the same tree so both backends agree on what matches.
+type Expr interface {
+	render(b *strings.Builder) error
+	match(doc *schema.Document) bool
+}
+
+/**
+* @description: Render an expression as a Milvus boolean expression
+* @param expr expression, nil renders as the empty string which matches everything
+* @return the expression, error wrapping ErrInvalidFilter if an identifier or value is rejected
+ */
+func Render(expr Expr) (string, error) {
+	if expr == nil {
+		return "", nil
+	}
+	var b strings.Builder
+	if err := expr.render(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Match reports whether doc satisfies expr, a nil expression matches every document
+func Match(expr Expr, doc *schema.Document) bool {
+	return expr == nil || expr.match(doc)
+}
+
+// Ref names a scalar field of the collection or a JSON path inside meta_data
+type Ref struct {
+	field string
+	path  []string
+}
+
+// Field refers to a scalar field of the collection, e.g. user_id or event_id
+func Field(name string) Ref {
+	return Ref{field: name}
+}
+
+// Meta refers to a key of the meta_data JSON field, several keys address nested objects
+func Meta(path ...string) Ref {
+	return Ref{field: "meta_data", path: path}
+}
+
+// Comparisons render as "ref op value", ordering comparisons only match numbers against numbers and strings against strings
+func (r Ref) Eq(value any) Expr  { return compare{ref: r, op: "==", value: value} }
+func (r Ref) Ne(value any) Expr  { return compare{ref: r, op: "!=", value: value} }
+func (r Ref) Lt(value any) Expr  { return compare{ref: r, op: "<", value: value} }
+func (r Ref) Lte(value any) Expr { return compare{ref: r, op: "<=", value: value} }
+func (r Ref) Gt(value any) Expr  { return compare{ref: r, op: ">", value: value} }
+func (r Ref) Gte(value any) Expr { return compare{ref: r, op: ">=", value: value} }
+
+// In matches documents whose value is one of values, no values match nothing
+func (r Ref) In(values ...any) Expr {
+	return in{ref: r, values: values}
+}
+
+// Range matches lo <= value <= hi, a nil bound is open
+func (r Ref) Range(lo, hi any) Expr {
+	exprs := make([]Expr, 0, 2)
+	if lo != nil {
+		exprs = append(exprs, r.Gte(lo))
+	}
+	if hi != nil {
+		exprs = append(exprs, r.Lte(hi))
+	}
+	return And(exprs...)
+}
+
+func (r Ref) render(b *strings.Builder) error {
+	if !identifierPattern.MatchString(r.field) {
+		return fmt.Errorf("%w: field name %q", ErrInvalidFilter, r.field)
+	}
+	if r.field == "meta_data" && len(r.path) == 0 {
+		return fmt.Errorf("%w: meta_data needs a key", ErrInvalidFilter)
+	}
+	b.WriteString(r.field)
+	for _, key := range r.path {
+		if !identifierPattern.MatchString(key) {
+			return fmt.Errorf("%w: meta_data key %q", ErrInvalidFilter, key)
+		}
+		b.WriteString(`["`)
+		b.WriteString(key)
+		b.WriteString(`"]`)
+	}
+	return nil
+}
+
+// value resolves the reference on a memory store document, the user_id field lives in its metadata
+func (r Ref) value(doc *schema.Document) (any, bool) {
+	switch r.field {
+	case "event_id":
+		return doc.ID, true
+	case "content":
+		return doc.Content, true
+	case "meta_data":
+		var current any = doc.MetaData
+		for _, key := range r.path {
+			object, ok := current.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if current, ok = object[key]; !ok {
+				return nil, false
+			}
+		}
+		return current, true
+	default:
+		v, ok := doc.MetaData[r.field]
+		return v, ok
+	}
+}
+
+type compare struct {
+	ref   Ref
+	op    string
+	value any
+}
+
+func (c compare) render(b *strings.Builder) error {
+	if err := c.ref.render(b); err != nil {
+		return err
+	}
+	b.WriteString(" " + c.op + " ")
+	return writeLiteral(b, c.value)
+}
+
+func (c compare) match(doc *schema.Document) bool {
+	got, ok := c.ref.value(doc)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "==":
+		return valuesEqual(got, c.value)
+	case "!=":
+		return !valuesEqual(got, c.value)
+	}
+	order, ok := compareValues(got, c.value)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default:
+		return order >= 0
+	}
+}
+
+type in struct {
+	ref    Ref
+	values []any
+}
+
+func (e in) render(b *strings.Builder) error {
+	if err := e.ref.render(b); err != nil {
+		return err
+	}
+	b.WriteString(" in [")
+	for i, v := range e.values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := writeLiteral(b, v); err != nil {
+			return err
+		}
+	}
+	b.WriteString("]")
+	return nil
+}
+
+func (e in) match(doc *schema.Document) bool {
+	got, ok := e.ref.value(doc)
+	if !ok {
+		return false
+	}
+	for _, v := range e.values {
+		if valuesEqual(got, v) {
+			return true
+		}
+	}
+	return false
+}
+
+type logical struct {
+	op    string
+	exprs []Expr
+}
+
+// And matches documents satisfying every expression, nil expressions are skipped
+func And(exprs ...Expr) Expr {
+	return logical{op: "&&", exprs: exprs}
+}
+
+// Or matches documents satisfying any expression, nil expressions are skipped
+func Or(exprs ...Expr) Expr {
+	return logical{op: "||", exprs: exprs}
+}
+
+func (l logical) render(b *strings.Builder) error {
+	parts := make([]string, 0, len(l.exprs))
+	for _, expr := range l.exprs {
+		rendered, err := Render(expr)
+		if err != nil {
+			return err
+		}
+		if rendered != "" {
+			parts = append(parts, rendered)
+		}
+	}
+	if len(parts) == 1 {
+		b.WriteString(parts[0])
+		return nil
+	}
+	if len(parts) > 1 {
+		b.WriteString("(" + strings.Join(parts, ") "+l.op+" (") + ")")
+	}
+	return nil
+}
+
+// match treats an empty And or Or as matching everything, like its empty rendering does in Milvus
+func (l logical) match(doc *schema.Document) bool {
+	checked := false
+	for _, expr := range l.exprs {
+		if expr == nil {
+			continue
+		}
+		ok := expr.match(doc)
+		if l.op == "&&" && !ok {
+			return false
+		}
+		if l.op == "||" && ok {
+			return true
+		}
+		checked = true
+	}
+	return l.op == "&&" || !checked
+}
+
+type not struct {
+	expr Expr
+}
+
+// Not matches documents that do not satisfy expr
+func Not(expr Expr) Expr {
+	return not{expr: expr}
+}
+
+func (n not) render(b *strings.Builder) error {
+	rendered, err := Render(n.expr)
+	if err != nil {
+		return err
+	}
+	if rendered == "" {
+		return fmt.Errorf("%w: not of an empty expression", ErrInvalidFilter)
+	}
+	b.WriteString("not (" + rendered + ")")
+	return nil
+}
+
+func (n not) match(doc *schema.Document) bool {
+	return !Match(n.expr, doc)
+}
+
+// writeLiteral renders a value, strings are quoted with every quote, backslash and control character escaped
+func writeLiteral(b *strings.Builder, value any) error {
+	switch v := value.(type) {
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case int32:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float32:
+		return writeLiteral(b, float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%w: value %v", ErrInvalidFilter, v)
+		}
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		return fmt.Errorf("%w: unsupported value type %T", ErrInvalidFilter, value)
+	}
+	return nil
+}
+
+// compareValues orders two numbers or two strings, ok is false for any other pair
+func compareValues(a, b any) (order int, ok bool) {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	sa, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	sb, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(sa, sb), true
+}
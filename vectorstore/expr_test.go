@@ -0,0 +1,112 @@
+package vectorstore
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expr
+		want string
+	}{
+		{"nil", nil, ""},
+		{"string", Field("user_id").Eq("u1"), `user_id == "u1"`},
+		{"quote injection", Field("user_id").Eq(`u1" || user_id != "`), `user_id == "u1\" || user_id != \""`},
+		{"backslash injection", Field("user_id").Eq(`u1\`), `user_id == "u1\\"`},
+		{"newline", Field("user_id").Eq("u1\nu2"), `user_id == "u1\nu2"`},
+		{"meta path", Meta("location", "city").Eq("Paris"), `meta_data["location"]["city"] == "Paris"`},
+		{"number", Meta("rating").Gte(4.5), `meta_data["rating"] >= 4.5`},
+		{"in", Field("event_id").In("a", "b"), `event_id in ["a", "b"]`},
+		{"in without values", Field("event_id").In(), `event_id in []`},
+		{"and", And(Field("user_id").Eq("u1"), Meta("n").Lt(3)), `(user_id == "u1") && (meta_data["n"] < 3)`},
+		{"and of one", And(nil, Field("user_id").Eq("u1")), `user_id == "u1"`},
+		{"empty and", And(), ""},
+		{"or", Or(Meta("n").Eq(1), Meta("n").Eq(2)), `(meta_data["n"] == 1) || (meta_data["n"] == 2)`},
+		{"not", Not(Field("user_id").Eq("u1")), `not (user_id == "u1")`},
+		{"open range", Meta("n").Range(1, nil), `meta_data["n"] >= 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.expr)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expr
+	}{
+		{"field injection", Field("user_id == 1 || user_id").Eq("u1")},
+		{"meta key injection", Meta(`a"] == 1 || meta_data["b`).Eq(1)},
+		{"meta without key", Meta().Eq(1)},
+		{"not of empty", Not(And())},
+		{"not of nil", Not(nil)},
+		{"nan", Meta("n").Eq(math.NaN())},
+		{"infinity", Meta("n").Lt(math.Inf(1))},
+		{"unsupported type", Meta("n").Eq([]string{"a"})},
+		{"nested", And(Field("user_id").Eq("u1"), Or(Field("bad field").Eq(1)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Render(tt.expr); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("Render() = %q, %v, want ErrInvalidFilter", got, err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	doc := &schema.Document{
+		ID:      "e1",
+		Content: "Dinner at Chez Marie",
+		MetaData: map[string]any{
+			"user_id":  "u1",
+			"rating":   4.0,
+			"location": map[string]any{"city": "Paris"},
+		},
+	}
+	tests := []struct {
+		name string
+		expr Expr
+		want bool
+	}{
+		{"nil", nil, true},
+		{"user", Field("user_id").Eq("u1"), true},
+		{"other user", Field("user_id").Eq("u2"), false},
+		{"injected user", Field("user_id").Eq(`u2" || user_id != "`), false},
+		{"event id", Field("event_id").Eq("e1"), true},
+		{"int against float", Meta("rating").Eq(4), true},
+		{"ordering", Meta("rating").Range(3, 5), true},
+		{"ordering outside", Meta("rating").Gt(4), false},
+		{"string against number", Meta("rating").Lt("5"), false},
+		{"missing key", Meta("missing").Ne("x"), false},
+		{"nested path", Meta("location", "city").Eq("Paris"), true},
+		{"path through scalar", Meta("rating", "x").Eq(1), false},
+		{"in", Field("user_id").In("u2", "u1"), true},
+		{"in without values", Field("user_id").In(), false},
+		{"empty and", And(), true},
+		{"empty or", Or(), true},
+		{"or", Or(Field("user_id").Eq("u2"), Meta("rating").Eq(4)), true},
+		{"not", Not(Field("user_id").Eq("u1")), false},
+		{"not of empty", Not(And()), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.expr, doc); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultTopK is used when the caller does not pass retriever.WithTopK
+const defaultTopK = 3
+
+type memoryEntry struct {
+	doc    *schema.Document
+	vector []float64
+}
+
+// MemoryStore is an in-process Store doing exact cosine search over every document.
+// It is meant for development and tests, data is lost when the process exits.
+type MemoryStore struct {
+	embedder embedding.Embedder
+
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+/**
+* @description: Create an empty in-memory store
+* @param embedder embedder used for documents and queries
+* @return the store, error if embedder is nil
+ */
+func NewMemoryStore(embedder embedding.Embedder) (*MemoryStore, error) {
+	if embedder == nil {
+		return nil, fmt.Errorf("embedder not initialized")
+	}
+	return &MemoryStore{
+		embedder: embedder,
+		entries:  make(map[string]memoryEntry),
+	}, nil
+}
+
+// Store embeds docs and upserts them by ID
+func (s *MemoryStore) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) (ids []string, err error) {
+	ctx = callbacks.EnsureRunInfo(ctx, s.GetType(), components.ComponentOfIndexer)
+	ctx = callbacks.OnStart(ctx, &indexer.CallbackInput{Docs: docs})
+	defer func() {
+		if err != nil {
+			callbacks.OnError(ctx, err)
+		}
+	}()
+
+	texts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		if doc.MetaData["user_id"] == nil {
+			return nil, fmt.Errorf("user_id is missing in meta_data for document ID %s", doc.ID)
+		}
+		texts = append(texts, doc.Content)
+	}
+	vectors, err := s.embedder.EmbedStrings(embeddingCtx(ctx, s.embedder), texts)
+	if err != nil {
+		return nil, fmt.Errorf("[memory store] embedding has error: %w", err)
+	}
+	if len(vectors) != len(docs) {
+		return nil, fmt.Errorf("[memory store] invalid return length of vectors, got=%d, expected=%d", len(vectors), len(docs))
+	}
+
+	s.mu.Lock()
+	ids = make([]string, 0, len(docs))
+	for i, doc := range docs {
+		s.entries[doc.ID] = memoryEntry{doc: copyDocument(doc), vector: vectors[i]}
+		ids = append(ids, doc.ID)
+	}
+	s.mu.Unlock()
+
+	callbacks.OnEnd(ctx, &indexer.CallbackOutput{IDs: ids})
+	return ids, nil
+}
+
+// Retrieve returns the TopK documents most similar to query that match the WithFilter option
+func (s *MemoryStore) Retrieve(ctx context.Context, query string, opts ...retriever.Option) (docs []*schema.Document, err error) {
+	topK := defaultTopK
+	co := retriever.GetCommonOptions(&retriever.Options{TopK: &topK}, opts...)
+	io := retriever.GetImplSpecificOptions(&implOptions{}, opts...)
+
+	ctx = callbacks.EnsureRunInfo(ctx, s.GetType(), components.ComponentOfRetriever)
+	cbInput := &retriever.CallbackInput{Query: query, TopK: *co.TopK, ScoreThreshold: co.ScoreThreshold}
+	if io.Filter != nil {
+		if cbInput.Filter, err = milvusExpr(*io.Filter); err != nil {
+			return nil, err
+		}
+	}
+	ctx = callbacks.OnStart(ctx, cbInput)
+	defer func() {
+		if err != nil {
+			callbacks.OnError(ctx, err)
+		}
+	}()
+
+	vectors, err := s.embedder.EmbedStrings(embeddingCtx(ctx, s.embedder), []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("[memory store] embedding has error: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("[memory store] invalid return length of vector, got=%d, expected=1", len(vectors))
+	}
+
+	type hit struct {
+		entry memoryEntry
+		score float64
+	}
+	s.mu.RLock()
+	hits := make([]hit, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if io.Filter != nil && !matches(*io.Filter, entry.doc) {
+			continue
+		}
+		score := cosine(vectors[0], entry.vector)
+		if co.ScoreThreshold != nil && score < *co.ScoreThreshold {
+			continue
+		}
+		hits = append(hits, hit{entry: entry, score: score})
+	}
+	s.mu.RUnlock()
+
+	// Ties are broken by ID so results are deterministic
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].entry.doc.ID < hits[j].entry.doc.ID
+	})
+	if len(hits) > *co.TopK {
+		hits = hits[:*co.TopK]
+	}
+
+	docs = make([]*schema.Document, 0, len(hits))
+	for _, h := range hits {
+		docs = append(docs, copyDocument(h.entry.doc).WithScore(h.score))
+	}
+	callbacks.OnEnd(ctx, &retriever.CallbackOutput{Docs: docs})
+	return docs, nil
+}
+
+// IDs returns the IDs of every stored document matching filter, sorted
+func (s *MemoryStore) IDs(ctx context.Context, filter Filter) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0)
+	for id, entry := range s.entries {
+		if matches(filter, entry.doc) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete removes documents by ID
+func (s *MemoryStore) Delete(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.entries, id)
+	}
+	return nil
+}
+
+// Check always succeeds, the store lives in process
+func (s *MemoryStore) Check(ctx context.Context) error {
+	return nil
+}
+
+// Flush is a no-op, writes are applied immediately
+func (s *MemoryStore) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close drops every stored document
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]memoryEntry)
+	return nil
+}
+
+// Len returns the number of stored documents
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// GetType reports the component type for eino callbacks
+func (s *MemoryStore) GetType() string {
+	return "Memory"
+}
+
+// IsCallbacksEnabled is true because Store and Retrieve report callbacks themselves
+func (s *MemoryStore) IsCallbacksEnabled() bool {
+	return true
+}
+
+// matches reports whether doc satisfies filter, evaluating the same expression the milvus store renders
+func matches(filter Filter, doc *schema.Document) bool {
+	return Match(filter.expr(), doc)
+}
+
+// valuesEqual compares metadata values, treating every numeric type as float64 like JSON does
+func valuesEqual(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return a == b
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func copyDocument(doc *schema.Document) *schema.Document {
+	metaData := make(map[string]any, len(doc.MetaData))
+	for k, v := range doc.MetaData {
+		metaData[k] = v
+	}
+	return &schema.Document{ID: doc.ID, Content: doc.Content, MetaData: metaData}
+}
+
+// embeddingCtx gives nested embedding calls their own run info, like the milvus components do
+func embeddingCtx(ctx context.Context, emb embedding.Embedder) context.Context {
+	runInfo := &callbacks.RunInfo{Component: components.ComponentOfEmbedding}
+	if embType, ok := components.GetType(emb); ok {
+		runInfo.Type = embType
+	}
+	runInfo.Name = runInfo.Type + string(runInfo.Component)
+	return callbacks.ReuseHandlers(ctx, runInfo)
+}
@@ -0,0 +1,98 @@
+package vectorstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"mealmate-agent/embedder"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+func newTestMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	emb, err := embedder.NewHashEmbedder(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewMemoryStore(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []*schema.Document{
+		{ID: "e1", Content: "Sushi dinner at Sakura", MetaData: map[string]any{"user_id": "u1", "cuisine": "japanese"}},
+		{ID: "e2", Content: "Ramen lunch at Ichiran", MetaData: map[string]any{"user_id": "u1", "cuisine": "japanese"}},
+		{ID: "e3", Content: "Pizza night at Luigi", MetaData: map[string]any{"user_id": "u1", "cuisine": "italian"}},
+		{ID: "e4", Content: "Sushi dinner at Sakura", MetaData: map[string]any{"user_id": "u2", "cuisine": "japanese"}},
+	}
+	if _, err := store.Store(context.Background(), docs); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestMemoryStoreRetrieve(t *testing.T) {
+	store := newTestMemoryStore(t)
+	tests := []struct {
+		name   string
+		query  string
+		topK   int
+		filter *Filter
+		want   []string
+	}{
+		{"top match first", "sushi at sakura", 1, nil, []string{"e1"}},
+		{"ties broken by id", "sushi at sakura", 2, nil, []string{"e1", "e4"}},
+		{"user filter", "sushi at sakura", 3, &Filter{UserID: "u2"}, []string{"e4"}},
+		{"meta filter", "dinner", 5, &Filter{UserID: "u1", Meta: map[string]any{"cuisine": "italian"}}, []string{"e3"}},
+		{"where filter", "ramen lunch", 5, &Filter{Where: Field("event_id").In("e2", "e3")}, []string{"e2", "e3"}},
+		{"injected user", "sushi", 5, &Filter{UserID: `u1" || user_id != "`}, []string{}},
+		{"no match", "sushi", 5, &Filter{UserID: "u3"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []retriever.Option{retriever.WithTopK(tt.topK)}
+			if tt.filter != nil {
+				opts = append(opts, WithFilter(*tt.filter))
+			}
+			docs, err := store.Retrieve(context.Background(), tt.query, opts...)
+			if err != nil {
+				t.Fatalf("Retrieve() error = %v", err)
+			}
+			got := make([]string, 0, len(docs))
+			for _, doc := range docs {
+				got = append(got, doc.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Retrieve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreIDsAndDelete(t *testing.T) {
+	store := newTestMemoryStore(t)
+	ctx := context.Background()
+	ids, err := store.IDs(ctx, Filter{UserID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"e1", "e2", "e3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("IDs() = %v, want %v", ids, want)
+	}
+	if err := store.Delete(ctx, []string{"e1", "unknown"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Len(); got != 3 {
+		t.Errorf("Len() = %d after delete, want 3", got)
+	}
+}
+
+func TestMemoryStoreRequiresUser(t *testing.T) {
+	store := newTestMemoryStore(t)
+	_, err := store.Store(context.Background(), []*schema.Document{{ID: "e5", Content: "Tacos"}})
+	if err == nil {
+		t.Error("Store() of a document without user_id succeeded")
+	}
+}
@@ -0,0 +1,361 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	milvusindexer "github.com/cloudwego/eino-ext/components/indexer/milvus"
+	milvusretriever "github.com/cloudwego/eino-ext/components/retriever/milvus"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// idsBatchSize is how many IDs one page of IDs reads
+const idsBatchSize = 1000
+
+// eventFields is the schema of the event collection, dim is the vector dimension of the embedder
+func eventFields(dim int) []*entity.Field {
+	return []*entity.Field{
+		{
+			Name:       "event_id",
+			DataType:   entity.FieldTypeVarChar,
+			PrimaryKey: true,
+			AutoID:     false,
+			TypeParams: map[string]string{
+				"max_length": "256",
+			},
+		},
+		{
+			Name:     "vector",
+			DataType: entity.FieldTypeFloatVector,
+			TypeParams: map[string]string{
+				"dim": strconv.Itoa(dim),
+			},
+		},
+		{
+			Name:     "content",
+			DataType: entity.FieldTypeVarChar,
+			TypeParams: map[string]string{
+				"max_length":      "8192",
+				"enable_analyzer": "false",
+			},
+		},
+		{
+			Name:     "meta_data",
+			DataType: entity.FieldTypeJSON,
+			TypeParams: map[string]string{
+				"enable_analyzer": "false",
+			},
+		},
+		{
+			Name:     "user_id",
+			DataType: entity.FieldTypeVarChar,
+			TypeParams: map[string]string{
+				"max_length":      "256",
+				"enable_analyzer": "false",
+			},
+		},
+	}
+}
+
+// MilvusConfig configures the event collection and its searches
+type MilvusConfig struct {
+	Collection string
+	// Dim is the vector dimension, used when the collection is created
+	Dim int
+	// TopK is the default number of documents a search returns
+	TopK int
+	// SearchEf is the HNSW search breadth, at least TopK
+	SearchEf int
+}
+
+// MilvusStore is the Store backed by a Milvus collection
+type MilvusStore struct {
+	client     client.Client
+	collection string
+	indexer    *milvusindexer.Indexer
+	retriever  *milvusretriever.Retriever
+}
+
+/**
+* @description: Create a store on the configured collection, creating it if missing
+* @param ctx context.Context
+* @param milvusClient connected milvus client, owned by the store from now on
+* @param embedder embedder used for documents and queries
+* @param config collection and search settings
+* @return the store, error if the indexer or retriever cannot be created
+ */
+func NewMilvusStore(ctx context.Context, milvusClient client.Client, embedder embedding.Embedder, config MilvusConfig) (*MilvusStore, error) {
+	if milvusClient == nil {
+		return nil, fmt.Errorf("milvus client not initialized")
+	}
+	if embedder == nil {
+		return nil, fmt.Errorf("embedder not initialized")
+	}
+	collection := config.Collection
+
+	idx, err := newEventIndexer(ctx, milvusClient, config, embedder)
+	if err != nil {
+		return nil, err
+	}
+	rtr, err := newEventRetriever(ctx, milvusClient, config, embedder)
+	if err != nil {
+		return nil, err
+	}
+	return &MilvusStore{
+		client:     milvusClient,
+		collection: collection,
+		indexer:    idx,
+		retriever:  rtr,
+	}, nil
+}
+
+func newEventIndexer(ctx context.Context, milvusClient client.Client, config MilvusConfig, embedder embedding.Embedder) (*milvusindexer.Indexer, error) {
+	return milvusindexer.NewIndexer(ctx, &milvusindexer.IndexerConfig{
+		Client:     milvusClient,
+		Collection: config.Collection,
+		Embedding:  embedder,
+		Fields:     eventFields(config.Dim),
+		MetricType: milvusindexer.COSINE,
+		DocumentConverter: func(ctx context.Context, docs []*schema.Document, vectors [][]float64) ([]interface{}, error) {
+			rows := make([]interface{}, 0, len(docs))
+			for i, doc := range docs {
+				userId := doc.MetaData["user_id"]
+				if userId == nil {
+					return nil, fmt.Errorf("user_id is missing in meta_data for document ID %s", doc.ID)
+				}
+
+				metaData := make(map[string]any)
+				for k, v := range doc.MetaData {
+					if k != "user_id" {
+						metaData[k] = v
+					}
+				}
+
+				// Convert []float64 to []float32 for Milvus
+				vector32 := make([]float32, len(vectors[i]))
+				for j, v := range vectors[i] {
+					vector32[j] = float32(v)
+				}
+
+				row := map[string]interface{}{
+					"event_id":  doc.ID,
+					"vector":    vector32,
+					"content":   doc.Content,
+					"meta_data": metaData,
+					"user_id":   userId,
+				}
+				rows = append(rows, row)
+			}
+			return rows, nil
+		},
+	})
+}
+
+func newEventRetriever(ctx context.Context, milvusClient client.Client, config MilvusConfig, embedder embedding.Embedder) (*milvusretriever.Retriever, error) {
+	searchParam, err := entity.NewIndexHNSWSearchParam(config.SearchEf)
+	if err != nil {
+		return nil, err
+	}
+	return milvusretriever.NewRetriever(ctx, &milvusretriever.RetrieverConfig{
+		Client:      milvusClient,
+		Collection:  config.Collection,
+		VectorField: "vector",
+		OutputFields: []string{
+			"event_id",
+			"content",
+			"meta_data",
+			"user_id",
+		},
+		TopK:      config.TopK,
+		Embedding: embedder,
+		DocumentConverter: func(ctx context.Context, doc client.SearchResult) ([]*schema.Document, error) {
+			var err error
+			result := make([]*schema.Document, doc.IDs.Len())
+			for i := range result {
+				result[i] = &schema.Document{
+					MetaData: make(map[string]any),
+				}
+				if i < len(doc.Scores) {
+					result[i].WithScore(float64(doc.Scores[i]))
+				}
+			}
+			for _, field := range doc.Fields {
+				switch field.Name() {
+				case "event_id":
+					for i, document := range result {
+						document.ID, err = doc.IDs.GetAsString(i)
+						if err != nil {
+							return nil, fmt.Errorf("failed to get id: %w", err)
+						}
+					}
+				case "content":
+					for i, document := range result {
+						document.Content, err = field.GetAsString(i)
+						if err != nil {
+							return nil, fmt.Errorf("failed to get content: %w", err)
+						}
+					}
+				case "meta_data":
+					for i, document := range result {
+						b, err := field.Get(i)
+						bytes, ok := b.([]byte)
+						if !ok {
+							return nil, fmt.Errorf("failed to get metadata: %w", err)
+						}
+						// Keep the score set above, the stored metadata never contains it
+						score := document.Score()
+						if err := sonic.Unmarshal(bytes, &document.MetaData); err != nil {
+							return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+						}
+						document.WithScore(score)
+					}
+				case "user_id":
+					for i, document := range result {
+						document.MetaData["user_id"], err = field.GetAsString(i)
+						if err != nil {
+							return nil, fmt.Errorf("failed to get user_id: %w", err)
+						}
+					}
+				}
+			}
+			return result, nil
+		},
+		VectorConverter: func(ctx context.Context, vectors [][]float64) ([]entity.Vector, error) {
+			vecs := make([]entity.Vector, len(vectors))
+			for i, vector := range vectors {
+				float32Vec := make([]float32, len(vector))
+				for j, v := range vector {
+					float32Vec[j] = float32(v)
+				}
+				vecs[i] = entity.FloatVector(float32Vec)
+			}
+			return vecs, nil
+		},
+		MetricType: entity.COSINE,
+		Sp:         searchParam,
+	})
+}
+
+// Store embeds and upserts docs into the collection
+func (s *MilvusStore) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) ([]string, error) {
+	return s.indexer.Store(ctx, docs, opts...)
+}
+
+// Retrieve searches the collection, translating a WithFilter option into a boolean expression
+func (s *MilvusStore) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	io := retriever.GetImplSpecificOptions(&implOptions{}, opts...)
+	if io.Filter != nil {
+		expr, err := milvusExpr(*io.Filter)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, milvusretriever.WithFilter(expr))
+	}
+	return s.retriever.Retrieve(ctx, query, opts...)
+}
+
+// milvusExpr renders a Filter as a Milvus boolean expression
+func milvusExpr(filter Filter) (string, error) {
+	return Render(filter.expr())
+}
+
+// IDs queries the event_id of every row matching filter, page by page so a large collection stays under the query
+// result window of Milvus
+func (s *MilvusStore) IDs(ctx context.Context, filter Filter) ([]string, error) {
+	expr, err := Render(And(Field("event_id").Ne(""), filter.expr()))
+	if err != nil {
+		return nil, err
+	}
+	it, err := s.client.QueryIterator(ctx, client.NewQueryIteratorOption(s.collection).
+		WithExpr(expr).
+		WithOutputFields("event_id").
+		WithBatchSize(idsBatchSize))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for {
+		rs, err := it.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		column := rs.GetColumn("event_id")
+		if column == nil {
+			return ids, nil
+		}
+		for i := 0; i < column.Len(); i++ {
+			id, err := column.GetAsString(i)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+}
+
+// Delete removes rows by event_id
+func (s *MilvusStore) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	values := make([]any, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id)
+	}
+	expr, err := Render(Field("event_id").In(values...))
+	if err != nil {
+		return err
+	}
+	return s.client.Delete(ctx, s.collection, "", expr)
+}
+
+// Check reports whether milvus is healthy and the collection is loaded
+func (s *MilvusStore) Check(ctx context.Context) error {
+	state, err := s.client.CheckHealth(ctx)
+	if err != nil {
+		return err
+	}
+	if !state.IsHealthy {
+		return fmt.Errorf("milvus is unhealthy: %v", state.Reasons)
+	}
+	loadState, err := s.client.GetLoadState(ctx, s.collection, nil)
+	if err != nil {
+		return err
+	}
+	if loadState != entity.LoadStateLoaded {
+		return fmt.Errorf("collection %s is not loaded (state %d)", s.collection, loadState)
+	}
+	return nil
+}
+
+// Flush persists pending inserts of the collection
+func (s *MilvusStore) Flush(ctx context.Context) error {
+	return s.client.Flush(ctx, s.collection, false)
+}
+
+// Close closes the milvus client
+func (s *MilvusStore) Close() error {
+	return s.client.Close()
+}
+
+// GetType reports the component type for eino callbacks
+func (s *MilvusStore) GetType() string {
+	return "Milvus"
+}
+
+// IsCallbacksEnabled is true because the wrapped milvus indexer and retriever report callbacks themselves
+func (s *MilvusStore) IsCallbacksEnabled() bool {
+	return true
+}
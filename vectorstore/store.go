@@ -0,0 +1,77 @@
+package vectorstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+)
+
+const (
+	// KindMilvus stores events in a Milvus collection
+	KindMilvus = "milvus"
+	// KindMemory keeps events in process memory, for development and tests without Milvus
+	KindMemory = "memory"
+)
+
+// Store persists event documents with their embeddings and searches them by cosine similarity.
+// Documents carry the owning user in MetaData["user_id"].
+type Store interface {
+	indexer.Indexer
+	retriever.Retriever
+	// IDs returns the IDs of every document matching filter
+	IDs(ctx context.Context, filter Filter) ([]string, error)
+	// Delete removes documents by ID, unknown IDs are ignored
+	Delete(ctx context.Context, ids []string) error
+	// Check reports whether the backend is reachable and ready to serve searches
+	Check(ctx context.Context) error
+	// Flush persists pending writes
+	Flush(ctx context.Context) error
+	// Close releases the backend connection
+	Close() error
+}
+
+// Filter restricts a search to one user and to documents whose metadata match exactly
+type Filter struct {
+	UserID string
+	Meta   map[string]any
+	// Where is an additional condition built with Field and Meta, nil for none
+	Where Expr
+}
+
+// expr combines the filter conditions into one expression
+func (f Filter) expr() Expr {
+	exprs := make([]Expr, 0, len(f.Meta)+2)
+	if f.UserID != "" {
+		exprs = append(exprs, Field("user_id").Eq(f.UserID))
+	}
+	for _, k := range f.metaKeys() {
+		exprs = append(exprs, Meta(k).Eq(f.Meta[k]))
+	}
+	if f.Where != nil {
+		exprs = append(exprs, f.Where)
+	}
+	return And(exprs...)
+}
+
+// metaKeys returns the metadata filter keys in a stable order
+func (f Filter) metaKeys() []string {
+	keys := make([]string, 0, len(f.Meta))
+	for k := range f.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type implOptions struct {
+	Filter *Filter
+}
+
+// WithFilter restricts Retrieve to documents matching filter
+func WithFilter(filter Filter) retriever.Option {
+	return retriever.WrapImplSpecificOptFn(func(o *implOptions) {
+		o.Filter = &filter
+	})
+}